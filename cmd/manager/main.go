@@ -5,6 +5,7 @@ import (
 	"errors"
 	"flag"
 	"fmt"
+	"net/http"
 	"os"
 	"runtime"
 	"strings"
@@ -20,6 +21,13 @@ import (
 	machineconfigapi "github.com/openshift/machine-config-operator/pkg/apis/machineconfiguration.openshift.io/v1"
 	"github.com/openshift/managed-upgrade-operator/pkg/apis"
 	"github.com/openshift/managed-upgrade-operator/pkg/controller"
+	upgrademetrics "github.com/openshift/managed-upgrade-operator/pkg/metrics"
+	"github.com/openshift/managed-upgrade-operator/pkg/monitor"
+	"github.com/openshift/managed-upgrade-operator/pkg/operatorconfig"
+	"github.com/openshift/managed-upgrade-operator/pkg/policy"
+	"github.com/openshift/managed-upgrade-operator/pkg/prometheus"
+	"github.com/openshift/managed-upgrade-operator/pkg/reporter"
+	"github.com/openshift/managed-upgrade-operator/pkg/upgradestatus"
 	"github.com/openshift/managed-upgrade-operator/version"
 	"github.com/operator-framework/operator-sdk/pkg/k8sutil"
 	"github.com/operator-framework/operator-sdk/pkg/kube-metrics"
@@ -42,6 +50,9 @@ var (
 	metricsHost               = "0.0.0.0"
 	metricsPort         int32 = 8383
 	operatorMetricsPort int32 = 8686
+	debugPort           int32 = 8484
+	// The debug endpoints are not authenticated, they are only reachable from within the pod
+	debugHost = "127.0.0.1"
 )
 var log = logf.Log.WithName("cmd")
 
@@ -96,7 +107,7 @@ func main() {
 		os.Exit(1)
 	}
 
-	// This set the sync period to 5m
+	// This set the sync period to 5m, upgrade progress is picked up from the cluster resource watches in between
 	syncPeriod := time.Duration(5 * time.Minute)
 
 	// Set default manager options
@@ -159,6 +170,40 @@ func main() {
 	// Add the Metrics Service
 	addMetrics(ctx, cfg)
 
+	// Serve the effective operator configuration so it can be checked on a given cluster
+	if err := mgr.Add(manager.RunnableFunc(serveDebug)); err != nil {
+		log.Error(err, "")
+		os.Exit(1)
+	}
+
+	// Pull the upgrade policies of the cluster, when configured
+	if err := mgr.Add(policy.NewPoller(mgr.GetClient(), policy.NewBuilder())); err != nil {
+		log.Error(err, "")
+		os.Exit(1)
+	}
+
+	// Evaluate the critical signals while upgrading, when configured
+	if err := mgr.Add(monitor.NewMonitor(mgr.GetClient(), prometheus.NewBuilder(), &upgrademetrics.Counter{})); err != nil {
+		log.Error(err, "")
+		os.Exit(1)
+	}
+
+	// The status writes conflicting with another write read the UpgradeConfig again from the API server, bypassing the cache
+	upgradestatus.SetReader(mgr.GetAPIReader())
+
+	// Report the upgrade state transitions, when configured
+	operatorNamespace, err := operatorconfig.OperatorNamespace()
+	if err != nil {
+		log.Error(err, "")
+		os.Exit(1)
+	}
+	statusReporter := reporter.NewStatusReporter(mgr.GetClient(), operatorNamespace)
+	upgradestatus.AddListener(statusReporter.Observe)
+	if err := mgr.Add(statusReporter); err != nil {
+		log.Error(err, "")
+		os.Exit(1)
+	}
+
 	log.Info("Starting the Cmd.")
 
 	// Start the Cmd
@@ -168,6 +213,23 @@ func main() {
 	}
 }
 
+// serveDebug serves the debug endpoints on the loopback interface until stopped:
+// * /config returns the effective operator configuration and the ConfigMap it was loaded from
+func serveDebug(stop <-chan struct{}) error {
+	mux := http.NewServeMux()
+	mux.Handle("/config", operatorconfig.Handler())
+	server := &http.Server{Addr: fmt.Sprintf("%s:%d", debugHost, debugPort), Handler: mux}
+	go func() {
+		<-stop
+		server.Close()
+	}()
+	err := server.ListenAndServe()
+	if err == http.ErrServerClosed {
+		return nil
+	}
+	return err
+}
+
 // addMetrics will create the Services and Service Monitors to allow the operator export the metrics by using
 // the Prometheus operator
 func addMetrics(ctx context.Context, cfg *rest.Config) {
@@ -3,6 +3,7 @@ package v1alpha1
 import (
 	"time"
 
+	configv1 "github.com/openshift/api/config/v1"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
@@ -15,16 +16,190 @@ type UpgradeConfigSpec struct {
 	// This defines the 3rd party operator subscriptions upgrade
 	// +kubebuilder:validation:Optional
 	SubscriptionUpdates []SubscriptionUpdate `json:"subscriptionUpdates,omitempty"`
+
+	// This defines the external approval gates that must pass before an upgrade step is performed
+	// +kubebuilder:validation:Optional
+	Gates []UpgradeGate `json:"gates,omitempty"`
+
+	// Require a human approval, given with the approval annotations, once the pre-upgrade checks have passed
+	// +kubebuilder:validation:Optional
+	RequireApproval bool `json:"requireApproval,omitempty"`
+
+	// Describe how long to wait for the approval before the upgrade is abandoned, in minutes
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:Minimum=1
+	ApprovalTimeoutMinutes int32 `json:"approvalTimeoutMinutes,omitempty"`
+
+	// Only run the read-only upgrade checks and report what the other steps would change, without upgrading the cluster.
+	// It is ignored while an upgrade is in progress.
+	// +kubebuilder:validation:Optional
+	DryRun bool `json:"dryRun,omitempty"`
+
+	// Describe how long the maintenance window for the upgrade is, in minutes. The upgrade does not start if it is estimated to take longer
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:Minimum=1
+	MaintenanceWindowMinutes int32 `json:"maintenanceWindowMinutes,omitempty"`
+
+	// This defines which ClusterVersion overrides are removed when the upgrade is commenced, all of them if unset
+	// +kubebuilder:validation:Optional
+	OverridePolicy *OverridePolicy `json:"overridePolicy,omitempty"`
+
+	// This defines how an upgrade started outside the operator is handled, it is adopted if unset
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:Enum={"Adopt","Forbid"}
+	ExternalUpgradePolicy ExternalUpgradePolicy `json:"externalUpgradePolicy,omitempty"`
+
+	// Describe the time the upgrade starts at, in RFC3339 format. The upgrade starts right away if unset
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:Format=date-time
+	UpgradeAt string `json:"upgradeAt,omitempty"`
+}
+
+type ExternalUpgradePolicy string
+
+const (
+	// Record the upgrade started outside the operator in the history and track it to completion
+	ExternalUpgradePolicyAdopt ExternalUpgradePolicy = "Adopt"
+	// Record the upgrade started outside the operator in the history and raise an alert until it is acknowledged
+	ExternalUpgradePolicyForbid ExternalUpgradePolicy = "Forbid"
+)
+
+type OverridePolicyType string
+
+const (
+	// Keep all the ClusterVersion overrides
+	OverridePolicyKeep OverridePolicyType = "Keep"
+	// Remove all the ClusterVersion overrides
+	OverridePolicyClearAll OverridePolicyType = "ClearAll"
+	// Remove the ClusterVersion overrides matching one of the matchers
+	OverridePolicyClearMatching OverridePolicyType = "ClearMatching"
+)
+
+// OverridePolicy describe which ClusterVersion overrides are removed for the upgrade
+type OverridePolicy struct {
+	// Describe whether the overrides are kept, all removed or only the matching ones removed
+	// +kubebuilder:validation:Enum={"Keep","ClearAll","ClearMatching"}
+	Policy OverridePolicyType `json:"policy"`
+	// Describe the overrides removed by the ClearMatching policy
+	// +kubebuilder:validation:Optional
+	Matching []OverrideMatcher `json:"matching,omitempty"`
+	// Put the removed overrides back once the upgrade is verified
+	// +kubebuilder:validation:Optional
+	Restore bool `json:"restore,omitempty"`
+}
+
+// OverrideMatcher matches ClusterVersion overrides, an empty field matches any value
+type OverrideMatcher struct {
+	// Describe the kind of the overridden component
+	// +kubebuilder:validation:Optional
+	Kind string `json:"kind,omitempty"`
+	// Describe the API group of the overridden component
+	// +kubebuilder:validation:Optional
+	Group string `json:"group,omitempty"`
+	// Describe the namespace of the overridden component
+	// +kubebuilder:validation:Optional
+	Namespace string `json:"namespace,omitempty"`
+	// Describe the name of the overridden component
+	// +kubebuilder:validation:Optional
+	Name string `json:"name,omitempty"`
 }
 
+// Matches returns true if the override matches every field set in the matcher
+func (m OverrideMatcher) Matches(override configv1.ComponentOverride) bool {
+	return (len(m.Kind) == 0 || m.Kind == override.Kind) &&
+		(len(m.Group) == 0 || m.Group == override.Group) &&
+		(len(m.Namespace) == 0 || m.Namespace == override.Namespace) &&
+		(len(m.Name) == 0 || m.Name == override.Name)
+}
+
+// Removes returns true if the policy removes the override. Without policy, every override is removed.
+func (p *OverridePolicy) Removes(override configv1.ComponentOverride) bool {
+	if p == nil {
+		return true
+	}
+	switch p.Policy {
+	case OverridePolicyKeep:
+		return false
+	case OverridePolicyClearMatching:
+		for _, m := range p.Matching {
+			if m.Matches(override) {
+				return true
+			}
+		}
+		return false
+	default:
+		return true
+	}
+}
+
+const (
+	// Annotation recording the identity of the person approving the upgrade
+	ApprovedByAnnotation = "upgrade.managed.openshift.io/approved-by"
+	// Annotation recording the version the approval was given for
+	ApprovedVersionAnnotation = "upgrade.managed.openshift.io/approved-version"
+	// Annotation asking the operator to retry a failed upgrade, it is removed once the retry started
+	RetryAnnotation = "upgrade.managed.openshift.io/retry"
+	// Annotation pausing the upgrade while it is set to "true"
+	PauseAnnotation = "upgrade.managed.openshift.io/paused"
+	// Annotation asking the operator to resume the worker upgrade paused by the monitor, it is removed once resumed
+	ResumeAnnotation = "upgrade.managed.openshift.io/resume"
+	// Annotation acknowledging the forbidden upgrade started outside the operator, it is removed once acknowledged
+	ExternalUpgradeAcknowledgedAnnotation = "upgrade.managed.openshift.io/external-upgrade-acknowledged"
+	// Annotation recording the upgrade policy the UpgradeConfig was created from
+	PolicyIDAnnotation = "upgrade.managed.openshift.io/policy-id"
+	// Annotation recording whether the upgrade policy is scheduled manually or automatically
+	PolicyScheduleTypeAnnotation = "upgrade.managed.openshift.io/policy-schedule-type"
+)
+
 // UpgradeConfigStatus defines the observed state of UpgradeConfig
 type UpgradeConfigStatus struct {
 
 	// This record history of every upgrade
 	// +kubebuilder:validation:Optional
 	History UpgradeHistories `json:"history,omitempty"`
+
+	// This record the result of the last dry run, it is kept out of the history
+	// +kubebuilder:validation:Optional
+	DryRun *UpgradeHistory `json:"dryRun,omitempty"`
+
+	// This record the ClusterVersion overrides removed to upgrade the cluster which the override policy restores, until they are restored
+	// +kubebuilder:validation:Optional
+	RemovedOverrides []configv1.ComponentOverride `json:"removedOverrides,omitempty"`
+
+	// This record the conditions of the UpgradeConfig itself, as opposed to the conditions of an upgrade
+	// +kubebuilder:validation:Optional
+	Conditions Conditions `json:"conditions,omitempty"`
+
+	// This record the results of the health checks last performed before an upgrade
+	// +kubebuilder:validation:Optional
+	PreflightResults []PreflightResult `json:"preflightResults,omitempty"`
+}
+
+// PreflightResult records the outcome of a health check performed before the upgrade
+type PreflightResult struct {
+	// Name of the health check
+	Name string `json:"name"`
+	// +kubebuilder:validation:Enum={"Pass","Fail","Warn"}
+	// Outcome of the health check, only a failure blocks the upgrade
+	Result PreflightStatus `json:"result"`
+	// Summary of the outcome
+	// +kubebuilder:validation:Optional
+	Message string `json:"message,omitempty"`
+	// What the outcome is based on, like the firing alerts or the degraded operators
+	// +kubebuilder:validation:Optional
+	Evidence []string `json:"evidence,omitempty"`
+	// When the health check was performed
+	CheckedTime metav1.Time `json:"checkedTime"`
 }
 
+type PreflightStatus string
+
+const (
+	PreflightPass PreflightStatus = "Pass"
+	PreflightFail PreflightStatus = "Fail"
+	PreflightWarn PreflightStatus = "Warn"
+)
+
 // Conditions is a set of Condition instances.
 type UpgradeHistories []UpgradeHistory
 
@@ -32,7 +207,7 @@ type UpgradeHistories []UpgradeHistory
 type UpgradeHistory struct {
 	//Desired version of this upgrade
 	Version string `json:"version,omitempty"`
-	// +kubebuilder:validation:Enum={"New","Pending","Upgrading","Upgraded", "Failed"}
+	// +kubebuilder:validation:Enum={"New","Pending","Upgrading","Upgraded","Failed","Superseded"}
 	// +kubebuilder:default:="New"
 	// This describe the status of the upgrade process
 	Phase UpgradePhase `json:"phase"`
@@ -43,6 +218,155 @@ type UpgradeHistory struct {
 
 	// +kubebuilder:validation:Optional
 	CompleteTime *metav1.Time `json:"completeTime,omitempty"`
+
+	// This marks the result of a dry run, whose phase tells whether the upgrade would succeed.
+	// Dry runs are recorded in status.dryRun, only the earlier versions of the operator recorded them in the history.
+	// +kubebuilder:validation:Optional
+	DryRun bool `json:"dryRun,omitempty"`
+
+	// This describe how long each stage of the upgrade is expected to take
+	// +kubebuilder:validation:Optional
+	Estimate *UpgradeEstimate `json:"estimate,omitempty"`
+
+	// This describe who started the upgrade, the operator if unset
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:Enum={"Operator","External","Imported"}
+	Source UpgradeSource `json:"source,omitempty"`
+
+	// This describe how long the health checks performed after the upgrade took to settle
+	// +kubebuilder:validation:Optional
+	Settle *HealthCheckSettle `json:"settle,omitempty"`
+
+	// This compares the SLO metrics before and after the upgrade
+	// +kubebuilder:validation:Optional
+	Regression *RegressionComparison `json:"regression,omitempty"`
+
+	// This describe how far the control plane upgrade went
+	// +kubebuilder:validation:Optional
+	ControlPlaneProgress *ControlPlaneProgress `json:"controlPlaneProgress,omitempty"`
+
+	// This record the results of the health checks a dry run would perform before the upgrade
+	// +kubebuilder:validation:Optional
+	PreflightResults []PreflightResult `json:"preflightResults,omitempty"`
+}
+
+type UpgradeSource string
+
+const (
+	// The upgrade was performed by the operator
+	UpgradeSourceOperator UpgradeSource = "Operator"
+	// The upgrade was started outside the operator, e.g. with oc adm upgrade
+	UpgradeSourceExternal UpgradeSource = "External"
+	// The upgrade was imported from the ClusterVersion history, it happened before the operator was installed
+	UpgradeSourceImported UpgradeSource = "Imported"
+)
+
+// HealthCheckSettle describe how the health checks performed after the upgrade settled, they must pass
+// continuously for the settle period of the operator configuration
+type HealthCheckSettle struct {
+	// When the health checks were first performed
+	StartTime *metav1.Time `json:"startTime"`
+	// Since when the health checks pass, unset while they fail
+	// +kubebuilder:validation:Optional
+	PassingSince *metav1.Time `json:"passingSince,omitempty"`
+	// When the health checks had passed for the whole settle period
+	// +kubebuilder:validation:Optional
+	SettledTime *metav1.Time `json:"settledTime,omitempty"`
+	// How long settling took, from the first health check until they settled, in seconds
+	// +kubebuilder:validation:Optional
+	SettleSeconds int64 `json:"settleSeconds,omitempty"`
+	// Number of times the health checks failed again after passing
+	// +kubebuilder:validation:Optional
+	Flaps int32 `json:"flaps,omitempty"`
+}
+
+// RegressionComparison compares the SLO metrics over a window before the upgrade commenced and after it was verified
+type RegressionComparison struct {
+	// End of the window before the upgrade, when it commenced
+	BeforeWindowEnd metav1.Time `json:"beforeWindowEnd"`
+	// Start of the window after the upgrade, when it was verified
+	AfterWindowStart metav1.Time `json:"afterWindowStart"`
+	// Length of both windows, in minutes
+	WindowMinutes int32 `json:"windowMinutes"`
+	// Comparison of each metric
+	// +kubebuilder:validation:Optional
+	Metrics []MetricComparison `json:"metrics,omitempty"`
+}
+
+// MetricComparison compares the average of a metric over the windows before and after the upgrade
+type MetricComparison struct {
+	// Name of the metric in the operator configuration
+	Name string `json:"name"`
+	// Average of the metric before the upgrade
+	// +kubebuilder:validation:Optional
+	Before string `json:"before,omitempty"`
+	// Average of the metric after the upgrade
+	// +kubebuilder:validation:Optional
+	After string `json:"after,omitempty"`
+	// Change of the average, after minus before
+	// +kubebuilder:validation:Optional
+	Delta string `json:"delta,omitempty"`
+	// Change of the average relative to before the upgrade, in percent
+	// +kubebuilder:validation:Optional
+	DeltaPercent string `json:"deltaPercent,omitempty"`
+	// This marks the metrics which increased by more than one of their thresholds
+	Regressed bool `json:"regressed"`
+	// Human readable message describing the comparison
+	// +kubebuilder:validation:Optional
+	Message string `json:"message,omitempty"`
+}
+
+// ControlPlaneProgress describe how far the control plane upgrade went, from the ClusterOperators and the ClusterVersion
+type ControlPlaneProgress struct {
+	// Number of ClusterOperators reporting the target version
+	UpdatedOperators int32 `json:"updatedOperators"`
+	// Number of ClusterOperators
+	TotalOperators int32 `json:"totalOperators"`
+	// ClusterOperators not reporting the target version yet, sorted
+	// +kubebuilder:validation:Optional
+	PendingOperators []string `json:"pendingOperators,omitempty"`
+	// ClusterOperators not reporting the target version yet which are progressing, sorted
+	// +kubebuilder:validation:Optional
+	ProgressingOperators []string `json:"progressingOperators,omitempty"`
+	// Number of release manifests the cluster version operator applied, N in "N of M done"
+	// +kubebuilder:validation:Optional
+	DoneManifests int32 `json:"doneManifests,omitempty"`
+	// Number of release manifests, M in "N of M done"
+	// +kubebuilder:validation:Optional
+	TotalManifests int32 `json:"totalManifests,omitempty"`
+	// Message of the ClusterVersion Progressing condition
+	// +kubebuilder:validation:Optional
+	Message string `json:"message,omitempty"`
+	// Last time the number of updated operators or applied manifests changed
+	// +kubebuilder:validation:Optional
+	LastProgressTime *metav1.Time `json:"lastProgressTime,omitempty"`
+}
+
+// UpgradeEstimate describe the expected duration of the upgrade stages
+type UpgradeEstimate struct {
+	// Expected duration of the control plane upgrade, in minutes
+	ControlPlaneMinutes int32 `json:"controlPlaneMinutes"`
+	// Expected duration of the master nodes upgrade, in minutes
+	MasterNodesMinutes int32 `json:"masterNodesMinutes"`
+	// Expected duration of the worker nodes upgrade, in minutes
+	WorkerNodesMinutes int32 `json:"workerNodesMinutes"`
+	// Number of batches the master nodes are upgraded in
+	MasterBatches int32 `json:"masterBatches"`
+	// Number of batches the worker nodes are upgraded in
+	WorkerBatches int32 `json:"workerBatches"`
+	// Number of past upgrades the estimate is based on
+	// +kubebuilder:validation:Optional
+	Samples int32 `json:"samples,omitempty"`
+}
+
+// TotalMinutes returns the expected duration of the whole upgrade.
+// Master and worker nodes are upgraded in parallel once the control plane is upgraded.
+func (e *UpgradeEstimate) TotalMinutes() int32 {
+	nodes := e.WorkerNodesMinutes
+	if e.MasterNodesMinutes > nodes {
+		nodes = e.MasterNodesMinutes
+	}
+	return e.ControlPlaneMinutes + nodes
 }
 
 type UpgradeConditionType string
@@ -75,6 +399,7 @@ const (
 	UpgradeValidated              UpgradeConditionType = "Validation"
 	UpgradePreHealthCheck         UpgradeConditionType = "PreHealthCheck"
 	UpgradeScaleUpExtraNodes      UpgradeConditionType = "ScaleUpExtraNodes"
+	AwaitingApproval              UpgradeConditionType = "AwaitingApproval"
 	ControlPlaneMaintWindow       UpgradeConditionType = "ControlPlaneMaintWindow"
 	CommenceUpgrade               UpgradeConditionType = "CommenceUpgrade"
 	ControlPlaneUpgraded          UpgradeConditionType = "ControlPlaneUpgraded"
@@ -85,8 +410,27 @@ const (
 	RemoveExtraScaledNodes        UpgradeConditionType = "RemoveExtraScaledNodes"
 	UpdateSubscriptions           UpgradeConditionType = "UpdateSubscriptions"
 	PostUpgradeVerification       UpgradeConditionType = "PostUpgradeVerification"
+	RestoreOverrides              UpgradeConditionType = "RestoreOverrides"
 	RemoveMaintWindow             UpgradeConditionType = "RemoveMaintWindow"
 	PostClusterHealthCheck        UpgradeConditionType = "PostClusterHealthCheck"
+	RegressionCheck               UpgradeConditionType = "RegressionCheck"
+	ExternalUpgradeDetected       UpgradeConditionType = "ExternalUpgradeDetected"
+	ImportedFromClusterVersion    UpgradeConditionType = "ImportedFromClusterVersion"
+
+	// Conflict is set on the UpgradeConfigs which are not active because an older one exists
+	Conflict UpgradeConditionType = "Conflict"
+	// Degraded is set on the UpgradeConfig whose worker upgrade the monitor paused because a critical signal tripped
+	Degraded UpgradeConditionType = "Degraded"
+	// ExternalUpgradeForbidden is set on the UpgradeConfig whose policy forbids the upgrade started outside the operator,
+	// until the upgrade is acknowledged
+	ExternalUpgradeForbidden UpgradeConditionType = "ExternalUpgradeForbidden"
+	// DryRunIgnored is set on the UpgradeConfig asking for a dry run while its upgrade is in progress, the upgrade carries on
+	DryRunIgnored UpgradeConditionType = "DryRunIgnored"
+	// InvalidSchedule is set on the UpgradeConfig whose upgrade time cannot be parsed, the upgrade does not start until it is fixed
+	InvalidSchedule UpgradeConditionType = "InvalidSchedule"
+
+	// GateConditionPrefix prefixes the condition types recording approval gate decisions
+	GateConditionPrefix = "ApprovalGate-"
 )
 
 type UpgradePhase string
@@ -97,7 +441,9 @@ const (
 	UpgradePhaseUpgrading UpgradePhase = "Upgrading"
 	UpgradePhaseUpgraded  UpgradePhase = "Upgraded"
 	UpgradePhaseFailed    UpgradePhase = "Failed"
-	UpgradePhaseUnknown   UpgradePhase = "Unknown"
+	// The upgrade was retargeted to a newer version before it completed
+	UpgradePhaseSuperseded UpgradePhase = "Superseded"
+	UpgradePhaseUnknown    UpgradePhase = "Unknown"
 )
 
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
@@ -150,6 +496,28 @@ type SubscriptionUpdate struct {
 	Name string `json:"name"`
 }
 
+// UpgradeGate describes an HTTP endpoint which must approve an upgrade step before it is performed
+type UpgradeGate struct {
+	// Describe the name of the gate
+	Name string `json:"name"`
+	// Describe the upgrade step the gate must approve before it is performed
+	Step UpgradeConditionType `json:"step"`
+	// Describe the URL the upgrade context is POSTed to
+	URL string `json:"url"`
+	// Describe the secret holding the key used to HMAC-sign the requests
+	// +kubebuilder:validation:Optional
+	SecretRef *corev1.SecretReference `json:"secretRef,omitempty"`
+	// Describe how long to wait for the endpoint to respond, in seconds
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:Minimum=1
+	TimeoutSeconds int32 `json:"timeoutSeconds,omitempty"`
+}
+
+// GateConditionType returns the condition type used to record the decision of the named gate
+func GateConditionType(name string) UpgradeConditionType {
+	return UpgradeConditionType(GateConditionPrefix + name)
+}
+
 // IsTrue Condition whether the condition status is "True".
 func (c UpgradeCondition) IsTrue() bool {
 	return c.Status == corev1.ConditionTrue
@@ -271,17 +639,36 @@ func (conditions *Conditions) RemoveCondition(t UpgradeConditionType) bool {
 	return false
 }
 
+// GetHistory returns the upgrade history of the given version, dry runs excluded
 func (histories UpgradeHistories) GetHistory(version string) *UpgradeHistory {
 	for _, history := range histories {
-		if history.Version == version {
+		if history.Version == version && !history.DryRun {
 			return &history
 		}
 	}
 	return nil
 }
+
+// IsDryRun returns true if the UpgradeConfig only dry runs its upgrade.
+// The dry run flag is ignored while an upgrade the operator started is in progress, so it is not abandoned midway.
+func (uc *UpgradeConfig) IsDryRun() bool {
+	return uc.Spec.DryRun && !uc.IsUpgrading()
+}
+
+// IsUpgrading returns true if an upgrade the operator started is in progress
+func (uc *UpgradeConfig) IsUpgrading() bool {
+	for _, history := range uc.Status.History {
+		if !history.DryRun && history.Source != UpgradeSourceExternal && history.Phase == UpgradePhaseUpgrading {
+			return true
+		}
+	}
+	return false
+}
+
+// SetHistory adds (or updates) the history with the same version and dry run flag
 func (histories *UpgradeHistories) SetHistory(history UpgradeHistory) {
 	for i, h := range *histories {
-		if h.Version == history.Version {
+		if h.Version == history.Version && h.DryRun == history.DryRun {
 			(*histories)[i] = history
 			return
 		}
@@ -5,6 +5,8 @@
 package v1alpha1
 
 import (
+	configv1 "github.com/openshift/api/config/v1"
+	v1 "k8s.io/api/core/v1"
 	runtime "k8s.io/apimachinery/pkg/runtime"
 )
 
@@ -30,6 +32,162 @@ func (in Conditions) DeepCopy() Conditions {
 	return *out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ControlPlaneProgress) DeepCopyInto(out *ControlPlaneProgress) {
+	*out = *in
+	if in.PendingOperators != nil {
+		in, out := &in.PendingOperators, &out.PendingOperators
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.ProgressingOperators != nil {
+		in, out := &in.ProgressingOperators, &out.ProgressingOperators
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.LastProgressTime != nil {
+		in, out := &in.LastProgressTime, &out.LastProgressTime
+		*out = (*in).DeepCopy()
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ControlPlaneProgress.
+func (in *ControlPlaneProgress) DeepCopy() *ControlPlaneProgress {
+	if in == nil {
+		return nil
+	}
+	out := new(ControlPlaneProgress)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HealthCheckSettle) DeepCopyInto(out *HealthCheckSettle) {
+	*out = *in
+	if in.StartTime != nil {
+		in, out := &in.StartTime, &out.StartTime
+		*out = (*in).DeepCopy()
+	}
+	if in.PassingSince != nil {
+		in, out := &in.PassingSince, &out.PassingSince
+		*out = (*in).DeepCopy()
+	}
+	if in.SettledTime != nil {
+		in, out := &in.SettledTime, &out.SettledTime
+		*out = (*in).DeepCopy()
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HealthCheckSettle.
+func (in *HealthCheckSettle) DeepCopy() *HealthCheckSettle {
+	if in == nil {
+		return nil
+	}
+	out := new(HealthCheckSettle)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MetricComparison) DeepCopyInto(out *MetricComparison) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MetricComparison.
+func (in *MetricComparison) DeepCopy() *MetricComparison {
+	if in == nil {
+		return nil
+	}
+	out := new(MetricComparison)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *OverrideMatcher) DeepCopyInto(out *OverrideMatcher) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OverrideMatcher.
+func (in *OverrideMatcher) DeepCopy() *OverrideMatcher {
+	if in == nil {
+		return nil
+	}
+	out := new(OverrideMatcher)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *OverridePolicy) DeepCopyInto(out *OverridePolicy) {
+	*out = *in
+	if in.Matching != nil {
+		in, out := &in.Matching, &out.Matching
+		*out = make([]OverrideMatcher, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OverridePolicy.
+func (in *OverridePolicy) DeepCopy() *OverridePolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(OverridePolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PreflightResult) DeepCopyInto(out *PreflightResult) {
+	*out = *in
+	if in.Evidence != nil {
+		in, out := &in.Evidence, &out.Evidence
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	in.CheckedTime.DeepCopyInto(&out.CheckedTime)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PreflightResult.
+func (in *PreflightResult) DeepCopy() *PreflightResult {
+	if in == nil {
+		return nil
+	}
+	out := new(PreflightResult)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RegressionComparison) DeepCopyInto(out *RegressionComparison) {
+	*out = *in
+	in.BeforeWindowEnd.DeepCopyInto(&out.BeforeWindowEnd)
+	in.AfterWindowStart.DeepCopyInto(&out.AfterWindowStart)
+	if in.Metrics != nil {
+		in, out := &in.Metrics, &out.Metrics
+		*out = make([]MetricComparison, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RegressionComparison.
+func (in *RegressionComparison) DeepCopy() *RegressionComparison {
+	if in == nil {
+		return nil
+	}
+	out := new(RegressionComparison)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *SubscriptionUpdate) DeepCopyInto(out *SubscriptionUpdate) {
 	*out = *in
@@ -142,6 +300,18 @@ func (in *UpgradeConfigSpec) DeepCopyInto(out *UpgradeConfigSpec) {
 		*out = make([]SubscriptionUpdate, len(*in))
 		copy(*out, *in)
 	}
+	if in.Gates != nil {
+		in, out := &in.Gates, &out.Gates
+		*out = make([]UpgradeGate, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.OverridePolicy != nil {
+		in, out := &in.OverridePolicy, &out.OverridePolicy
+		*out = new(OverridePolicy)
+		(*in).DeepCopyInto(*out)
+	}
 	return
 }
 
@@ -165,6 +335,30 @@ func (in *UpgradeConfigStatus) DeepCopyInto(out *UpgradeConfigStatus) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.DryRun != nil {
+		in, out := &in.DryRun, &out.DryRun
+		*out = new(UpgradeHistory)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.RemovedOverrides != nil {
+		in, out := &in.RemovedOverrides, &out.RemovedOverrides
+		*out = make([]configv1.ComponentOverride, len(*in))
+		copy(*out, *in)
+	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make(Conditions, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.PreflightResults != nil {
+		in, out := &in.PreflightResults, &out.PreflightResults
+		*out = make([]PreflightResult, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 	return
 }
 
@@ -178,6 +372,43 @@ func (in *UpgradeConfigStatus) DeepCopy() *UpgradeConfigStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *UpgradeEstimate) DeepCopyInto(out *UpgradeEstimate) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new UpgradeEstimate.
+func (in *UpgradeEstimate) DeepCopy() *UpgradeEstimate {
+	if in == nil {
+		return nil
+	}
+	out := new(UpgradeEstimate)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *UpgradeGate) DeepCopyInto(out *UpgradeGate) {
+	*out = *in
+	if in.SecretRef != nil {
+		in, out := &in.SecretRef, &out.SecretRef
+		*out = new(v1.SecretReference)
+		**out = **in
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new UpgradeGate.
+func (in *UpgradeGate) DeepCopy() *UpgradeGate {
+	if in == nil {
+		return nil
+	}
+	out := new(UpgradeGate)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in UpgradeHistories) DeepCopyInto(out *UpgradeHistories) {
 	{
@@ -218,6 +449,33 @@ func (in *UpgradeHistory) DeepCopyInto(out *UpgradeHistory) {
 		in, out := &in.CompleteTime, &out.CompleteTime
 		*out = (*in).DeepCopy()
 	}
+	if in.Estimate != nil {
+		in, out := &in.Estimate, &out.Estimate
+		*out = new(UpgradeEstimate)
+		**out = **in
+	}
+	if in.Settle != nil {
+		in, out := &in.Settle, &out.Settle
+		*out = new(HealthCheckSettle)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Regression != nil {
+		in, out := &in.Regression, &out.Regression
+		*out = new(RegressionComparison)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ControlPlaneProgress != nil {
+		in, out := &in.ControlPlaneProgress, &out.ControlPlaneProgress
+		*out = new(ControlPlaneProgress)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.PreflightResults != nil {
+		in, out := &in.PreflightResults, &out.PreflightResults
+		*out = make([]PreflightResult, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 	return
 }
 
@@ -0,0 +1,130 @@
+package cluster_upgrader
+
+import (
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/golang/mock/gomock"
+	machineapi "github.com/openshift/machine-api-operator/pkg/apis/machine/v1beta1"
+	upgradev1alpha1 "github.com/openshift/managed-upgrade-operator/pkg/apis/upgrade/v1alpha1"
+	"github.com/openshift/managed-upgrade-operator/pkg/metrics"
+	"github.com/openshift/managed-upgrade-operator/util/mocks"
+	testStructs "github.com/openshift/managed-upgrade-operator/util/mocks/structs"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("AwaitApproval", func() {
+	var (
+		mockCtrl       *gomock.Controller
+		mockKubeClient *mocks.MockClient
+		upgradeConfig  *upgradev1alpha1.UpgradeConfig
+		logger         logr.Logger
+	)
+
+	run := func() (bool, error) {
+		return AwaitApproval(mockKubeClient, &metrics.Counter{}, nil, upgradeConfig, logger)
+	}
+	approve := func(approver string, version string) {
+		upgradeConfig.Annotations = map[string]string{
+			upgradev1alpha1.ApprovedByAnnotation:      approver,
+			upgradev1alpha1.ApprovedVersionAnnotation: version,
+		}
+	}
+	waitingSince := func(d time.Duration) {
+		upgradeConfig.Status.History[0].Conditions.SetCondition(upgradev1alpha1.UpgradeCondition{
+			Type:      upgradev1alpha1.AwaitingApproval,
+			Status:    corev1.ConditionFalse,
+			StartTime: &metav1.Time{Time: time.Now().Add(-d)},
+		})
+	}
+
+	BeforeEach(func() {
+		mockCtrl = gomock.NewController(GinkgoT())
+		mockKubeClient = mocks.NewMockClient(mockCtrl)
+		upgradeConfig = testStructs.NewUpgradeConfigBuilder().GetUpgradeConfig()
+		upgradeConfig.Spec.RequireApproval = true
+		upgradeConfig.Status.History = upgradev1alpha1.UpgradeHistories{{
+			Version:    upgradeConfig.Spec.Desired.Version,
+			Phase:      upgradev1alpha1.UpgradePhaseUpgrading,
+			Conditions: upgradev1alpha1.NewConditions(),
+		}}
+		waitingSince(time.Hour)
+		logger = logf.Log.WithName("await approval test logger")
+	})
+
+	AfterEach(func() {
+		mockCtrl.Finish()
+	})
+
+	Context("When the desired version is approved", func() {
+		It("proceeds and records the approver", func() {
+			mockUpdater := mocks.NewMockStatusWriter(mockCtrl)
+			mockKubeClient.EXPECT().Status().Return(mockUpdater)
+			mockUpdater.EXPECT().Patch(gomock.Any(), gomock.Any(), gomock.Any())
+			approve("jane@example.com", upgradeConfig.Spec.Desired.Version)
+			approved, err := run()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(approved).To(BeTrue())
+			condition := upgradeConfig.Status.History[0].Conditions.GetCondition(upgradev1alpha1.AwaitingApproval)
+			Expect(condition.IsTrue()).To(BeTrue())
+			Expect(condition.StartTime).NotTo(BeNil())
+			Expect(condition.CompleteTime).NotTo(BeNil())
+			Expect(condition.Message).To(Equal("upgrade to " + upgradeConfig.Spec.Desired.Version + " approved by jane@example.com"))
+		})
+	})
+
+	Context("When another version is approved", func() {
+		It("keeps waiting", func() {
+			approve("jane@example.com", "4.4.0")
+			approved, err := run()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(approved).To(BeFalse())
+		})
+	})
+
+	Context("When the approval times out", func() {
+		It("removes the extra upgrade workers and fails the upgrade", func() {
+			waitingSince(25 * time.Hour)
+			extra := machineapi.MachineSet{ObjectMeta: metav1.ObjectMeta{Name: "worker-a-upgrade", Namespace: "openshift-machine-api"}}
+			mockKubeClient.EXPECT().List(gomock.Any(), gomock.Any(), gomock.Any()).SetArg(1, machineapi.MachineSetList{Items: []machineapi.MachineSet{extra}})
+			mockKubeClient.EXPECT().Delete(gomock.Any(), &extra)
+			approved, err := run()
+			Expect(approved).To(BeFalse())
+			Expect(err).To(BeAssignableToTypeOf(&failedStepError{}))
+			Expect(err.Error()).To(Equal("upgrade was not approved within 24h0m0s"))
+		})
+
+		It("applies the approval timeout of the UpgradeConfig", func() {
+			upgradeConfig.Spec.ApprovalTimeoutMinutes = 30
+			mockKubeClient.EXPECT().List(gomock.Any(), gomock.Any(), gomock.Any())
+			approved, err := run()
+			Expect(approved).To(BeFalse())
+			Expect(err.Error()).To(Equal("upgrade was not approved within 30m0s"))
+		})
+	})
+
+	Context("When the approval step succeeds", func() {
+		It("records the approver in the step condition", func() {
+			mockUpdater := mocks.NewMockStatusWriter(mockCtrl)
+			mockKubeClient.EXPECT().Status().Return(mockUpdater).AnyTimes()
+			mockUpdater.EXPECT().Patch(gomock.Any(), gomock.Any(), gomock.Any()).AnyTimes()
+			for _, key := range Ordering() {
+				if key != upgradev1alpha1.AwaitingApproval {
+					upgradeConfig.Status.History[0].Conditions.SetCondition(upgradev1alpha1.UpgradeCondition{Type: key, Status: corev1.ConditionTrue})
+				}
+			}
+			approve("jane@example.com", upgradeConfig.Spec.Desired.Version)
+			upgrader := clusterUpgrader{Steps: UpgradeSteps{upgradev1alpha1.AwaitingApproval: AwaitApproval}, client: mockKubeClient, metrics: &metrics.Counter{}}
+			err := upgrader.UpgradeCluster(upgradeConfig, logger)
+			Expect(err).NotTo(HaveOccurred())
+			condition := upgradeConfig.Status.History[0].Conditions.GetCondition(upgradev1alpha1.AwaitingApproval)
+			Expect(condition.IsTrue()).To(BeTrue())
+			Expect(condition.Message).To(Equal("upgrade to " + upgradeConfig.Spec.Desired.Version + " approved by jane@example.com"))
+		})
+	})
+})
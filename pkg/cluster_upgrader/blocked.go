@@ -0,0 +1,120 @@
+package cluster_upgrader
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	configv1 "github.com/openshift/api/config/v1"
+	machineconfigapi "github.com/openshift/machine-config-operator/pkg/apis/machineconfiguration.openshift.io/v1"
+	daemonconsts "github.com/openshift/machine-config-operator/pkg/daemon/constants"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Condition of the ClusterVersion set while the cluster version operator can not apply the desired version
+const clusterVersionFailing configv1.ClusterStatusConditionType = "Failing"
+
+// Reasons of the steps blocked by the cluster
+const (
+	ReasonClusterVersionFailing = "ClusterVersionFailing"
+	ReasonNodeDegraded          = "NodeDegraded"
+	ReasonRenderDegraded        = "RenderDegraded"
+)
+
+// stepBlockedError is returned by a step which can not progress until the cluster is fixed.
+// The step is not done and the upgrade carries on once the cluster recovers.
+type stepBlockedError struct {
+	// CamelCase code recorded as the reason of the step condition
+	reason string
+	msg    string
+}
+
+func (e *stepBlockedError) Error() string {
+	return e.msg
+}
+
+// clusterVersionBlocked returns why the cluster version operator can not apply the desired version, nil unless it is failing
+func clusterVersionBlocked(clusterVersion *configv1.ClusterVersion) *stepBlockedError {
+	var failing, progressing *configv1.ClusterOperatorStatusCondition
+	for i, c := range clusterVersion.Status.Conditions {
+		switch c.Type {
+		case clusterVersionFailing:
+			failing = &clusterVersion.Status.Conditions[i]
+		case configv1.OperatorProgressing:
+			progressing = &clusterVersion.Status.Conditions[i]
+		}
+	}
+	if failing == nil || failing.Status != configv1.ConditionTrue {
+		return nil
+	}
+
+	msg := fmt.Sprintf("the cluster version operator is failing to apply %s since %s", clusterVersion.Status.Desired.Version, failing.LastTransitionTime.UTC().Format(time.RFC3339))
+	if len(failing.Reason) > 0 {
+		msg += fmt.Sprintf(" (%s)", failing.Reason)
+	}
+	if len(failing.Message) > 0 {
+		msg += ": " + failing.Message
+	}
+	if progressing != nil && len(progressing.Message) > 0 {
+		msg += ". Progressing: " + progressing.Message
+	}
+	return &stepBlockedError{reason: ReasonClusterVersionFailing, msg: msg}
+}
+
+// poolBlocked returns why the nodes of the MachineConfigPool can not be updated, nil unless the pool is degraded.
+// The nodes the machine config daemon reports as degraded are named along with the reason it gives.
+func poolBlocked(c client.Client, pool *machineconfigapi.MachineConfigPool) (*stepBlockedError, error) {
+	render := machineconfigapi.GetMachineConfigPoolCondition(pool.Status, machineconfigapi.MachineConfigPoolRenderDegraded)
+	if render != nil && render.Status == corev1.ConditionTrue {
+		return &stepBlockedError{
+			reason: ReasonRenderDegraded,
+			msg:    fmt.Sprintf("the configuration of MachineConfigPool %s can not be rendered, check its MachineConfigs: %s", pool.Name, render.Message),
+		}, nil
+	}
+
+	node := machineconfigapi.GetMachineConfigPoolCondition(pool.Status, machineconfigapi.MachineConfigPoolNodeDegraded)
+	if node == nil || node.Status != corev1.ConditionTrue {
+		return nil, nil
+	}
+	msg := fmt.Sprintf("%d nodes of MachineConfigPool %s are degraded: %s", pool.Status.DegradedMachineCount, pool.Name, node.Message)
+	degraded, err := degradedNodes(c, pool)
+	if err != nil {
+		return nil, err
+	}
+	if len(degraded) > 0 {
+		msg += ". Machine config daemon: " + strings.Join(degraded, "; ")
+	}
+	return &stepBlockedError{reason: ReasonNodeDegraded, msg: msg}, nil
+}
+
+// degradedNodes describes the nodes of the pool whose machine config daemon is degraded or can not reconcile, sorted by name
+func degradedNodes(c client.Client, pool *machineconfigapi.MachineConfigPool) ([]string, error) {
+	if pool.Spec.NodeSelector == nil {
+		return nil, nil
+	}
+	selector, err := metav1.LabelSelectorAsSelector(pool.Spec.NodeSelector)
+	if err != nil {
+		return nil, err
+	}
+	nodes := &corev1.NodeList{}
+	err = c.List(context.TODO(), nodes, client.MatchingLabelsSelector{Selector: selector})
+	if err != nil {
+		return nil, err
+	}
+
+	degraded := []string{}
+	for _, n := range nodes.Items {
+		state := n.Annotations[daemonconsts.MachineConfigDaemonStateAnnotationKey]
+		if state != daemonconsts.MachineConfigDaemonStateDegraded && state != daemonconsts.MachineConfigDaemonStateUnreconcilable {
+			continue
+		}
+		degraded = append(degraded, fmt.Sprintf("%s is %s applying %s: %s", n.Name, state,
+			n.Annotations[daemonconsts.DesiredMachineConfigAnnotationKey], n.Annotations[daemonconsts.MachineConfigDaemonReasonAnnotationKey]))
+	}
+	sort.Strings(degraded)
+	return degraded, nil
+}
@@ -0,0 +1,130 @@
+package cluster_upgrader
+
+import (
+	"time"
+
+	"github.com/golang/mock/gomock"
+	configv1 "github.com/openshift/api/config/v1"
+	machineconfigapi "github.com/openshift/machine-config-operator/pkg/apis/machineconfiguration.openshift.io/v1"
+	daemonconsts "github.com/openshift/machine-config-operator/pkg/daemon/constants"
+	"github.com/openshift/managed-upgrade-operator/util/mocks"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Blocked steps", func() {
+	var (
+		mockCtrl       *gomock.Controller
+		mockKubeClient *mocks.MockClient
+	)
+
+	BeforeEach(func() {
+		mockCtrl = gomock.NewController(GinkgoT())
+		mockKubeClient = mocks.NewMockClient(mockCtrl)
+	})
+
+	AfterEach(func() {
+		mockCtrl.Finish()
+	})
+
+	Context("When the cluster version operator applies the desired version", func() {
+		var clusterVersion *configv1.ClusterVersion
+
+		BeforeEach(func() {
+			clusterVersion = &configv1.ClusterVersion{Status: configv1.ClusterVersionStatus{Desired: configv1.Update{Version: "4.5.1"}}}
+		})
+
+		It("is not blocked while not failing", func() {
+			clusterVersion.Status.Conditions = []configv1.ClusterOperatorStatusCondition{
+				{Type: clusterVersionFailing, Status: configv1.ConditionFalse},
+				{Type: configv1.OperatorProgressing, Status: configv1.ConditionTrue, Message: "Working towards 4.5.1"},
+			}
+			Expect(clusterVersionBlocked(clusterVersion)).To(BeNil())
+		})
+
+		It("reports the failure with its reason, message and the progress", func() {
+			since := metav1.NewTime(time.Date(2020, 6, 1, 10, 0, 0, 0, time.UTC))
+			clusterVersion.Status.Conditions = []configv1.ClusterOperatorStatusCondition{
+				{Type: clusterVersionFailing, Status: configv1.ConditionTrue, LastTransitionTime: since, Reason: "ClusterOperatorDegraded", Message: "Cluster operator dns is degraded"},
+				{Type: configv1.OperatorProgressing, Status: configv1.ConditionTrue, Message: "Unable to apply 4.5.1"},
+			}
+			blocked := clusterVersionBlocked(clusterVersion)
+			Expect(blocked.reason).To(Equal(ReasonClusterVersionFailing))
+			Expect(blocked.Error()).To(Equal("the cluster version operator is failing to apply 4.5.1 since 2020-06-01T10:00:00Z (ClusterOperatorDegraded): Cluster operator dns is degraded. Progressing: Unable to apply 4.5.1"))
+		})
+
+		It("leaves out what the conditions do not say", func() {
+			since := metav1.NewTime(time.Date(2020, 6, 1, 10, 0, 0, 0, time.UTC))
+			clusterVersion.Status.Conditions = []configv1.ClusterOperatorStatusCondition{
+				{Type: clusterVersionFailing, Status: configv1.ConditionTrue, LastTransitionTime: since},
+			}
+			Expect(clusterVersionBlocked(clusterVersion).Error()).To(Equal("the cluster version operator is failing to apply 4.5.1 since 2020-06-01T10:00:00Z"))
+		})
+	})
+
+	Context("When the nodes of a MachineConfigPool are updated", func() {
+		var pool *machineconfigapi.MachineConfigPool
+
+		BeforeEach(func() {
+			pool = &machineconfigapi.MachineConfigPool{
+				ObjectMeta: metav1.ObjectMeta{Name: "worker"},
+				Spec: machineconfigapi.MachineConfigPoolSpec{
+					NodeSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"node-role.kubernetes.io/worker": ""}},
+				},
+				Status: machineconfigapi.MachineConfigPoolStatus{DegradedMachineCount: 1},
+			}
+		})
+
+		setCondition := func(conditionType machineconfigapi.MachineConfigPoolConditionType, message string) {
+			pool.Status.Conditions = append(pool.Status.Conditions, machineconfigapi.MachineConfigPoolCondition{Type: conditionType, Status: corev1.ConditionTrue, Message: message})
+		}
+		node := func(name string, state string) corev1.Node {
+			return corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: name, Annotations: map[string]string{
+				daemonconsts.MachineConfigDaemonStateAnnotationKey:  state,
+				daemonconsts.DesiredMachineConfigAnnotationKey:      "rendered-worker-2",
+				daemonconsts.MachineConfigDaemonReasonAnnotationKey: "failed to drain " + name,
+			}}}
+		}
+
+		It("is not blocked while the pool is not degraded", func() {
+			blocked, err := poolBlocked(mockKubeClient, pool)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(blocked).To(BeNil())
+		})
+
+		It("reports a configuration which can not be rendered over degraded nodes", func() {
+			setCondition(machineconfigapi.MachineConfigPoolNodeDegraded, "node worker-0 is degraded")
+			setCondition(machineconfigapi.MachineConfigPoolRenderDegraded, "machineconfig 99-worker is invalid")
+			mockKubeClient.EXPECT().List(gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+			blocked, err := poolBlocked(mockKubeClient, pool)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(blocked.reason).To(Equal(ReasonRenderDegraded))
+			Expect(blocked.Error()).To(ContainSubstring("machineconfig 99-worker is invalid"))
+		})
+
+		It("names the degraded nodes along with what their machine config daemon reports, sorted", func() {
+			setCondition(machineconfigapi.MachineConfigPoolNodeDegraded, "2 nodes are reporting degraded status on sync")
+			mockKubeClient.EXPECT().List(gomock.Any(), gomock.Any(), gomock.Any()).SetArg(1, corev1.NodeList{Items: []corev1.Node{
+				node("worker-2", daemonconsts.MachineConfigDaemonStateUnreconcilable),
+				node("worker-0", daemonconsts.MachineConfigDaemonStateDone),
+				node("worker-1", daemonconsts.MachineConfigDaemonStateDegraded),
+			}})
+			blocked, err := poolBlocked(mockKubeClient, pool)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(blocked.reason).To(Equal(ReasonNodeDegraded))
+			Expect(blocked.Error()).To(Equal("1 nodes of MachineConfigPool worker are degraded: 2 nodes are reporting degraded status on sync. Machine config daemon: " +
+				"worker-1 is Degraded applying rendered-worker-2: failed to drain worker-1; " +
+				"worker-2 is Unreconcilable applying rendered-worker-2: failed to drain worker-2"))
+		})
+
+		It("does not list the nodes of a pool without node selector", func() {
+			pool.Spec.NodeSelector = nil
+			nodes, err := degradedNodes(mockKubeClient, pool)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(nodes).To(BeEmpty())
+		})
+	})
+})
@@ -2,26 +2,27 @@ package cluster_upgrader
 
 import (
 	"context"
-	"crypto/tls"
-	"encoding/json"
 	"fmt"
-	"io/ioutil"
-	"net/http"
 	"net/url"
 	"runtime"
 	"sort"
-	"strings"
-	"sync"
 	"time"
 
+	"github.com/openshift/managed-upgrade-operator/pkg/estimator"
+	"github.com/openshift/managed-upgrade-operator/pkg/gates"
+	"github.com/openshift/managed-upgrade-operator/pkg/healthcheck"
 	"github.com/openshift/managed-upgrade-operator/pkg/maintenance"
 	"github.com/openshift/managed-upgrade-operator/pkg/metrics"
+	"github.com/openshift/managed-upgrade-operator/pkg/monitor"
+	"github.com/openshift/managed-upgrade-operator/pkg/operatorconfig"
+	"github.com/openshift/managed-upgrade-operator/pkg/progress"
+	"github.com/openshift/managed-upgrade-operator/pkg/prometheus"
+	"github.com/openshift/managed-upgrade-operator/pkg/upgradestatus"
 
 	"github.com/blang/semver"
 	"github.com/go-logr/logr"
 	"github.com/google/uuid"
 	configv1 "github.com/openshift/api/config/v1"
-	routev1 "github.com/openshift/api/route/v1"
 	"github.com/openshift/cluster-version-operator/pkg/cincinnati"
 	machineapi "github.com/openshift/machine-api-operator/pkg/apis/machine/v1beta1"
 	machineconfigapi "github.com/openshift/machine-config-operator/pkg/apis/machineconfiguration.openshift.io/v1"
@@ -37,12 +38,11 @@ import (
 )
 
 var (
-	once                sync.Once
-	osdUpgradeSteps     UpgradeSteps
 	UpgradeStepOrdering = []upgradev1alpha1.UpgradeConditionType{
 		upgradev1alpha1.UpgradeValidated,
 		upgradev1alpha1.UpgradePreHealthCheck,
 		upgradev1alpha1.UpgradeScaleUpExtraNodes,
+		upgradev1alpha1.AwaitingApproval,
 		upgradev1alpha1.CommenceUpgrade,
 		upgradev1alpha1.ControlPlaneMaintWindow,
 		upgradev1alpha1.ControlPlaneUpgraded,
@@ -53,20 +53,23 @@ var (
 		upgradev1alpha1.RemoveExtraScaledNodes,
 		upgradev1alpha1.UpdateSubscriptions,
 		upgradev1alpha1.PostUpgradeVerification,
+		upgradev1alpha1.RestoreOverrides,
 		upgradev1alpha1.RemoveMaintWindow,
 		upgradev1alpha1.PostClusterHealthCheck,
+		upgradev1alpha1.RegressionCheck,
 	}
 )
 
 const (
-	TIMEOUT_SCALE_EXTRAL_NODES = 30 * time.Minute
-	LABEL_UPGRADE              = "upgrade.managed.openshift.io"
+	TIMEOUT_APPROVAL = 24 * time.Hour
+	LABEL_UPGRADE    = "upgrade.managed.openshift.io"
 )
 
 // Interface describing the functions of a cluster upgrader.
 //go:generate mockgen -destination=mocks/cluster_upgrader.go -package=mocks github.com/openshift/managed-upgrade-operator/pkg/cluster_upgrader ClusterUpgrader
 type ClusterUpgrader interface {
 	UpgradeCluster(upgradeConfig *upgradev1alpha1.UpgradeConfig, logger logr.Logger) error
+	DryRun(upgradeConfig *upgradev1alpha1.UpgradeConfig, logger logr.Logger) error
 }
 
 //go:generate mockgen -destination=mocks/cluster_upgrader_builder.go -package=mocks github.com/openshift/managed-upgrade-operator/pkg/cluster_upgrader ClusterUpgraderBuilder
@@ -77,6 +80,8 @@ type ClusterUpgraderBuilder interface {
 func NewBuilder() ClusterUpgraderBuilder {
 	return &clusterUpgraderBuilder{
 		maintenanceBuilder: maintenance.NewBuilder(),
+		gateBuilder:        gates.NewBuilder(),
+		prometheusBuilder:  prometheus.NewBuilder(),
 	}
 }
 
@@ -88,6 +93,8 @@ type UpgradeStep func(c client.Client, metricsClient metrics.Metrics, m maintena
 
 type clusterUpgraderBuilder struct {
 	maintenanceBuilder maintenance.MaintenanceBuilder
+	gateBuilder        gates.GateBuilder
+	prometheusBuilder  prometheus.PrometheusClientBuilder
 }
 
 func (cub *clusterUpgraderBuilder) NewClient(c client.Client) (ClusterUpgrader, error) {
@@ -96,31 +103,37 @@ func (cub *clusterUpgraderBuilder) NewClient(c client.Client) (ClusterUpgrader,
 	if err != nil {
 		return nil, err
 	}
+	g, err := cub.gateBuilder.NewClient(c)
+	if err != nil {
+		return nil, err
+	}
 
-	once.Do(func() {
-		osdUpgradeSteps = map[upgradev1alpha1.UpgradeConditionType]UpgradeStep{
-			upgradev1alpha1.UpgradeValidated:              ValidateUpgradeConfig,
-			upgradev1alpha1.UpgradePreHealthCheck:         PreClusterHealthCheck,
-			upgradev1alpha1.UpgradeScaleUpExtraNodes:      EnsureExtraUpgradeWorkers,
-			upgradev1alpha1.ControlPlaneMaintWindow:       CreateControlPlaneMaintWindow,
-			upgradev1alpha1.CommenceUpgrade:               CommenceUpgrade,
-			upgradev1alpha1.ControlPlaneUpgraded:          ControlPlaneUpgraded,
-			upgradev1alpha1.AllMasterNodesUpgraded:        AllMastersUpgraded,
-			upgradev1alpha1.RemoveControlPlaneMaintWindow: RemoveControlPlaneMaintWindow,
-			upgradev1alpha1.WorkersMaintWindow:            CreateWorkerMaintWindow,
-			upgradev1alpha1.AllWorkerNodesUpgraded:        AllWorkersUpgraded,
-			upgradev1alpha1.RemoveExtraScaledNodes:        RemoveExtraScaledNodes,
-			upgradev1alpha1.UpdateSubscriptions:           UpdateSubscriptions,
-			upgradev1alpha1.PostUpgradeVerification:       PostUpgradeVerification,
-			upgradev1alpha1.RemoveMaintWindow:             RemoveMaintWindow,
-			upgradev1alpha1.PostClusterHealthCheck:        PostClusterHealthCheck,
-		}
-	})
+	osdUpgradeSteps := map[upgradev1alpha1.UpgradeConditionType]UpgradeStep{
+		upgradev1alpha1.UpgradeValidated:              ValidateUpgradeConfig,
+		upgradev1alpha1.UpgradePreHealthCheck:         PreClusterHealthCheck,
+		upgradev1alpha1.UpgradeScaleUpExtraNodes:      EnsureExtraUpgradeWorkers,
+		upgradev1alpha1.AwaitingApproval:              AwaitApproval,
+		upgradev1alpha1.ControlPlaneMaintWindow:       CreateControlPlaneMaintWindow,
+		upgradev1alpha1.CommenceUpgrade:               CommenceUpgrade,
+		upgradev1alpha1.ControlPlaneUpgraded:          ControlPlaneUpgraded,
+		upgradev1alpha1.AllMasterNodesUpgraded:        AllMastersUpgraded,
+		upgradev1alpha1.RemoveControlPlaneMaintWindow: RemoveControlPlaneMaintWindow,
+		upgradev1alpha1.WorkersMaintWindow:            CreateWorkerMaintWindow,
+		upgradev1alpha1.AllWorkerNodesUpgraded:        AllWorkersUpgraded,
+		upgradev1alpha1.RemoveExtraScaledNodes:        RemoveExtraScaledNodes,
+		upgradev1alpha1.UpdateSubscriptions:           UpdateSubscriptions,
+		upgradev1alpha1.PostUpgradeVerification:       PostUpgradeVerification,
+		upgradev1alpha1.RestoreOverrides:              RestoreOverrides,
+		upgradev1alpha1.RemoveMaintWindow:             RemoveMaintWindow,
+		upgradev1alpha1.PostClusterHealthCheck:        PostClusterHealthCheck,
+		upgradev1alpha1.RegressionCheck:               RegressionCheck(cub.prometheusBuilder),
+	}
 	return &clusterUpgrader{
 		Steps:       osdUpgradeSteps,
 		client:      c,
 		maintenance: m,
 		metrics:     metricsClient,
+		gate:        g,
 	}, nil
 }
 
@@ -130,6 +143,7 @@ type clusterUpgrader struct {
 	client      client.Client
 	maintenance maintenance.Maintenance
 	metrics     metrics.Metrics
+	gate        gates.Gate
 }
 
 // Ordering returns the ordering of predicates.
@@ -137,16 +151,14 @@ func Ordering() []upgradev1alpha1.UpgradeConditionType {
 	return UpgradeStepOrdering
 }
 
-// ClusterHealthCheck performs cluster healthy check
+// PreClusterHealthCheck performs the health checks configured before the upgrade and records their results in the status
 func PreClusterHealthCheck(c client.Client, metricsClient metrics.Metrics, m maintenance.Maintenance, upgradeConfig *upgradev1alpha1.UpgradeConfig, logger logr.Logger) (bool, error) {
-	ok, err := performClusterHealthCheck(c, logger)
-	if err != nil || !ok {
-		metricsClient.UpdateMetricClusterCheckFailed(upgradeConfig.Name)
+	results := healthcheck.Run(c, operatorconfig.PreUpgrade, upgradeConfig, logger)
+	err := upgradestatus.Patch(c, upgradeConfig, upgradestatus.SetPreflightResults(healthcheck.PreflightResults(results)))
+	if err != nil {
 		return false, err
 	}
-
-	metricsClient.UpdateMetricClusterCheckSucceeded(upgradeConfig.Name)
-	return true, nil
+	return clusterHealthy(metricsClient, results, upgradeConfig)
 }
 
 // This will create a new machineset with 1 extra replicas for workers in every region
@@ -222,13 +234,14 @@ func EnsureExtraUpgradeWorkers(c client.Client, metricsClient metrics.Metrics, m
 		logger.Error(err, "failed to list nodes")
 		return false, err
 	}
+	scaleTimeout := operatorconfig.Get().ScaleTimeout()
 	allNodeReady := true
 	for _, ms := range upgradeMachinesets.Items {
 		//We assume the create time is the start time for scale up extra compute nodes
 		startTime := ms.CreationTimestamp
 		if ms.Status.Replicas != ms.Status.ReadyReplicas {
 
-			if time.Now().After(startTime.Time.Add(TIMEOUT_SCALE_EXTRAL_NODES)) {
+			if time.Now().After(startTime.Time.Add(scaleTimeout)) {
 				//TODO send out timeout alerts
 				logger.Info("machineset provisioning timout")
 			}
@@ -260,8 +273,8 @@ func EnsureExtraUpgradeWorkers(c client.Client, metricsClient metrics.Metrics, m
 		}
 		if !nodeReady {
 			allNodeReady = false
-			if time.Now().After(startTime.Time.Add(TIMEOUT_SCALE_EXTRAL_NODES)) {
-				logger.Info("node is not ready within 30mins")
+			if time.Now().After(startTime.Time.Add(scaleTimeout)) {
+				logger.Info(fmt.Sprintf("node is not ready within %s", scaleTimeout))
 				//TODO send out timeout alerts
 				return false, fmt.Errorf("timeout waiting for node:%s to become ready", nodeName)
 
@@ -277,6 +290,55 @@ func EnsureExtraUpgradeWorkers(c client.Client, metricsClient metrics.Metrics, m
 
 }
 
+// approval tells who approved the upgrade to the desired version, empty until approved
+func approval(upgradeConfig *upgradev1alpha1.UpgradeConfig) string {
+	approver := upgradeConfig.Annotations[upgradev1alpha1.ApprovedByAnnotation]
+	if len(approver) == 0 || upgradeConfig.Annotations[upgradev1alpha1.ApprovedVersionAnnotation] != upgradeConfig.Spec.Desired.Version {
+		return ""
+	}
+	return fmt.Sprintf("upgrade to %s approved by %s", upgradeConfig.Spec.Desired.Version, approver)
+}
+
+// AwaitApproval waits for the approval annotations to be set on the UpgradeConfig for the desired version.
+// If the approval is not given within the approval timeout, the extra upgrade workers are removed and the upgrade fails.
+func AwaitApproval(c client.Client, metricsClient metrics.Metrics, m maintenance.Maintenance, upgradeConfig *upgradev1alpha1.UpgradeConfig, logger logr.Logger) (bool, error) {
+	if approved := approval(upgradeConfig); len(approved) > 0 {
+		logger.Info(approved)
+		// The approver is kept with the step so that approvals can be audited
+		return true, completeStep(c, upgradeConfig, upgradev1alpha1.AwaitingApproval, approved)
+	}
+
+	timeout := TIMEOUT_APPROVAL
+	if upgradeConfig.Spec.ApprovalTimeoutMinutes > 0 {
+		timeout = time.Duration(upgradeConfig.Spec.ApprovalTimeoutMinutes) * time.Minute
+	}
+	history := upgradeConfig.Status.History.GetHistory(upgradeConfig.Spec.Desired.Version)
+	condition := history.Conditions.GetCondition(upgradev1alpha1.AwaitingApproval)
+	if condition != nil && condition.StartTime != nil && time.Now().After(condition.StartTime.Add(timeout)) {
+		logger.Info(fmt.Sprintf("upgrade to %s was not approved within %s, removing extra upgrade workers", upgradeConfig.Spec.Desired.Version, timeout))
+		_, err := RemoveExtraScaledNodes(c, metricsClient, m, upgradeConfig, logger)
+		if err != nil {
+			return false, err
+		}
+		return false, &failedStepError{fmt.Sprintf("upgrade was not approved within %s", timeout)}
+	}
+
+	logger.Info(fmt.Sprintf("waiting for annotations %s and %s=%s", upgradev1alpha1.ApprovedByAnnotation, upgradev1alpha1.ApprovedVersionAnnotation, upgradeConfig.Spec.Desired.Version))
+	return false, nil
+}
+
+// completeStep records the step of the desired version as done with a message describing how it completed.
+// UpgradeCluster keeps the condition of a step which completed itself, instead of recording that it succeeded.
+func completeStep(c client.Client, upgradeConfig *upgradev1alpha1.UpgradeConfig, key upgradev1alpha1.UpgradeConditionType, message string) error {
+	condition := newUpgradeCondition(fmt.Sprintf("%s succeed", key), message, key, corev1.ConditionTrue)
+	history := upgradeConfig.Status.History.GetHistory(upgradeConfig.Spec.Desired.Version)
+	if recorded := history.Conditions.GetCondition(key); recorded != nil {
+		condition.StartTime = recorded.StartTime
+	}
+	condition.CompleteTime = &metav1.Time{Time: time.Now()}
+	return upgradestatus.Patch(c, upgradeConfig, upgradestatus.SetHistoryCondition(upgradeConfig.Spec.Desired.Version, *condition))
+}
+
 // CommenceUpgrade will update the clusterversion object to apply the desired version to trigger real OCP upgrade
 func CommenceUpgrade(c client.Client, metricsClient metrics.Metrics, m maintenance.Maintenance, upgradeConfig *upgradev1alpha1.UpgradeConfig, logger logr.Logger) (bool, error) {
 	clusterVersion := &configv1.ClusterVersion{}
@@ -289,23 +351,122 @@ func CommenceUpgrade(c client.Client, metricsClient metrics.Metrics, m maintenan
 		clusterVersion.Spec.Channel == upgradeConfig.Spec.Desired.Channel {
 		return true, nil
 	}
-	// https://issues.redhat.com/browse/OSD-3442
-	clusterVersion.Spec.Overrides = []configv1.ComponentOverride{}
+
+	patch := client.MergeFrom(clusterVersion.DeepCopy())
+	// Without policy all overrides are removed, https://issues.redhat.com/browse/OSD-3442
+	kept, removed := splitOverrides(clusterVersion.Spec.Overrides, upgradeConfig.Spec.OverridePolicy)
+	// The removed overrides are only recorded to be restored, the ones left by a past upgrade which did not restore them
+	// must not be restored by a later one
+	restore := isStepEnabled(upgradev1alpha1.RestoreOverrides, upgradeConfig)
+	if !restore && len(upgradeConfig.Status.RemovedOverrides) > 0 {
+		err = upgradestatus.Patch(c, upgradeConfig, upgradestatus.SetRemovedOverrides(nil))
+		if err != nil {
+			return false, err
+		}
+	}
+	if len(removed) > 0 {
+		logger.Info(fmt.Sprintf("removing %d clusterversion overrides", len(removed)))
+		if restore {
+			// Record the overrides before removing them, so they can not be lost
+			err = upgradestatus.Patch(c, upgradeConfig, upgradestatus.SetRemovedOverrides(mergeOverrides(upgradeConfig.Status.RemovedOverrides, removed)))
+			if err != nil {
+				return false, err
+			}
+		}
+		// Guard the patch with the resourceVersion, so an override added in the meantime is not dropped
+		base := clusterVersion.DeepCopy()
+		base.ResourceVersion = ""
+		patch = client.MergeFrom(base)
+		clusterVersion.Spec.Overrides = kept
+	}
 	clusterVersion.Spec.DesiredUpdate = &configv1.Update{Version: upgradeConfig.Spec.Desired.Version}
 	clusterVersion.Spec.Channel = upgradeConfig.Spec.Desired.Channel
 
 	//Record the timestamp when we start the upgrade
 	metricsClient.UpdateMetricUpgradeStartTime(time.Now(), upgradeConfig.Name)
-	err = c.Update(context.TODO(), clusterVersion)
+	err = c.Patch(context.TODO(), clusterVersion, patch)
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// RestoreOverrides puts back the ClusterVersion overrides removed to upgrade the cluster
+func RestoreOverrides(c client.Client, metricsClient metrics.Metrics, m maintenance.Maintenance, upgradeConfig *upgradev1alpha1.UpgradeConfig, logger logr.Logger) (bool, error) {
+	if len(upgradeConfig.Status.RemovedOverrides) == 0 {
+		return true, nil
+	}
+	clusterVersion := &configv1.ClusterVersion{}
+	err := c.Get(context.TODO(), types.NamespacedName{Name: "version"}, clusterVersion)
+	if err != nil {
+		return false, err
+	}
+
+	base := clusterVersion.DeepCopy()
+	base.ResourceVersion = ""
+	overrides := mergeOverrides(clusterVersion.Spec.Overrides, upgradeConfig.Status.RemovedOverrides)
+	if len(overrides) != len(clusterVersion.Spec.Overrides) {
+		logger.Info(fmt.Sprintf("restoring %d clusterversion overrides", len(overrides)-len(clusterVersion.Spec.Overrides)))
+		clusterVersion.Spec.Overrides = overrides
+		err = c.Patch(context.TODO(), clusterVersion, client.MergeFrom(base))
+		if err != nil {
+			return false, err
+		}
+	}
+
+	err = upgradestatus.Patch(c, upgradeConfig, upgradestatus.SetRemovedOverrides(nil))
 	if err != nil {
 		return false, err
 	}
 	return true, nil
 }
 
-// Create the maintenance window for control plane
+// splitOverrides separates the overrides kept by the policy from the ones it removes
+func splitOverrides(overrides []configv1.ComponentOverride, policy *upgradev1alpha1.OverridePolicy) ([]configv1.ComponentOverride, []configv1.ComponentOverride) {
+	kept := []configv1.ComponentOverride{}
+	removed := []configv1.ComponentOverride{}
+	for _, o := range overrides {
+		if policy.Removes(o) {
+			removed = append(removed, o)
+		} else {
+			kept = append(kept, o)
+		}
+	}
+	return kept, removed
+}
+
+// mergeOverrides appends the additional overrides whose component is not overridden yet
+func mergeOverrides(overrides []configv1.ComponentOverride, additional []configv1.ComponentOverride) []configv1.ComponentOverride {
+	merged := append([]configv1.ComponentOverride{}, overrides...)
+	for _, a := range additional {
+		found := false
+		for _, o := range merged {
+			if o.Kind == a.Kind && o.Group == a.Group && o.Namespace == a.Namespace && o.Name == a.Name {
+				found = true
+				break
+			}
+		}
+		if !found {
+			merged = append(merged, a)
+		}
+	}
+	return merged
+}
+
+// Create the maintenance window for control plane, sized by the estimated duration of the control plane and master nodes upgrade
 func CreateControlPlaneMaintWindow(c client.Client, metricsClient metrics.Metrics, m maintenance.Maintenance, upgradeConfig *upgradev1alpha1.UpgradeConfig, logger logr.Logger) (bool, error) {
-	endTime := time.Now().Add(90 * time.Minute)
+	var estimate *upgradev1alpha1.UpgradeEstimate
+	if history := upgradeConfig.Status.History.GetHistory(upgradeConfig.Spec.Desired.Version); history != nil {
+		estimate = history.Estimate
+	}
+	if estimate == nil {
+		var err error
+		estimate, err = estimateUpgrade(c, upgradeConfig, false)
+		if err != nil {
+			return false, err
+		}
+	}
+	endTime := time.Now().Add(time.Duration(estimate.ControlPlaneMinutes+estimate.MasterNodesMinutes) * time.Minute)
 	err := m.StartControlPlane(endTime)
 	if err != nil {
 		return false, err
@@ -324,18 +485,14 @@ func RemoveControlPlaneMaintWindow(c client.Client, metricsClient metrics.Metric
 	return true, nil
 }
 
-// Create the maintenance window for workers
+// Create the maintenance window for workers, sized by the estimated duration of the pending worker nodes upgrade
 func CreateWorkerMaintWindow(c client.Client, metricsClient metrics.Metrics, m maintenance.Maintenance, upgradeConfig *upgradev1alpha1.UpgradeConfig, logger logr.Logger) (bool, error) {
-	configPool := &machineconfigapi.MachineConfigPool{}
-	err := c.Get(context.TODO(), types.NamespacedName{Name: "worker"}, configPool)
+	estimate, err := estimateUpgrade(c, upgradeConfig, true)
 	if err != nil {
 		return false, nil
 	}
 
-	pendingWorkerCount := configPool.Status.MachineCount - configPool.Status.UpdatedMachineCount
-	maintenanceDurationPerNode := 8 * time.Minute
-	workerMaintenanceExpectedDuration := time.Duration(pendingWorkerCount) * maintenanceDurationPerNode
-	endTime := time.Now().Add(workerMaintenanceExpectedDuration)
+	endTime := time.Now().Add(time.Duration(estimate.WorkerNodesMinutes) * time.Minute)
 	err = m.StartWorker(endTime)
 	if err != nil {
 		return false, err
@@ -344,6 +501,22 @@ func CreateWorkerMaintWindow(c client.Client, metricsClient metrics.Metrics, m m
 	return true, nil
 }
 
+// estimateUpgrade predicts the duration of the upgrade stages from the MachineConfigPools and the past upgrades.
+// If pendingOnly is set, only the nodes not yet upgraded are accounted for.
+func estimateUpgrade(c client.Client, upgradeConfig *upgradev1alpha1.UpgradeConfig, pendingOnly bool) (*upgradev1alpha1.UpgradeEstimate, error) {
+	masterPool := &machineconfigapi.MachineConfigPool{}
+	err := c.Get(context.TODO(), types.NamespacedName{Name: "master"}, masterPool)
+	if err != nil {
+		return nil, err
+	}
+	workerPool := &machineconfigapi.MachineConfigPool{}
+	err = c.Get(context.TODO(), types.NamespacedName{Name: "worker"}, workerPool)
+	if err != nil {
+		return nil, err
+	}
+	return estimator.Estimate(estimator.NewPool(masterPool, pendingOnly), estimator.NewPool(workerPool, pendingOnly), upgradeConfig.Status.History), nil
+}
+
 // This check whether all the master nodes are ready with new config
 func AllMastersUpgraded(c client.Client, metricsClient metrics.Metrics, m maintenance.Maintenance, upgradeConfig *upgradev1alpha1.UpgradeConfig, logger logr.Logger) (bool, error) {
 
@@ -353,6 +526,10 @@ func AllMastersUpgraded(c client.Client, metricsClient metrics.Metrics, m mainte
 
 // This check whether all the worker nodes are ready with new config
 func AllWorkersUpgraded(c client.Client, metricsClient metrics.Metrics, m maintenance.Maintenance, upgradeConfig *upgradev1alpha1.UpgradeConfig, logger logr.Logger) (bool, error) {
+	if monitor.IsPaused(upgradeConfig) {
+		logger.Info(fmt.Sprintf("the worker upgrade is paused by the monitor until the %s annotation is set", upgradev1alpha1.ResumeAnnotation))
+		return false, nil
+	}
 	ok, err := nodesUpgraded(c, "worker", logger)
 	if err != nil || !ok {
 		return false, err
@@ -376,7 +553,7 @@ func RemoveExtraScaledNodes(c client.Client, metricsClient metrics.Metrics, m ma
 	}
 	for _, item := range upgradeMachinesets.Items {
 		err = c.Delete(context.TODO(), &item)
-		if err != nil {
+		if err != nil && !errors.IsNotFound(err) {
 			return false, err
 		}
 	}
@@ -423,6 +600,7 @@ func PostUpgradeVerification(c client.Client, metricsClient metrics.Metrics, m m
 
 // performPostUpgradeVerification verify all replicasets are at expected counts and all daemonsets are at expected counts
 func performUpgradeVerification(c client.Client, logger logr.Logger) (bool, error) {
+	cfg := operatorconfig.Get()
 	replicaSetList := &appsv1.ReplicaSetList{}
 	err := c.List(context.TODO(), replicaSetList)
 	if err != nil {
@@ -431,9 +609,7 @@ func performUpgradeVerification(c client.Client, logger logr.Logger) (bool, erro
 	readyRs := 0
 	totalRs := 0
 	for _, replica := range replicaSetList.Items {
-		if strings.HasPrefix(replica.Namespace, "default") ||
-			strings.HasPrefix(replica.Namespace, "kube") ||
-			strings.HasPrefix(replica.Namespace, "openshift") {
+		if cfg.IsVerifiedNamespace(replica.Namespace) {
 			totalRs = totalRs + 1
 			if replica.Status.ReadyReplicas == replica.Status.Replicas {
 				readyRs = readyRs + 1
@@ -455,9 +631,7 @@ func performUpgradeVerification(c client.Client, logger logr.Logger) (bool, erro
 	readyDS := 0
 	totalDS := 0
 	for _, ds := range dsList.Items {
-		if strings.HasPrefix(ds.Namespace, "default") ||
-			strings.HasPrefix(ds.Namespace, "kube") ||
-			strings.HasPrefix(ds.Namespace, "openshift") {
+		if cfg.IsVerifiedNamespace(ds.Namespace) {
 			totalDS = totalDS + 1
 			if ds.Status.DesiredNumberScheduled == ds.Status.NumberReady {
 				readyDS = readyDS + 1
@@ -482,16 +656,41 @@ func RemoveMaintWindow(c client.Client, metricsClient metrics.Metrics, m mainten
 	return true, nil
 }
 
-// This perform cluster health check after upgrade
+// PostClusterHealthCheck performs the health checks configured after the upgrade, they must pass continuously
+// for the settle period. How long they took to settle is recorded in the history.
 func PostClusterHealthCheck(c client.Client, metricsClient metrics.Metrics, m maintenance.Maintenance, upgradeConfig *upgradev1alpha1.UpgradeConfig, logger logr.Logger) (bool, error) {
-	ok, err := performClusterHealthCheck(c, logger)
-	if err != nil || !ok {
-		metricsClient.UpdateMetricClusterCheckFailed(upgradeConfig.Name)
+	results := healthcheck.Run(c, operatorconfig.PostUpgrade, upgradeConfig, logger)
+	healthy, healthErr := clusterHealthy(metricsClient, results, upgradeConfig)
+
+	now := metav1.Now()
+	settle := &upgradev1alpha1.HealthCheckSettle{StartTime: &now}
+	if history := upgradeConfig.Status.History.GetHistory(upgradeConfig.Spec.Desired.Version); history != nil && history.Settle != nil {
+		settle = history.Settle.DeepCopy()
+	}
+	settled := false
+	if healthy {
+		if settle.PassingSince == nil {
+			settle.PassingSince = &now
+		}
+		period := operatorconfig.Get().SettlePeriod()
+		settled = now.Sub(settle.PassingSince.Time) >= period
+		if settled {
+			settle.SettledTime = &now
+			settle.SettleSeconds = int64(now.Sub(settle.StartTime.Time).Seconds())
+		} else {
+			logger.Info(fmt.Sprintf("health checks pass since %s, waiting for them to pass for %s", settle.PassingSince.Time, period))
+		}
+	} else {
+		if settle.PassingSince != nil {
+			settle.Flaps++
+		}
+		settle.PassingSince = nil
+	}
+	err := upgradestatus.Patch(c, upgradeConfig, upgradestatus.SetSettle(upgradeConfig.Spec.Desired.Version, *settle))
+	if err != nil {
 		return false, err
 	}
-
-	metricsClient.UpdateMetricClusterCheckSucceeded(upgradeConfig.Name)
-	return true, nil
+	return settled, healthErr
 }
 
 // Check whether nodes are upgraded or not
@@ -501,6 +700,13 @@ func nodesUpgraded(c client.Client, nodeType string, logger logr.Logger) (bool,
 	if err != nil {
 		return false, nil
 	}
+	blocked, err := poolBlocked(c, configPool)
+	if err != nil {
+		return false, err
+	}
+	if blocked != nil {
+		return false, blocked
+	}
 	if configPool.Status.MachineCount != configPool.Status.UpdatedMachineCount {
 		errMsg := fmt.Sprintf("not all %s are upgraded, upgraded: %v, total: %v", nodeType, configPool.Status.UpdatedMachineCount, configPool.Status.MachineCount)
 		logger.Info(errMsg)
@@ -523,9 +729,19 @@ func ControlPlaneUpgraded(c client.Client, metricsClient metrics.Metrics, m main
 		if c.State == configv1.CompletedUpdate && c.Version == upgradeConfig.Spec.Desired.Version {
 			// send controlplane upgrade complete timestamp
 			metricsClient.UpdateMetricControlPlaneEndTime(time.Now(), upgradeConfig.Name)
+			metricsClient.UpdateMetricControlPlaneOperatorsProgress(1, upgradeConfig.Name)
+			metricsClient.UpdateMetricControlPlaneManifestsProgress(1, upgradeConfig.Name)
 			return true, nil
 		}
 	}
+
+	err = recordControlPlaneProgress(c, metricsClient, upgradeConfig, clusterVersion)
+	if err != nil {
+		return false, err
+	}
+	if blocked := clusterVersionBlocked(clusterVersion); blocked != nil {
+		return false, blocked
+	}
 	return false, nil
 
 }
@@ -534,32 +750,52 @@ func ControlPlaneUpgraded(c client.Client, metricsClient metrics.Metrics, m main
 func (cu clusterUpgrader) UpgradeCluster(upgradeConfig *upgradev1alpha1.UpgradeConfig, logger logr.Logger) error {
 
 	logger.Info("upgrading cluster")
-	history := upgradeConfig.Status.History.GetHistory(upgradeConfig.Spec.Desired.Version)
-	conditions := history.Conditions
+	// Every status change is written as its own mutation of the history, re-applied on top of a fresh read should
+	// the UpgradeConfig have changed, so it never overwrites what the steps record in the history themselves.
+	// The start of a step is persisted before the step is performed and its completion before the next one is.
+	version := upgradeConfig.Spec.Desired.Version
+	history := upgradeConfig.Status.History.GetHistory(version)
 
 	if history.Phase != upgradev1alpha1.UpgradePhaseUpgrading {
-		history.Phase = upgradev1alpha1.UpgradePhaseUpgrading
-		history.StartTime = &metav1.Time{Time: time.Now()}
-		upgradeConfig.Status.History.SetHistory(*history)
-		err := cu.client.Status().Update(context.TODO(), upgradeConfig)
+		estimate, err := estimateUpgrade(cu.client, upgradeConfig, false)
 		if err != nil {
-			logger.Error(err, "failed to update upgradeconfig")
+			logger.Error(err, "failed to estimate the upgrade duration")
+		}
+		err = upgradestatus.Patch(cu.client, upgradeConfig,
+			upgradestatus.SetPhase(version, upgradev1alpha1.UpgradePhaseUpgrading),
+			upgradestatus.SetStartTime(version, metav1.Now()),
+			upgradestatus.SetEstimate(version, estimate))
+		if err != nil {
+			return err
 		}
 	}
 
 	for _, key := range Ordering() {
+		if !isStepEnabled(key, upgradeConfig) {
+			continue
+		}
 
 		logger.Info(fmt.Sprintf("Perform %s", key))
 
-		condition := conditions.GetCondition(key)
+		condition := upgradeConfig.Status.History.GetHistory(version).Conditions.GetCondition(key)
 		if condition == nil {
+			gateErr := cu.runGates(key, upgradeConfig, logger)
+			if _, ok := gateErr.(*failedStepError); ok {
+				err := upgradestatus.Patch(cu.client, upgradeConfig,
+					upgradestatus.SetPhase(version, upgradev1alpha1.UpgradePhaseFailed),
+					upgradestatus.SetCompleteTime(version, metav1.Now()))
+				if err != nil {
+					return err
+				}
+			}
+			if gateErr != nil {
+				return gateErr
+			}
+
 			logger.Info(fmt.Sprintf("Adding %s condition", key))
 			condition = newUpgradeCondition(fmt.Sprintf("start %s", key), fmt.Sprintf("start %s", key), key, corev1.ConditionFalse)
 			condition.StartTime = &metav1.Time{Time: time.Now()}
-			conditions.SetCondition(*condition)
-			history.Conditions = conditions
-			upgradeConfig.Status.History.SetHistory(*history)
-			err := cu.client.Status().Update(context.TODO(), upgradeConfig)
+			err := upgradestatus.Patch(cu.client, upgradeConfig, upgradestatus.SetHistoryCondition(version, *condition))
 			if err != nil {
 				return err
 			}
@@ -568,30 +804,37 @@ func (cu clusterUpgrader) UpgradeCluster(upgradeConfig *upgradev1alpha1.UpgradeC
 			logger.Info(fmt.Sprintf("%s already done, skip", key))
 			continue
 		}
-		result, err := cu.Steps[key](cu.client, cu.metrics, cu.maintenance, upgradeConfig, logger)
+		result, stepErr := cu.Steps[key](cu.client, cu.metrics, cu.maintenance, upgradeConfig, logger)
 
-		if err != nil {
-			logger.Error(err, fmt.Sprintf("error when %s", key))
+		if stepErr != nil {
+			logger.Error(stepErr, fmt.Sprintf("error when %s", key))
 			condition.Reason = fmt.Sprintf("%s not done", key)
-			condition.Message = err.Error()
-			conditions.SetCondition(*condition)
-			history.Conditions = conditions
-			upgradeConfig.Status.History.SetHistory(*history)
-			err = cu.client.Status().Update(context.TODO(), upgradeConfig)
+			if blocked, ok := stepErr.(*stepBlockedError); ok {
+				condition.Reason = blocked.reason
+			}
+			condition.Message = stepErr.Error()
+			mutations := []upgradestatus.Mutation{upgradestatus.SetHistoryCondition(version, *condition)}
+			if _, ok := stepErr.(*failedStepError); ok {
+				mutations = append(mutations,
+					upgradestatus.SetPhase(version, upgradev1alpha1.UpgradePhaseFailed),
+					upgradestatus.SetCompleteTime(version, metav1.Now()))
+			}
+			err := upgradestatus.Patch(cu.client, upgradeConfig, mutations...)
 			if err != nil {
 				return err
 			}
-			return err
+			return stepErr
 		}
 		if result {
+			if upgradeConfig.Status.History.GetHistory(version).Conditions.IsTrueFor(key) {
+				// The step completed itself, describing how
+				continue
+			}
 			condition.CompleteTime = &metav1.Time{Time: time.Now()}
 			condition.Reason = fmt.Sprintf("%s succeed", key)
 			condition.Message = fmt.Sprintf("%s succeed", key)
 			condition.Status = corev1.ConditionTrue
-			conditions.SetCondition(*condition)
-			history.Conditions = conditions
-			upgradeConfig.Status.History.SetHistory(*history)
-			err = cu.client.Status().Update(context.TODO(), upgradeConfig)
+			err := upgradestatus.Patch(cu.client, upgradeConfig, upgradestatus.SetHistoryCondition(version, *condition))
 			if err != nil {
 				return err
 			}
@@ -599,139 +842,123 @@ func (cu clusterUpgrader) UpgradeCluster(upgradeConfig *upgradev1alpha1.UpgradeC
 			logger.Info(fmt.Sprintf("%s not done, skip following steps", key))
 			condition.Reason = fmt.Sprintf("%s not done", key)
 			condition.Message = fmt.Sprintf("%s still in progress", key)
-			conditions.SetCondition(*condition)
-			history.Conditions = conditions
-			upgradeConfig.Status.History.SetHistory(*history)
-			err = cu.client.Status().Update(context.TODO(), upgradeConfig)
-			if err != nil {
-				return err
+			if recorded := upgradeConfig.Status.History.GetHistory(version).ControlPlaneProgress; key == upgradev1alpha1.ControlPlaneUpgraded && recorded != nil {
+				condition.Message += ": " + progress.Summary(recorded)
 			}
-			return nil
+			return upgradestatus.Patch(cu.client, upgradeConfig, upgradestatus.SetHistoryCondition(version, *condition))
 		}
 	}
-	history.Phase = upgradev1alpha1.UpgradePhaseUpgraded
-	history.CompleteTime = &metav1.Time{Time: time.Now()}
-	upgradeConfig.Status.History.SetHistory(*history)
-	err := cu.client.Status().Update(context.TODO(), upgradeConfig)
-	if err != nil {
-		return err
-	}
-	return nil
+	return upgradestatus.Patch(cu.client, upgradeConfig,
+		upgradestatus.SetPhase(version, upgradev1alpha1.UpgradePhaseUpgraded),
+		upgradestatus.SetCompleteTime(version, metav1.Now()))
 
 }
 
-// check several things about the cluster and report problems
-// * critical alerts
-// * degraded operators (if there are critical alerts only)
-func performClusterHealthCheck(c client.Client, logger logr.Logger) (bool, error) {
-	sa := &corev1.ServiceAccount{}
-
-	err := c.Get(context.TODO(), types.NamespacedName{Namespace: "openshift-monitoring", Name: "prometheus-k8s"}, sa)
-	if err != nil {
-		return false, fmt.Errorf("Unable to fetch prometheus-k8s service account: %s", err)
-	}
-
-	tokenSecret := ""
-	for _, secret := range sa.Secrets {
-		if strings.HasPrefix(secret.Name, "prometheus-k8s-token") {
-			tokenSecret = secret.Name
-		}
-	}
-	if len(tokenSecret) == 0 {
-		return false, fmt.Errorf("failed to find token secret for prommetheus-k8s SA")
-	}
-
-	logger.Info(fmt.Sprintf("found out secret %s", tokenSecret))
-
-	secret := &corev1.Secret{}
-
-	err = c.Get(context.TODO(), types.NamespacedName{Namespace: "openshift-monitoring", Name: tokenSecret}, secret)
-	if err != nil {
-		return false, fmt.Errorf("Unable to fetch secret %s: %s", tokenSecret, err)
-	}
-
-	token := secret.Data[corev1.ServiceAccountTokenKey]
-
-	route := &routev1.Route{}
-	err = c.Get(context.TODO(), types.NamespacedName{Namespace: "openshift-monitoring", Name: "prometheus-k8s"}, route)
-	if err != nil {
-		return false, err
-	}
-	tr := &http.Transport{
-		TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
-	}
-
-	hclient := http.Client{Transport: tr}
-	promurl := "https://" + route.Spec.Host + "/api/v1/query"
-
-	req, err := http.NewRequest("GET", promurl, nil)
-	if err != nil {
-		return false, fmt.Errorf("Could not query Prometheus: %s", err)
-	}
-	q := req.URL.Query()
-	alertQuery := "ALERTS{alertstate=\"firing\",severity=\"critical\",namespace=~\"^openshift.*|^kube.*|^default$\",namespace!=\"openshift-customer-monitoring\",alertname!=\"ClusterUpgradingSRE\",alertname!=\"DNSErrors05MinSRE\",alertname!=\"MetricsClientSendFailingSRE\"}"
-	q.Add("query", alertQuery)
+// failedStepError is returned by a step which can not succeed anymore, it fails the whole upgrade
+type failedStepError struct {
+	msg string
+}
 
-	req.URL.RawQuery = q.Encode()
-	req.Header.Add("Authorization", "Bearer "+string(token))
-	resp, err := hclient.Do(req)
-	if err != nil {
-		return false, err
-	}
+func (e *failedStepError) Error() string {
+	return e.msg
+}
 
-	defer resp.Body.Close()
-	body, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		return false, fmt.Errorf("Error when querying Prometheus alerts: %s", err)
+// isStepEnabled returns false for optional steps the UpgradeConfig or the operator configuration do not ask for
+func isStepEnabled(key upgradev1alpha1.UpgradeConditionType, upgradeConfig *upgradev1alpha1.UpgradeConfig) bool {
+	switch key {
+	case upgradev1alpha1.AwaitingApproval:
+		return upgradeConfig.Spec.RequireApproval
+	case upgradev1alpha1.RestoreOverrides:
+		return upgradeConfig.Spec.OverridePolicy != nil && upgradeConfig.Spec.OverridePolicy.Restore
+	case upgradev1alpha1.RegressionCheck:
+		return len(operatorconfig.Get().Regression.Metrics) > 0
+	default:
+		return true
 	}
+}
 
-	logger.Info(fmt.Sprintf("alerts : %s", body))
-	alerts := &AlertResponse{}
-
-	err = json.Unmarshal(body, alerts)
-	if err != nil {
-		return false, err
-	}
+// GateRetryError is returned while an approval gate asked to be asked again later, the step is not performed until then
+type GateRetryError struct {
+	Gate       string
+	RetryAfter time.Duration
+}
 
-	if len(alerts.Data.Result) > 0 {
-		logger.Info("there are critical alerts exists, cannot upgrade now")
-		// Send the metrics for the cluster check failed if we have opening alerts
-		return false, fmt.Errorf("there are %d critical alerts", len(alerts.Data.Result))
-	}
+func (e *GateRetryError) Error() string {
+	return fmt.Sprintf("gate %s asked to retry after %s", e.Gate, e.RetryAfter.Round(time.Second))
+}
 
-	//check co status
+// runGates asks every approval gate configured for the step whether the step may be performed, nil means they all approved.
+// Each decision is recorded as a condition in the history of the desired version. A deny fails the upgrade.
+func (cu clusterUpgrader) runGates(key upgradev1alpha1.UpgradeConditionType, upgradeConfig *upgradev1alpha1.UpgradeConfig, logger logr.Logger) error {
+	version := upgradeConfig.Spec.Desired.Version
+	for _, gate := range upgradeConfig.Spec.Gates {
+		if gate.Step != key {
+			continue
+		}
+		gateType := upgradev1alpha1.GateConditionType(gate.Name)
+		condition := upgradeConfig.Status.History.GetHistory(version).Conditions.GetCondition(gateType)
+		if condition != nil && condition.IsTrue() {
+			continue
+		}
+		if condition == nil {
+			condition = newUpgradeCondition("", "", gateType, corev1.ConditionFalse)
+			condition.StartTime = &metav1.Time{Time: time.Now()}
+		}
 
-	operatorList := &configv1.ClusterOperatorList{}
-	err = c.List(context.TODO(), operatorList, []client.ListOption{}...)
-	if err != nil {
-		return false, err
-	}
+		logger.Info(fmt.Sprintf("asking gate %s to approve %s", gate.Name, key))
+		result, gateErr := cu.gate.Evaluate(gate, upgradeConfig)
+		switch {
+		case gateErr != nil:
+			condition.Reason = "GateError"
+			condition.Message = gateErr.Error()
+		case result.Decision == gates.DecisionApprove:
+			condition.Status = corev1.ConditionTrue
+			condition.CompleteTime = &metav1.Time{Time: time.Now()}
+			condition.Reason = "Approved"
+			condition.Message = gateMessage(fmt.Sprintf("gate %s approved %s", gate.Name, key), result.Reason)
+		case result.Decision == gates.DecisionDeny:
+			condition.CompleteTime = &metav1.Time{Time: time.Now()}
+			condition.Reason = "Denied"
+			condition.Message = gateMessage(fmt.Sprintf("gate %s denied %s", gate.Name, key), result.Reason)
+			gateErr = &failedStepError{msg: condition.Message}
+		default:
+			condition.Reason = "RetryAfter"
+			condition.Message = gateMessage(fmt.Sprintf("gate %s asked to retry %s after %s", gate.Name, key, result.RetryAfter.Round(time.Second)), result.Reason)
+			gateErr = &GateRetryError{Gate: gate.Name, RetryAfter: result.RetryAfter}
+		}
 
-	degradedOperators := []string{}
-	for _, co := range operatorList.Items {
-		for _, condition := range co.Status.Conditions {
-			if (condition.Type == configv1.OperatorDegraded && condition.Status == configv1.ConditionTrue) || (condition.Type == configv1.OperatorAvailable && condition.Status == configv1.ConditionFalse) {
-				degradedOperators = append(degradedOperators, co.Name)
-			}
+		err := upgradestatus.Patch(cu.client, upgradeConfig, upgradestatus.SetHistoryCondition(version, *condition))
+		if err != nil {
+			return err
+		}
+		if _, ok := gateErr.(*GateRetryError); ok {
+			logger.Info(condition.Message)
+			return gateErr
+		}
+		if gateErr != nil {
+			logger.Error(gateErr, fmt.Sprintf("gate %s did not approve %s", gate.Name, key))
+			return gateErr
 		}
 	}
+	return nil
+}
 
-	if len(degradedOperators) > 0 {
-		logger.Info(fmt.Sprintf("degraded operators :%s", strings.Join(degradedOperators, ",")))
-		// Send the metrics for the cluster check failed if we have degraded operators
-		return false, fmt.Errorf("degraded operators :%s", strings.Join(degradedOperators, ","))
+func gateMessage(msg string, reason string) string {
+	if len(reason) == 0 {
+		return msg
 	}
-	return true, nil
-
+	return fmt.Sprintf("%s: %s", msg, reason)
 }
 
-type AlertResponse struct {
-	Status string    `json:"status"`
-	Data   AlertData `json:"data"`
-}
+// clusterHealthy returns true if none of the health checks failed, the failed ones are all reported in the error
+func clusterHealthy(metricsClient metrics.Metrics, results []healthcheck.Result, upgradeConfig *upgradev1alpha1.UpgradeConfig) (bool, error) {
+	if len(healthcheck.Failed(results)) > 0 {
+		metricsClient.UpdateMetricClusterCheckFailed(upgradeConfig.Name)
+		return false, fmt.Errorf(healthcheck.Summary(results))
+	}
 
-type AlertData struct {
-	Result []interface{} `json:"result"`
+	metricsClient.UpdateMetricClusterCheckSucceeded(upgradeConfig.Name)
+	return true, nil
 }
 
 // ValidateUpgradeConfig will run the validation steps which defined in performValidateUpgradeConfig
@@ -773,6 +1000,17 @@ func performValidateUpgradeConfig(c client.Client, upgradeConfig *upgradev1alpha
 		return false, fmt.Errorf("cluster is already on version %s", current)
 	}
 
+	// The upgrade must fit in the maintenance window
+	if upgradeConfig.Spec.MaintenanceWindowMinutes > 0 {
+		estimate, err := estimateUpgrade(c, upgradeConfig, false)
+		if err != nil {
+			return false, err
+		}
+		if estimate.TotalMinutes() > upgradeConfig.Spec.MaintenanceWindowMinutes {
+			return false, fmt.Errorf("upgrade is estimated to take %d minutes, longer than the %d minutes maintenance window", estimate.TotalMinutes(), upgradeConfig.Spec.MaintenanceWindowMinutes)
+		}
+	}
+
 	// Compare the versions, if the current version is greater than desired, failed the validation, we don't support version rollback
 	versions := []string{current, upgradeConfig.Spec.Desired.Version}
 	logger.Info("compare two versions")
@@ -782,7 +1020,22 @@ func performValidateUpgradeConfig(c client.Client, upgradeConfig *upgradev1alpha
 		return false, fmt.Errorf("desired version %s is greater than current version %s", upgradeConfig.Spec.Desired.Version, current)
 	}
 
-	// Find the available versions from cincinnati
+	found, err := isAvailableUpdate(clusterVersion, current, upgradeConfig.Spec.Desired.Version, upgradeConfig.Spec.Desired.Channel)
+	if err != nil {
+		return false, err
+	}
+	if !found {
+		logger.Info(fmt.Sprintf("failed to find the desired version %s in channel %s", upgradeConfig.Spec.Desired.Version, upgradeConfig.Spec.Desired.Channel))
+		//We need update the condition
+		errMsg := fmt.Sprintf("cannot find version %s in available updates", upgradeConfig.Spec.Desired.Version)
+		return false, fmt.Errorf(errMsg)
+	}
+
+	return true, nil
+}
+
+// isAvailableUpdate returns true if cincinnati offers an update from one version to the other in the channel
+func isAvailableUpdate(clusterVersion *configv1.ClusterVersion, from string, to string, channel string) (bool, error) {
 	clusterId, err := uuid.Parse(string(clusterVersion.Spec.ClusterID))
 	if err != nil {
 		return false, err
@@ -791,12 +1044,12 @@ func performValidateUpgradeConfig(c client.Client, upgradeConfig *upgradev1alpha
 	if err != nil {
 		return false, err
 	}
-	currentVersion, err := semver.Parse(current)
+	fromVersion, err := semver.Parse(from)
 	if err != nil {
 		return false, err
 	}
 
-	updates, err := cincinnati.NewClient(clusterId, nil, nil).GetUpdates(upstreamURI, runtime.GOARCH, upgradeConfig.Spec.Desired.Channel, currentVersion)
+	updates, err := cincinnati.NewClient(clusterId, nil, nil).GetUpdates(upstreamURI, runtime.GOARCH, channel, fromVersion)
 	if err != nil {
 		return false, err
 	}
@@ -809,22 +1062,12 @@ func performValidateUpgradeConfig(c client.Client, upgradeConfig *upgradev1alpha
 		})
 	}
 	// Check whether the desired version exists in availableUpdates
-
-	found := false
 	for _, v := range cvoUpdates {
-		if v.Version == upgradeConfig.Spec.Desired.Version && !v.Force {
-			found = true
+		if v.Version == to && !v.Force {
+			return true, nil
 		}
 	}
-
-	if !found {
-		logger.Info(fmt.Sprintf("failed to find the desired version %s in channel %s", upgradeConfig.Spec.Desired.Version, upgradeConfig.Spec.Desired.Channel))
-		//We need update the condition
-		errMsg := fmt.Sprintf("cannot find version %s in available updates", upgradeConfig.Spec.Desired.Version)
-		return false, fmt.Errorf(errMsg)
-	}
-
-	return true, nil
+	return false, nil
 }
 
 func getCurrentVersion(clusterVersion *configv1.ClusterVersion) string {
@@ -836,22 +1079,6 @@ func getCurrentVersion(clusterVersion *configv1.ClusterVersion) string {
 	return ""
 }
 
-// This return the current upgrade status
-func IsClusterUpgrading(c client.Client, version string) (bool, error) {
-	clusterVersion := &configv1.ClusterVersion{}
-	err := c.Get(context.TODO(), types.NamespacedName{Name: "version"}, clusterVersion)
-	if err != nil {
-		return false, err
-	}
-	for _, c := range clusterVersion.Status.Conditions {
-		if c.Type == configv1.OperatorProgressing && c.Status == configv1.ConditionTrue && version != clusterVersion.Spec.DesiredUpdate.Version {
-			return true, nil
-
-		}
-	}
-	return false, nil
-}
-
 func newUpgradeCondition(reason, msg string, conditionType upgradev1alpha1.UpgradeConditionType, s corev1.ConditionStatus) *upgradev1alpha1.UpgradeCondition {
 	return &upgradev1alpha1.UpgradeCondition{
 		Type:    conditionType,
@@ -861,7 +1088,19 @@ func newUpgradeCondition(reason, msg string, conditionType upgradev1alpha1.Upgra
 	}
 }
 
-// TODO readyToUpgrade checks whether it's ready to upgrade based on the scheduling
-func IsReadyToUpgrade(upgradeConfig *upgradev1alpha1.UpgradeConfig) bool {
-	return true
+// IsReadyToUpgrade checks whether it's ready to upgrade based on the scheduling.
+// If it is not, it returns how long until the upgrade starts. An invalid upgrade time never starts the upgrade.
+func IsReadyToUpgrade(upgradeConfig *upgradev1alpha1.UpgradeConfig) (bool, time.Duration, error) {
+	if len(upgradeConfig.Spec.UpgradeAt) == 0 {
+		return true, 0, nil
+	}
+	upgradeAt, err := time.Parse(time.RFC3339, upgradeConfig.Spec.UpgradeAt)
+	if err != nil {
+		return false, 0, fmt.Errorf("invalid upgrade time %s: %v", upgradeConfig.Spec.UpgradeAt, err)
+	}
+	wait := time.Until(upgradeAt)
+	if wait > 0 {
+		return false, wait, nil
+	}
+	return true, 0, nil
 }
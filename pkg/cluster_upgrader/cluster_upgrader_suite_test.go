@@ -0,0 +1,13 @@
+package cluster_upgrader
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestClusterUpgrader(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "ClusterUpgrader Suite")
+}
@@ -0,0 +1,143 @@
+package cluster_upgrader
+
+import (
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/golang/mock/gomock"
+	upgradev1alpha1 "github.com/openshift/managed-upgrade-operator/pkg/apis/upgrade/v1alpha1"
+	"github.com/openshift/managed-upgrade-operator/pkg/gates"
+	gateMocks "github.com/openshift/managed-upgrade-operator/pkg/gates/mocks"
+	"github.com/openshift/managed-upgrade-operator/pkg/maintenance"
+	"github.com/openshift/managed-upgrade-operator/pkg/metrics"
+	"github.com/openshift/managed-upgrade-operator/pkg/upgradestatus"
+	"github.com/openshift/managed-upgrade-operator/util/mocks"
+	testStructs "github.com/openshift/managed-upgrade-operator/util/mocks/structs"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("ClusterUpgrader", func() {
+	var (
+		mockCtrl       *gomock.Controller
+		mockKubeClient *mocks.MockClient
+		mockUpdater    *mocks.MockStatusWriter
+		mockGate       *gateMocks.MockGate
+		upgradeConfig  *upgradev1alpha1.UpgradeConfig
+		performed      []upgradev1alpha1.UpgradeConditionType
+		upgrader       clusterUpgrader
+		logger         logr.Logger
+	)
+
+	BeforeEach(func() {
+		mockCtrl = gomock.NewController(GinkgoT())
+		mockKubeClient = mocks.NewMockClient(mockCtrl)
+		mockUpdater = mocks.NewMockStatusWriter(mockCtrl)
+		mockGate = gateMocks.NewMockGate(mockCtrl)
+		mockKubeClient.EXPECT().Status().Return(mockUpdater).AnyTimes()
+		mockUpdater.EXPECT().Patch(gomock.Any(), gomock.Any(), gomock.Any()).AnyTimes()
+		upgradeConfig = testStructs.NewUpgradeConfigBuilder().GetUpgradeConfig()
+		upgradeConfig.Status.History = upgradev1alpha1.UpgradeHistories{{
+			Version:    upgradeConfig.Spec.Desired.Version,
+			Phase:      upgradev1alpha1.UpgradePhaseUpgrading,
+			Conditions: upgradev1alpha1.NewConditions(),
+		}}
+		performed = nil
+		steps := UpgradeSteps{}
+		for _, key := range Ordering() {
+			key := key
+			steps[key] = func(c client.Client, metricsClient metrics.Metrics, m maintenance.Maintenance, upgradeConfig *upgradev1alpha1.UpgradeConfig, logger logr.Logger) (bool, error) {
+				performed = append(performed, key)
+				return true, nil
+			}
+		}
+		upgrader = clusterUpgrader{Steps: steps, client: mockKubeClient, metrics: &metrics.Counter{}, gate: mockGate}
+		logger = logf.Log.WithName("cluster upgrader test logger")
+	})
+
+	AfterEach(func() {
+		mockCtrl.Finish()
+	})
+
+	history := func() *upgradev1alpha1.UpgradeHistory {
+		return upgradeConfig.Status.History.GetHistory(upgradeConfig.Spec.Desired.Version)
+	}
+
+	Context("When upgrading the cluster", func() {
+		It("performs every step and completes the upgrade", func() {
+			err := upgrader.UpgradeCluster(upgradeConfig, logger)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(performed).To(ContainElement(upgradev1alpha1.PostClusterHealthCheck))
+			Expect(history().Phase).To(Equal(upgradev1alpha1.UpgradePhaseUpgraded))
+			Expect(history().Conditions.IsTrueFor(upgradev1alpha1.UpgradeValidated)).To(BeTrue())
+		})
+
+		It("keeps what the steps record in the history", func() {
+			upgrader.Steps[upgradev1alpha1.PostClusterHealthCheck] = func(c client.Client, metricsClient metrics.Metrics, m maintenance.Maintenance, upgradeConfig *upgradev1alpha1.UpgradeConfig, logger logr.Logger) (bool, error) {
+				return true, upgradestatus.Patch(c, upgradeConfig, upgradestatus.SetSettle(upgradeConfig.Spec.Desired.Version, upgradev1alpha1.HealthCheckSettle{Flaps: 2}))
+			}
+			err := upgrader.UpgradeCluster(upgradeConfig, logger)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(history().Settle.Flaps).To(Equal(int32(2)))
+			Expect(history().Phase).To(Equal(upgradev1alpha1.UpgradePhaseUpgraded))
+		})
+
+		It("keeps the message of a step which completed itself", func() {
+			upgrader.Steps[upgradev1alpha1.PostClusterHealthCheck] = func(c client.Client, metricsClient metrics.Metrics, m maintenance.Maintenance, upgradeConfig *upgradev1alpha1.UpgradeConfig, logger logr.Logger) (bool, error) {
+				return true, completeStep(c, upgradeConfig, upgradev1alpha1.PostClusterHealthCheck, "healthy for 10m0s")
+			}
+			err := upgrader.UpgradeCluster(upgradeConfig, logger)
+			Expect(err).NotTo(HaveOccurred())
+			condition := history().Conditions.GetCondition(upgradev1alpha1.PostClusterHealthCheck)
+			Expect(condition.IsTrue()).To(BeTrue())
+			Expect(condition.Message).To(Equal("healthy for 10m0s"))
+			Expect(history().Conditions.GetCondition(upgradev1alpha1.UpgradeValidated).Message).To(Equal(string(upgradev1alpha1.UpgradeValidated) + " succeed"))
+		})
+
+		It("does not perform a step again once done", func() {
+			upgradeConfig.Status.History[0].Conditions.SetCondition(upgradev1alpha1.UpgradeCondition{Type: upgradev1alpha1.UpgradeValidated, Status: corev1.ConditionTrue})
+			err := upgrader.UpgradeCluster(upgradeConfig, logger)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(performed).NotTo(ContainElement(upgradev1alpha1.UpgradeValidated))
+		})
+	})
+
+	Context("When an approval gate is configured for a step", func() {
+		var gateType = upgradev1alpha1.GateConditionType("change-management")
+
+		BeforeEach(func() {
+			upgradeConfig.Spec.Gates = []upgradev1alpha1.UpgradeGate{{Name: "change-management", Step: upgradev1alpha1.UpgradeValidated, URL: "https://gate"}}
+		})
+
+		It("performs the step once the gate approves", func() {
+			mockGate.EXPECT().Evaluate(gomock.Any(), gomock.Any()).Return(&gates.Result{Decision: gates.DecisionApprove}, nil)
+			err := upgrader.UpgradeCluster(upgradeConfig, logger)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(performed).To(ContainElement(upgradev1alpha1.UpgradeValidated))
+			Expect(history().Conditions.IsTrueFor(gateType)).To(BeTrue())
+		})
+
+		It("fails the upgrade when the gate denies", func() {
+			mockGate.EXPECT().Evaluate(gomock.Any(), gomock.Any()).Return(&gates.Result{Decision: gates.DecisionDeny, Reason: "change freeze"}, nil)
+			err := upgrader.UpgradeCluster(upgradeConfig, logger)
+			Expect(err).To(BeAssignableToTypeOf(&failedStepError{}))
+			Expect(performed).To(BeEmpty())
+			Expect(history().Phase).To(Equal(upgradev1alpha1.UpgradePhaseFailed))
+			Expect(history().CompleteTime).NotTo(BeNil())
+			Expect(history().Conditions.GetCondition(gateType).Message).To(ContainSubstring("change freeze"))
+		})
+
+		It("returns when the gate may be asked again when it asks to retry", func() {
+			mockGate.EXPECT().Evaluate(gomock.Any(), gomock.Any()).Return(&gates.Result{Decision: gates.DecisionRetryAfter, RetryAfter: 10 * time.Minute}, nil)
+			err := upgrader.UpgradeCluster(upgradeConfig, logger)
+			Expect(err).To(Equal(&GateRetryError{Gate: "change-management", RetryAfter: 10 * time.Minute}))
+			Expect(performed).To(BeEmpty())
+			Expect(history().Phase).To(Equal(upgradev1alpha1.UpgradePhaseUpgrading))
+			Expect(history().Conditions.GetCondition(gateType).Reason).To(Equal("RetryAfter"))
+		})
+	})
+})
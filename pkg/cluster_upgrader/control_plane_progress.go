@@ -0,0 +1,35 @@
+package cluster_upgrader
+
+import (
+	"context"
+	"time"
+
+	configv1 "github.com/openshift/api/config/v1"
+	upgradev1alpha1 "github.com/openshift/managed-upgrade-operator/pkg/apis/upgrade/v1alpha1"
+	"github.com/openshift/managed-upgrade-operator/pkg/metrics"
+	"github.com/openshift/managed-upgrade-operator/pkg/progress"
+	"github.com/openshift/managed-upgrade-operator/pkg/upgradestatus"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// recordControlPlaneProgress records how far the control plane upgrade to the desired version went in the history,
+// when it changed, and exposes it as metrics
+func recordControlPlaneProgress(c client.Client, metricsClient metrics.Metrics, upgradeConfig *upgradev1alpha1.UpgradeConfig, clusterVersion *configv1.ClusterVersion) error {
+	history := upgradeConfig.Status.History.GetHistory(upgradeConfig.Spec.Desired.Version)
+	if history == nil {
+		return nil
+	}
+	operators := &configv1.ClusterOperatorList{}
+	err := c.List(context.TODO(), operators)
+	if err != nil {
+		return err
+	}
+
+	current := progress.ControlPlane(clusterVersion, operators.Items, upgradeConfig.Spec.Desired.Version, history.ControlPlaneProgress, time.Now())
+	metricsClient.UpdateMetricControlPlaneOperatorsProgress(progress.OperatorsRatio(&current), upgradeConfig.Name)
+	metricsClient.UpdateMetricControlPlaneManifestsProgress(progress.ManifestsRatio(&current), upgradeConfig.Name)
+	if !progress.Changed(history.ControlPlaneProgress, current) {
+		return nil
+	}
+	return upgradestatus.Patch(c, upgradeConfig, upgradestatus.SetControlPlaneProgress(upgradeConfig.Spec.Desired.Version, current))
+}
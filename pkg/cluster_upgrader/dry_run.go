@@ -0,0 +1,238 @@
+package cluster_upgrader
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+	configv1 "github.com/openshift/api/config/v1"
+	machineapi "github.com/openshift/machine-api-operator/pkg/apis/machine/v1beta1"
+	machineconfigapi "github.com/openshift/machine-config-operator/pkg/apis/machineconfiguration.openshift.io/v1"
+	upgradev1alpha1 "github.com/openshift/managed-upgrade-operator/pkg/apis/upgrade/v1alpha1"
+	"github.com/openshift/managed-upgrade-operator/pkg/healthcheck"
+	"github.com/openshift/managed-upgrade-operator/pkg/operatorconfig"
+	"github.com/openshift/managed-upgrade-operator/pkg/upgradestatus"
+	operatorv1alpha1 "github.com/operator-framework/api/pkg/operators/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Represents the simulation of an individual step, it reports what the step would do without changing the cluster.
+// What the step finds is recorded on the dry run history, never on the status of the actual upgrade.
+type DryRunStep func(c client.Client, upgradeConfig *upgradev1alpha1.UpgradeConfig, history *upgradev1alpha1.UpgradeHistory, logger logr.Logger) (string, error)
+
+var dryRunSteps = map[upgradev1alpha1.UpgradeConditionType]DryRunStep{
+	upgradev1alpha1.UpgradeValidated:              dryRunValidation,
+	upgradev1alpha1.UpgradePreHealthCheck:         dryRunHealthCheck,
+	upgradev1alpha1.UpgradeScaleUpExtraNodes:      dryRunExtraUpgradeWorkers,
+	upgradev1alpha1.CommenceUpgrade:               dryRunCommenceUpgrade,
+	upgradev1alpha1.ControlPlaneMaintWindow:       dryRunControlPlaneMaintWindow,
+	upgradev1alpha1.ControlPlaneUpgraded:          dryRunWait("the control plane to be upgraded"),
+	upgradev1alpha1.AllMasterNodesUpgraded:        dryRunNodesUpgraded("master"),
+	upgradev1alpha1.RemoveControlPlaneMaintWindow: dryRunWould("remove the control plane maintenance silences"),
+	upgradev1alpha1.WorkersMaintWindow:            dryRunWorkerMaintWindow,
+	upgradev1alpha1.AllWorkerNodesUpgraded:        dryRunNodesUpgraded("worker"),
+	upgradev1alpha1.RemoveExtraScaledNodes:        dryRunWould("delete the extra upgrade machinesets"),
+	upgradev1alpha1.UpdateSubscriptions:           dryRunUpdateSubscriptions,
+	upgradev1alpha1.PostUpgradeVerification:       dryRunUpgradeVerification,
+	upgradev1alpha1.RestoreOverrides:              dryRunRestoreOverrides,
+	upgradev1alpha1.RemoveMaintWindow:             dryRunWould("remove the maintenance silences"),
+	upgradev1alpha1.PostClusterHealthCheck:        dryRunWould("run the same health checks as PreHealthCheck"),
+	upgradev1alpha1.RegressionCheck:               dryRunWould("compare the SLO metrics before and after the upgrade"),
+}
+
+// DryRun runs the read-only upgrade steps and simulates the others, recording the results in the dry run status.
+// Every step is evaluated, a step which would fail does not stop the following ones.
+func (cu clusterUpgrader) DryRun(upgradeConfig *upgradev1alpha1.UpgradeConfig, logger logr.Logger) error {
+	logger.Info("dry running cluster upgrade")
+	history := upgradev1alpha1.UpgradeHistory{
+		Version:    upgradeConfig.Spec.Desired.Version,
+		Phase:      upgradev1alpha1.UpgradePhaseUpgraded,
+		DryRun:     true,
+		StartTime:  &metav1.Time{Time: time.Now()},
+		Conditions: upgradev1alpha1.NewConditions(),
+	}
+
+	estimate, err := estimateUpgrade(cu.client, upgradeConfig, false)
+	if err != nil {
+		logger.Error(err, "failed to estimate the upgrade duration")
+	}
+	history.Estimate = estimate
+
+	for _, key := range Ordering() {
+		step, ok := dryRunSteps[key]
+		if !ok || !isStepEnabled(key, upgradeConfig) {
+			continue
+		}
+
+		condition := newUpgradeCondition("", "", key, corev1.ConditionTrue)
+		condition.StartTime = &metav1.Time{Time: time.Now()}
+		msg, err := step(cu.client, upgradeConfig, &history, logger)
+		if err != nil {
+			logger.Info(fmt.Sprintf("%s would fail: %s", key, err))
+			condition.Status = corev1.ConditionFalse
+			condition.Reason = fmt.Sprintf("%s would fail", key)
+			condition.Message = err.Error()
+			history.Phase = upgradev1alpha1.UpgradePhaseFailed
+		} else {
+			condition.Reason = fmt.Sprintf("%s would succeed", key)
+			condition.Message = withGates(msg, key, upgradeConfig)
+		}
+		condition.CompleteTime = &metav1.Time{Time: time.Now()}
+		history.Conditions.SetCondition(*condition)
+	}
+
+	history.CompleteTime = &metav1.Time{Time: time.Now()}
+	return upgradestatus.Patch(cu.client, upgradeConfig, upgradestatus.SetDryRun(history))
+}
+
+// withGates mentions the approval gates which would be asked before the step
+func withGates(msg string, key upgradev1alpha1.UpgradeConditionType, upgradeConfig *upgradev1alpha1.UpgradeConfig) string {
+	for _, gate := range upgradeConfig.Spec.Gates {
+		if gate.Step == key {
+			msg = fmt.Sprintf("would ask gate %s at %s for approval; %s", gate.Name, gate.URL, msg)
+		}
+	}
+	return msg
+}
+
+func dryRunWould(action string) DryRunStep {
+	return func(c client.Client, upgradeConfig *upgradev1alpha1.UpgradeConfig, history *upgradev1alpha1.UpgradeHistory, logger logr.Logger) (string, error) {
+		return "would " + action, nil
+	}
+}
+
+func dryRunWait(what string) DryRunStep {
+	return dryRunWould("wait for " + what)
+}
+
+func dryRunValidation(c client.Client, upgradeConfig *upgradev1alpha1.UpgradeConfig, history *upgradev1alpha1.UpgradeHistory, logger logr.Logger) (string, error) {
+	ok, err := performValidateUpgradeConfig(c, upgradeConfig, logger)
+	if err != nil {
+		return "", err
+	}
+	if !ok {
+		return "", fmt.Errorf("upgradeconfig is not valid")
+	}
+	return "upgradeconfig is valid", nil
+}
+
+func dryRunHealthCheck(c client.Client, upgradeConfig *upgradev1alpha1.UpgradeConfig, history *upgradev1alpha1.UpgradeHistory, logger logr.Logger) (string, error) {
+	results := healthcheck.Run(c, operatorconfig.PreUpgrade, upgradeConfig, logger)
+	history.PreflightResults = healthcheck.PreflightResults(results)
+	if len(healthcheck.Failed(results)) > 0 {
+		return "", fmt.Errorf(healthcheck.Summary(results))
+	}
+	return "cluster is healthy, " + healthcheck.Summary(results), nil
+}
+
+func dryRunExtraUpgradeWorkers(c client.Client, upgradeConfig *upgradev1alpha1.UpgradeConfig, history *upgradev1alpha1.UpgradeHistory, logger logr.Logger) (string, error) {
+	originalMachineSets := &machineapi.MachineSetList{}
+	err := c.List(context.TODO(), originalMachineSets, []client.ListOption{
+		client.InNamespace("openshift-machine-api"),
+		client.MatchingLabels{"hive.openshift.io/machine-pool": "worker"},
+	}...)
+	if err != nil {
+		return "", err
+	}
+	if len(originalMachineSets.Items) == 0 {
+		return "", fmt.Errorf("failed to get original machineset")
+	}
+
+	names := []string{}
+	for _, ms := range originalMachineSets.Items {
+		names = append(names, ms.Name+"-upgrade")
+	}
+	return fmt.Sprintf("would create machinesets %s with 1 replica each", strings.Join(names, ",")), nil
+}
+
+func dryRunCommenceUpgrade(c client.Client, upgradeConfig *upgradev1alpha1.UpgradeConfig, history *upgradev1alpha1.UpgradeHistory, logger logr.Logger) (string, error) {
+	clusterVersion := &configv1.ClusterVersion{}
+	err := c.Get(context.TODO(), types.NamespacedName{Name: "version"}, clusterVersion)
+	if err != nil {
+		return "", err
+	}
+	msg := fmt.Sprintf("would update clusterversion from %s on channel %s to %s on channel %s",
+		getCurrentVersion(clusterVersion), clusterVersion.Spec.Channel, upgradeConfig.Spec.Desired.Version, upgradeConfig.Spec.Desired.Channel)
+	_, removed := splitOverrides(clusterVersion.Spec.Overrides, upgradeConfig.Spec.OverridePolicy)
+	if len(removed) > 0 {
+		msg = fmt.Sprintf("%s and remove %d of %d overrides", msg, len(removed), len(clusterVersion.Spec.Overrides))
+	}
+	return msg, nil
+}
+
+func dryRunRestoreOverrides(c client.Client, upgradeConfig *upgradev1alpha1.UpgradeConfig, history *upgradev1alpha1.UpgradeHistory, logger logr.Logger) (string, error) {
+	clusterVersion := &configv1.ClusterVersion{}
+	err := c.Get(context.TODO(), types.NamespacedName{Name: "version"}, clusterVersion)
+	if err != nil {
+		return "", err
+	}
+	_, removed := splitOverrides(clusterVersion.Spec.Overrides, upgradeConfig.Spec.OverridePolicy)
+	return fmt.Sprintf("would restore %d overrides", len(removed)), nil
+}
+
+func dryRunControlPlaneMaintWindow(c client.Client, upgradeConfig *upgradev1alpha1.UpgradeConfig, history *upgradev1alpha1.UpgradeHistory, logger logr.Logger) (string, error) {
+	estimate, err := estimateUpgrade(c, upgradeConfig, false)
+	if err != nil {
+		return "", err
+	}
+	duration := time.Duration(estimate.ControlPlaneMinutes+estimate.MasterNodesMinutes) * time.Minute
+	return fmt.Sprintf("would silence alerts for %s", duration), nil
+}
+
+func dryRunWorkerMaintWindow(c client.Client, upgradeConfig *upgradev1alpha1.UpgradeConfig, history *upgradev1alpha1.UpgradeHistory, logger logr.Logger) (string, error) {
+	estimate, err := estimateUpgrade(c, upgradeConfig, false)
+	if err != nil {
+		return "", err
+	}
+	duration := time.Duration(estimate.WorkerNodesMinutes) * time.Minute
+	return fmt.Sprintf("would silence alerts for %s for %d worker batches", duration, estimate.WorkerBatches), nil
+}
+
+func dryRunNodesUpgraded(nodeType string) DryRunStep {
+	return func(c client.Client, upgradeConfig *upgradev1alpha1.UpgradeConfig, history *upgradev1alpha1.UpgradeHistory, logger logr.Logger) (string, error) {
+		configPool := &machineconfigapi.MachineConfigPool{}
+		err := c.Get(context.TODO(), types.NamespacedName{Name: nodeType}, configPool)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("would wait for %d %s nodes to be upgraded", configPool.Status.MachineCount, nodeType), nil
+	}
+}
+
+func dryRunUpdateSubscriptions(c client.Client, upgradeConfig *upgradev1alpha1.UpgradeConfig, history *upgradev1alpha1.UpgradeHistory, logger logr.Logger) (string, error) {
+	changes := []string{}
+	for _, item := range upgradeConfig.Spec.SubscriptionUpdates {
+		sub := &operatorv1alpha1.Subscription{}
+		err := c.Get(context.TODO(), types.NamespacedName{Namespace: item.Namespace, Name: item.Name}, sub)
+		if err != nil {
+			if errors.IsNotFound(err) {
+				continue
+			}
+			return "", err
+		}
+		if sub.Spec.Channel != item.Channel {
+			changes = append(changes, fmt.Sprintf("%s/%s from %s to %s", item.Namespace, item.Name, sub.Spec.Channel, item.Channel))
+		}
+	}
+	if len(changes) == 0 {
+		return "no subscription would change", nil
+	}
+	return "would change the channel of subscriptions " + strings.Join(changes, ","), nil
+}
+
+func dryRunUpgradeVerification(c client.Client, upgradeConfig *upgradev1alpha1.UpgradeConfig, history *upgradev1alpha1.UpgradeHistory, logger logr.Logger) (string, error) {
+	ok, err := performUpgradeVerification(c, logger)
+	if err != nil {
+		return "", err
+	}
+	if !ok {
+		return "", fmt.Errorf("not all replicasets and daemonsets are ready")
+	}
+	return "all replicasets and daemonsets are ready", nil
+}
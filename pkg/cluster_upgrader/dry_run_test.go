@@ -0,0 +1,112 @@
+package cluster_upgrader
+
+import (
+	"context"
+
+	"github.com/go-logr/logr"
+	"github.com/golang/mock/gomock"
+	upgradev1alpha1 "github.com/openshift/managed-upgrade-operator/pkg/apis/upgrade/v1alpha1"
+	maintenanceMocks "github.com/openshift/managed-upgrade-operator/pkg/maintenance/mocks"
+	"github.com/openshift/managed-upgrade-operator/pkg/metrics"
+	"github.com/openshift/managed-upgrade-operator/pkg/operatorconfig"
+	"github.com/openshift/managed-upgrade-operator/util/mocks"
+	testStructs "github.com/openshift/managed-upgrade-operator/util/mocks/structs"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("DryRun", func() {
+	var (
+		mockCtrl       *gomock.Controller
+		mockKubeClient *mocks.MockClient
+		mockUpdater    *mocks.MockStatusWriter
+		upgradeConfig  *upgradev1alpha1.UpgradeConfig
+		upgrading      upgradev1alpha1.UpgradeHistory
+		preflight      []upgradev1alpha1.PreflightResult
+		patched        []*upgradev1alpha1.UpgradeConfig
+		upgrader       clusterUpgrader
+		logger         logr.Logger
+	)
+
+	BeforeEach(func() {
+		mockCtrl = gomock.NewController(GinkgoT())
+		mockKubeClient = mocks.NewMockClient(mockCtrl)
+		mockUpdater = mocks.NewMockStatusWriter(mockCtrl)
+		// Reading the cluster is all a dry run may do, any other call to the client fails the test
+		mockKubeClient.EXPECT().Get(gomock.Any(), gomock.Any(), gomock.Any()).AnyTimes()
+		mockKubeClient.EXPECT().List(gomock.Any(), gomock.Any(), gomock.Any()).AnyTimes()
+		mockKubeClient.EXPECT().Status().Return(mockUpdater).AnyTimes()
+		patched = nil
+		mockUpdater.EXPECT().Patch(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(
+			func(ctx context.Context, obj runtime.Object, patch client.Patch, opts ...client.PatchOption) error {
+				patched = append(patched, obj.(*upgradev1alpha1.UpgradeConfig).DeepCopy())
+				return nil
+			}).AnyTimes()
+
+		cfg := operatorconfig.DefaultConfig()
+		// An unknown health check fails
+		cfg.HealthCheck.PreUpgrade = []string{"Unknown"}
+		operatorconfig.Set(cfg, "", "")
+
+		upgradeConfig = testStructs.NewUpgradeConfigBuilder().GetUpgradeConfig()
+		upgradeConfig.Spec.DryRun = true
+		upgrading = upgradev1alpha1.UpgradeHistory{
+			Version:    upgradeConfig.Spec.Desired.Version,
+			Phase:      upgradev1alpha1.UpgradePhaseUpgrading,
+			Conditions: upgradev1alpha1.NewConditions(),
+		}
+		preflight = []upgradev1alpha1.PreflightResult{{Name: "ClusterOperators", Result: upgradev1alpha1.PreflightPass, CheckedTime: metav1.Now()}}
+		upgradeConfig.Status.History = upgradev1alpha1.UpgradeHistories{upgrading}
+		upgradeConfig.Status.PreflightResults = preflight
+		// The maintenance has no expectation, silencing alerts fails the test
+		upgrader = clusterUpgrader{client: mockKubeClient, maintenance: maintenanceMocks.NewMockMaintenance(mockCtrl), metrics: &metrics.Counter{}}
+		logger = logf.Log.WithName("dry run test logger")
+	})
+
+	AfterEach(func() {
+		operatorconfig.Set(operatorconfig.DefaultConfig(), "", "")
+		mockCtrl.Finish()
+	})
+
+	It("records the results in the dry run status", func() {
+		err := upgrader.DryRun(upgradeConfig, logger)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(patched).To(HaveLen(1))
+		history := patched[0].Status.DryRun
+		Expect(history).NotTo(BeNil())
+		Expect(history.Phase).To(Equal(upgradev1alpha1.UpgradePhaseFailed))
+		Expect(history.CompleteTime).NotTo(BeNil())
+		Expect(history.Conditions.IsFalseFor(upgradev1alpha1.UpgradePreHealthCheck)).To(BeTrue())
+		// A step which would fail does not stop the following ones
+		Expect(history.Conditions.GetCondition(upgradev1alpha1.PostClusterHealthCheck)).NotTo(BeNil())
+	})
+
+	It("records the results of the health checks in the dry run status only", func() {
+		err := upgrader.DryRun(upgradeConfig, logger)
+		Expect(err).NotTo(HaveOccurred())
+		history := patched[0].Status.DryRun
+		Expect(history.PreflightResults).To(HaveLen(1))
+		Expect(history.PreflightResults[0].Name).To(Equal("Unknown"))
+		Expect(history.PreflightResults[0].Result).To(Equal(upgradev1alpha1.PreflightFail))
+		Expect(patched[0].Status.PreflightResults).To(Equal(preflight))
+	})
+
+	It("drops the dry runs recorded in the history by earlier versions", func() {
+		upgradeConfig.Status.History = append(upgradev1alpha1.UpgradeHistories{{Version: "4.4.4", Phase: upgradev1alpha1.UpgradePhaseUpgraded, DryRun: true}}, upgrading)
+		err := upgrader.DryRun(upgradeConfig, logger)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(patched[0].Status.History).To(Equal(upgradev1alpha1.UpgradeHistories{upgrading}))
+	})
+
+	It("leaves the status of the actual upgrade untouched", func() {
+		err := upgrader.DryRun(upgradeConfig, logger)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(patched[0].Status.History).To(Equal(upgradev1alpha1.UpgradeHistories{upgrading}))
+		Expect(patched[0].Status.RemovedOverrides).To(BeEmpty())
+	})
+})
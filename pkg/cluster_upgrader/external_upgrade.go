@@ -0,0 +1,209 @@
+package cluster_upgrader
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-logr/logr"
+	configv1 "github.com/openshift/api/config/v1"
+	upgradev1alpha1 "github.com/openshift/managed-upgrade-operator/pkg/apis/upgrade/v1alpha1"
+	"github.com/openshift/managed-upgrade-operator/pkg/metrics"
+	"github.com/openshift/managed-upgrade-operator/pkg/upgradestatus"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Reasons of the ExternalUpgradeForbidden condition
+const (
+	ReasonExternalUpgradeStarted      = "ExternalUpgradeStarted"
+	ReasonExternalUpgradeAcknowledged = "Acknowledged"
+)
+
+// TrackExternalUpgrade records the upgrades started outside the operator in the history of the UpgradeConfig and
+// follows them until they complete. An upgrade is external when the ClusterVersion is upgrading to a version
+// none of the UpgradeConfigs desires, other than the current one. It returns true while an external upgrade is in progress.
+// An external upgrade the policy forbids sets the ExternalUpgradeForbidden condition, which stays set until acknowledged.
+func TrackExternalUpgrade(c client.Client, metricsClient metrics.Metrics, upgradeConfig *upgradev1alpha1.UpgradeConfig, logger logr.Logger) (bool, error) {
+	clusterVersion := &configv1.ClusterVersion{}
+	err := c.Get(context.TODO(), types.NamespacedName{Name: "version"}, clusterVersion)
+	if err != nil {
+		return false, err
+	}
+
+	mutations := []upgradestatus.Mutation{}
+	for _, uh := range upgradeConfig.Status.History {
+		if uh.Source != upgradev1alpha1.UpgradeSourceExternal || uh.Phase != upgradev1alpha1.UpgradePhaseUpgrading {
+			continue
+		}
+		// Changes are made on a copy, the UpgradeConfig is only changed by the status patch
+		h := *uh.DeepCopy()
+		if updateExternalHistory(&h, clusterVersion) {
+			logger.Info(fmt.Sprintf("external upgrade to %s is %s", h.Version, h.Phase))
+			mutations = append(mutations, upgradestatus.SetHistory(h))
+		}
+	}
+
+	external := false
+	version := clusterVersion.Status.Desired.Version
+	// A version the operator upgraded to is not external, even once the upgrade was retargeted to another version
+	previous := upgradeConfig.Status.History.GetHistory(version)
+	// The cluster version operator also progresses when reconciling the current version, which is not an upgrade
+	if isProgressing(clusterVersion) && version != getCurrentVersion(clusterVersion) && (previous == nil || !isOperatorUpgrade(previous)) {
+		external, err = isExternalVersion(c, version)
+		if err != nil {
+			return false, err
+		}
+	}
+	if external && previous == nil {
+		logger.Info(fmt.Sprintf("cluster is upgrading to %s outside the operator", version))
+		mutations = append(mutations, upgradestatus.SetHistory(newExternalHistory(version, clusterVersion, upgradeConfig.Spec.ExternalUpgradePolicy)))
+		if upgradeConfig.Spec.ExternalUpgradePolicy == upgradev1alpha1.ExternalUpgradePolicyForbid {
+			logger.Info(fmt.Sprintf("upgrade to %s started outside the operator is forbidden", version))
+			mutations = append(mutations, upgradestatus.SetCondition(upgradev1alpha1.UpgradeCondition{
+				Type:    upgradev1alpha1.ExternalUpgradeForbidden,
+				Status:  corev1.ConditionTrue,
+				Reason:  ReasonExternalUpgradeStarted,
+				Message: fmt.Sprintf("upgrade to %s was started outside the operator, which the policy forbids, until the %s annotation is set", version, upgradev1alpha1.ExternalUpgradeAcknowledgedAnnotation),
+			}))
+		}
+	}
+
+	if len(mutations) > 0 {
+		err = upgradestatus.Patch(c, upgradeConfig, mutations...)
+		if err != nil {
+			return false, err
+		}
+	}
+	updateMetricExternalUpgrade(metricsClient, upgradeConfig)
+	return external, nil
+}
+
+// AcknowledgeExternalUpgrade clears the ExternalUpgradeForbidden condition, then removes the acknowledgement annotation
+func AcknowledgeExternalUpgrade(c client.Client, metricsClient metrics.Metrics, upgradeConfig *upgradev1alpha1.UpgradeConfig, logger logr.Logger) error {
+	if upgradeConfig.Status.Conditions.IsTrueFor(upgradev1alpha1.ExternalUpgradeForbidden) {
+		logger.Info("the forbidden upgrade started outside the operator was acknowledged")
+		condition := upgradev1alpha1.UpgradeCondition{
+			Type:    upgradev1alpha1.ExternalUpgradeForbidden,
+			Status:  corev1.ConditionFalse,
+			Reason:  ReasonExternalUpgradeAcknowledged,
+			Message: "the upgrade started outside the operator was acknowledged",
+		}
+		err := upgradestatus.Patch(c, upgradeConfig, upgradestatus.SetCondition(condition))
+		if err != nil {
+			return err
+		}
+		updateMetricExternalUpgrade(metricsClient, upgradeConfig)
+	}
+
+	patch := client.MergeFrom(upgradeConfig.DeepCopy())
+	delete(upgradeConfig.Annotations, upgradev1alpha1.ExternalUpgradeAcknowledgedAnnotation)
+	return c.Patch(context.TODO(), upgradeConfig, patch)
+}
+
+// updateMetricExternalUpgrade reports the forbidden external upgrade until it is acknowledged
+func updateMetricExternalUpgrade(metricsClient metrics.Metrics, upgradeConfig *upgradev1alpha1.UpgradeConfig) {
+	if upgradeConfig.Status.Conditions.IsTrueFor(upgradev1alpha1.ExternalUpgradeForbidden) {
+		metricsClient.UpdateMetricExternalUpgradeForbidden(upgradeConfig.Name)
+	} else {
+		metricsClient.UpdateMetricExternalUpgradeCleared(upgradeConfig.Name)
+	}
+}
+
+// isProgressing returns true if the cluster version operator is rolling out a new version
+func isProgressing(clusterVersion *configv1.ClusterVersion) bool {
+	for _, c := range clusterVersion.Status.Conditions {
+		if c.Type == configv1.OperatorProgressing && c.Status == configv1.ConditionTrue {
+			return true
+		}
+	}
+	return false
+}
+
+// isExternalVersion returns true if no UpgradeConfig which may upgrade the cluster desires the version.
+// As in the arbitration, the dry runs and the UpgradeConfigs being deleted never upgrade it.
+func isExternalVersion(c client.Client, version string) (bool, error) {
+	ucList := &upgradev1alpha1.UpgradeConfigList{}
+	err := c.List(context.TODO(), ucList)
+	if err != nil {
+		return false, err
+	}
+	for _, uc := range ucList.Items {
+		if uc.IsDryRun() || uc.DeletionTimestamp != nil {
+			continue
+		}
+		if uc.Spec.Desired.Version == version {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// newExternalHistory returns the history recording the upgrade to the version started outside the operator
+func newExternalHistory(version string, clusterVersion *configv1.ClusterVersion, policy upgradev1alpha1.ExternalUpgradePolicy) upgradev1alpha1.UpgradeHistory {
+	history := upgradev1alpha1.UpgradeHistory{
+		Version:    version,
+		Phase:      upgradev1alpha1.UpgradePhaseUpgrading,
+		Source:     upgradev1alpha1.UpgradeSourceExternal,
+		StartTime:  &metav1.Time{Time: time.Now()},
+		Conditions: upgradev1alpha1.NewConditions(),
+	}
+	if update := findUpdateHistory(clusterVersion, version); update != nil {
+		history.StartTime = update.StartedTime.DeepCopy()
+	}
+
+	condition := newUpgradeCondition("Adopted", fmt.Sprintf("upgrade to %s was started outside the operator", version), upgradev1alpha1.ExternalUpgradeDetected, corev1.ConditionTrue)
+	if policy == upgradev1alpha1.ExternalUpgradePolicyForbid {
+		condition.Reason = "Forbidden"
+		condition.Message = fmt.Sprintf("upgrade to %s was started outside the operator, which the policy forbids", version)
+	}
+	condition.StartTime = history.StartTime.DeepCopy()
+	history.Conditions.SetCondition(*condition)
+	updateExternalHistory(&history, clusterVersion)
+	return history
+}
+
+// updateExternalHistory completes the history once the ClusterVersion reports the upgrade as completed,
+// or fails it when the ClusterVersion moved on to another version. It returns true if the history changed.
+func updateExternalHistory(history *upgradev1alpha1.UpgradeHistory, clusterVersion *configv1.ClusterVersion) bool {
+	update := findUpdateHistory(clusterVersion, history.Version)
+	switch {
+	case update != nil && update.State == configv1.CompletedUpdate:
+		history.Phase = upgradev1alpha1.UpgradePhaseUpgraded
+		history.CompleteTime = &metav1.Time{Time: time.Now()}
+		if update.CompletionTime != nil {
+			history.CompleteTime = update.CompletionTime.DeepCopy()
+		}
+		setExternalCondition(history, fmt.Sprintf("upgrade to %s completed", history.Version))
+		return true
+	case clusterVersion.Status.Desired.Version != history.Version:
+		history.Phase = upgradev1alpha1.UpgradePhaseFailed
+		history.CompleteTime = &metav1.Time{Time: time.Now()}
+		setExternalCondition(history, fmt.Sprintf("upgrade to %s was superseded by the upgrade to %s", history.Version, clusterVersion.Status.Desired.Version))
+		return true
+	default:
+		return false
+	}
+}
+
+func setExternalCondition(history *upgradev1alpha1.UpgradeHistory, msg string) {
+	condition := history.Conditions.GetCondition(upgradev1alpha1.ExternalUpgradeDetected)
+	if condition == nil {
+		return
+	}
+	condition.Message = msg
+	condition.CompleteTime = history.CompleteTime.DeepCopy()
+	history.Conditions.SetCondition(*condition)
+}
+
+// findUpdateHistory returns the most recent ClusterVersion history entry of the version
+func findUpdateHistory(clusterVersion *configv1.ClusterVersion, version string) *configv1.UpdateHistory {
+	for i := range clusterVersion.Status.History {
+		if clusterVersion.Status.History[i].Version == version {
+			return &clusterVersion.Status.History[i]
+		}
+	}
+	return nil
+}
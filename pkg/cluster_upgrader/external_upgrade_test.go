@@ -0,0 +1,181 @@
+package cluster_upgrader
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/golang/mock/gomock"
+	configv1 "github.com/openshift/api/config/v1"
+	upgradev1alpha1 "github.com/openshift/managed-upgrade-operator/pkg/apis/upgrade/v1alpha1"
+	"github.com/openshift/managed-upgrade-operator/pkg/metrics"
+	"github.com/openshift/managed-upgrade-operator/util/mocks"
+	testStructs "github.com/openshift/managed-upgrade-operator/util/mocks/structs"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("TrackExternalUpgrade", func() {
+	var (
+		mockCtrl       *gomock.Controller
+		mockKubeClient *mocks.MockClient
+		mockUpdater    *mocks.MockStatusWriter
+		upgradeConfig  *upgradev1alpha1.UpgradeConfig
+		upgradeConfigs []upgradev1alpha1.UpgradeConfig
+		clusterVersion configv1.ClusterVersion
+		logger         logr.Logger
+	)
+
+	const (
+		currentVersion  = "4.4.5"
+		externalVersion = "4.4.6"
+	)
+
+	track := func() (bool, error) {
+		mockKubeClient.EXPECT().Get(gomock.Any(), types.NamespacedName{Name: "version"}, gomock.Any()).SetArg(2, clusterVersion)
+		return TrackExternalUpgrade(mockKubeClient, &metrics.Counter{}, upgradeConfig, logger)
+	}
+	forbidden := func() *upgradev1alpha1.UpgradeCondition {
+		return upgradeConfig.Status.Conditions.GetCondition(upgradev1alpha1.ExternalUpgradeForbidden)
+	}
+
+	BeforeEach(func() {
+		mockCtrl = gomock.NewController(GinkgoT())
+		mockKubeClient = mocks.NewMockClient(mockCtrl)
+		mockUpdater = mocks.NewMockStatusWriter(mockCtrl)
+		mockKubeClient.EXPECT().Status().Return(mockUpdater).AnyTimes()
+		mockUpdater.EXPECT().Patch(gomock.Any(), gomock.Any(), gomock.Any()).AnyTimes()
+		upgradeConfigs = nil
+		mockKubeClient.EXPECT().List(gomock.Any(), gomock.Any()).DoAndReturn(
+			func(ctx context.Context, list runtime.Object, opts ...client.ListOption) error {
+				list.(*upgradev1alpha1.UpgradeConfigList).Items = upgradeConfigs
+				return nil
+			}).AnyTimes()
+		upgradeConfig = testStructs.NewUpgradeConfigBuilder().GetUpgradeConfig()
+		upgradeConfig.Spec.ExternalUpgradePolicy = upgradev1alpha1.ExternalUpgradePolicyForbid
+		clusterVersion = configv1.ClusterVersion{
+			Status: configv1.ClusterVersionStatus{
+				Desired: configv1.Update{Version: externalVersion},
+				History: []configv1.UpdateHistory{
+					{Version: externalVersion, State: configv1.PartialUpdate},
+					{Version: currentVersion, State: configv1.CompletedUpdate},
+				},
+				Conditions: []configv1.ClusterOperatorStatusCondition{{Type: configv1.OperatorProgressing, Status: configv1.ConditionTrue}},
+			},
+		}
+		logger = logf.Log.WithName("external upgrade test logger")
+	})
+
+	AfterEach(func() {
+		mockCtrl.Finish()
+	})
+
+	Context("When the cluster version operator reconciles the current version", func() {
+		It("does not record an external upgrade", func() {
+			clusterVersion.Status.Desired.Version = currentVersion
+			clusterVersion.Status.History = clusterVersion.Status.History[1:]
+			upgrading, err := track()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(upgrading).To(BeFalse())
+			Expect(upgradeConfig.Status.History).To(BeEmpty())
+			Expect(forbidden()).To(BeNil())
+		})
+	})
+
+	Context("When an UpgradeConfig desires the version", func() {
+		var other *upgradev1alpha1.UpgradeConfig
+
+		BeforeEach(func() {
+			other = testStructs.NewUpgradeConfigBuilder().WithNamespacedName(types.NamespacedName{Namespace: "test", Name: "other"}).GetUpgradeConfig()
+			other.Spec.Desired.Version = externalVersion
+		})
+
+		It("does not record an external upgrade", func() {
+			upgradeConfigs = []upgradev1alpha1.UpgradeConfig{*other}
+			upgrading, err := track()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(upgrading).To(BeFalse())
+			Expect(upgradeConfig.Status.History).To(BeEmpty())
+		})
+
+		It("records an external upgrade if the UpgradeConfig is a dry run", func() {
+			other.Spec.DryRun = true
+			upgradeConfigs = []upgradev1alpha1.UpgradeConfig{*other}
+			upgrading, err := track()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(upgrading).To(BeTrue())
+			Expect(upgradeConfig.Status.History.GetHistory(externalVersion).Source).To(Equal(upgradev1alpha1.UpgradeSourceExternal))
+		})
+
+		It("records an external upgrade if the UpgradeConfig is being deleted", func() {
+			other.DeletionTimestamp = &metav1.Time{Time: time.Now()}
+			upgradeConfigs = []upgradev1alpha1.UpgradeConfig{*other}
+			upgrading, err := track()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(upgrading).To(BeTrue())
+			Expect(upgradeConfig.Status.History.GetHistory(externalVersion).Source).To(Equal(upgradev1alpha1.UpgradeSourceExternal))
+		})
+	})
+
+	Context("When the policy forbids the upgrade started outside the operator", func() {
+		It("sets the ExternalUpgradeForbidden condition", func() {
+			upgrading, err := track()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(upgrading).To(BeTrue())
+			Expect(upgradeConfig.Status.History.GetHistory(externalVersion).Source).To(Equal(upgradev1alpha1.UpgradeSourceExternal))
+			Expect(forbidden().IsTrue()).To(BeTrue())
+			Expect(forbidden().Reason).To(Equal(ReasonExternalUpgradeStarted))
+		})
+
+		It("keeps the condition set once the upgrade completed", func() {
+			_, err := track()
+			Expect(err).NotTo(HaveOccurred())
+			clusterVersion.Status.History[0].State = configv1.CompletedUpdate
+			clusterVersion.Status.Conditions[0].Status = configv1.ConditionFalse
+			upgrading, err := track()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(upgrading).To(BeFalse())
+			Expect(upgradeConfig.Status.History.GetHistory(externalVersion).Phase).To(Equal(upgradev1alpha1.UpgradePhaseUpgraded))
+			Expect(forbidden().IsTrue()).To(BeTrue())
+		})
+
+		It("clears the condition once acknowledged", func() {
+			_, err := track()
+			Expect(err).NotTo(HaveOccurred())
+			upgradeConfig.Annotations = map[string]string{upgradev1alpha1.ExternalUpgradeAcknowledgedAnnotation: "true"}
+			var acknowledged *upgradev1alpha1.UpgradeConfig
+			mockKubeClient.EXPECT().Patch(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(
+				func(ctx context.Context, obj runtime.Object, patch client.Patch, opts ...client.PatchOption) error {
+					acknowledged = obj.(*upgradev1alpha1.UpgradeConfig).DeepCopy()
+					return nil
+				})
+			err = AcknowledgeExternalUpgrade(mockKubeClient, &metrics.Counter{}, upgradeConfig, logger)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(forbidden().Status).To(Equal(corev1.ConditionFalse))
+			Expect(forbidden().Reason).To(Equal(ReasonExternalUpgradeAcknowledged))
+			Expect(acknowledged.Annotations).NotTo(HaveKey(upgradev1alpha1.ExternalUpgradeAcknowledgedAnnotation))
+
+			// The upgrade in progress is not forbidden again
+			_, err = track()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(forbidden().Status).To(Equal(corev1.ConditionFalse))
+		})
+	})
+
+	Context("When the policy adopts the upgrade started outside the operator", func() {
+		It("does not set the ExternalUpgradeForbidden condition", func() {
+			upgradeConfig.Spec.ExternalUpgradePolicy = upgradev1alpha1.ExternalUpgradePolicyAdopt
+			upgrading, err := track()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(upgrading).To(BeTrue())
+			Expect(forbidden()).To(BeNil())
+		})
+	})
+})
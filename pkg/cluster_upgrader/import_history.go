@@ -0,0 +1,80 @@
+package cluster_upgrader
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/go-logr/logr"
+	configv1 "github.com/openshift/api/config/v1"
+	upgradev1alpha1 "github.com/openshift/managed-upgrade-operator/pkg/apis/upgrade/v1alpha1"
+	"github.com/openshift/managed-upgrade-operator/pkg/upgradestatus"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// ImportHistory backfills the empty history of the UpgradeConfig with the upgrades recorded in the ClusterVersion
+// history, which happened before the operator was installed.
+// The oldest ClusterVersion entry is the installation and the most recent one is left out while it is partial,
+// as it is the upgrade in progress.
+func ImportHistory(c client.Client, upgradeConfig *upgradev1alpha1.UpgradeConfig, logger logr.Logger) error {
+	if len(upgradeConfig.Status.History) > 0 {
+		return nil
+	}
+	clusterVersion := &configv1.ClusterVersion{}
+	err := c.Get(context.TODO(), types.NamespacedName{Name: "version"}, clusterVersion)
+	if err != nil {
+		return err
+	}
+
+	histories := importedHistories(clusterVersion)
+	if len(histories) == 0 {
+		return nil
+	}
+	logger.Info(fmt.Sprintf("importing %d upgrades from the clusterversion history", len(histories)))
+	mutations := []upgradestatus.Mutation{}
+	// Histories are added in front, so the oldest goes first
+	for i := len(histories) - 1; i >= 0; i-- {
+		mutations = append(mutations, upgradestatus.SetHistory(histories[i]))
+	}
+	return upgradestatus.Patch(c, upgradeConfig, mutations...)
+}
+
+// importedHistories converts the ClusterVersion history to upgrade histories, most recent first
+func importedHistories(clusterVersion *configv1.ClusterVersion) []upgradev1alpha1.UpgradeHistory {
+	updates := clusterVersion.Status.History
+	if len(updates) > 0 {
+		updates = updates[:len(updates)-1]
+	}
+	if len(updates) > 0 && updates[0].State == configv1.PartialUpdate {
+		updates = updates[1:]
+	}
+
+	histories := []upgradev1alpha1.UpgradeHistory{}
+	seen := map[string]bool{}
+	for _, update := range updates {
+		if seen[update.Version] {
+			continue
+		}
+		seen[update.Version] = true
+
+		history := upgradev1alpha1.UpgradeHistory{
+			Version:      update.Version,
+			Phase:        upgradev1alpha1.UpgradePhaseUpgraded,
+			Source:       upgradev1alpha1.UpgradeSourceImported,
+			StartTime:    update.StartedTime.DeepCopy(),
+			CompleteTime: update.CompletionTime.DeepCopy(),
+			Conditions:   upgradev1alpha1.NewConditions(),
+		}
+		// A partial update was superseded by a later one before it completed
+		if update.State != configv1.CompletedUpdate {
+			history.Phase = upgradev1alpha1.UpgradePhaseFailed
+		}
+		condition := newUpgradeCondition(string(update.State), fmt.Sprintf("imported from the clusterversion history, image %s", update.Image), upgradev1alpha1.ImportedFromClusterVersion, corev1.ConditionTrue)
+		condition.StartTime = history.StartTime.DeepCopy()
+		condition.CompleteTime = history.CompleteTime.DeepCopy()
+		history.Conditions.SetCondition(*condition)
+		histories = append(histories, history)
+	}
+	return histories
+}
@@ -34,6 +34,20 @@ func (m *MockClusterUpgrader) EXPECT() *MockClusterUpgraderMockRecorder {
 	return m.recorder
 }
 
+// DryRun mocks base method
+func (m *MockClusterUpgrader) DryRun(arg0 *v1alpha1.UpgradeConfig, arg1 logr.Logger) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DryRun", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DryRun indicates an expected call of DryRun
+func (mr *MockClusterUpgraderMockRecorder) DryRun(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DryRun", reflect.TypeOf((*MockClusterUpgrader)(nil).DryRun), arg0, arg1)
+}
+
 // UpgradeCluster mocks base method
 func (m *MockClusterUpgrader) UpgradeCluster(arg0 *v1alpha1.UpgradeConfig, arg1 logr.Logger) error {
 	m.ctrl.T.Helper()
@@ -0,0 +1,140 @@
+package cluster_upgrader
+
+import (
+	"github.com/go-logr/logr"
+	"github.com/golang/mock/gomock"
+	configv1 "github.com/openshift/api/config/v1"
+	upgradev1alpha1 "github.com/openshift/managed-upgrade-operator/pkg/apis/upgrade/v1alpha1"
+	"github.com/openshift/managed-upgrade-operator/pkg/metrics"
+	"github.com/openshift/managed-upgrade-operator/util/mocks"
+	testStructs "github.com/openshift/managed-upgrade-operator/util/mocks/structs"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("ClusterVersion overrides", func() {
+	var (
+		mockCtrl       *gomock.Controller
+		mockKubeClient *mocks.MockClient
+		mockUpdater    *mocks.MockStatusWriter
+		upgradeConfig  *upgradev1alpha1.UpgradeConfig
+		logger         logr.Logger
+	)
+
+	dns := configv1.ComponentOverride{Kind: "Deployment", Group: "apps", Namespace: "openshift-dns-operator", Name: "dns-operator", Unmanaged: true}
+	ingress := configv1.ComponentOverride{Kind: "Deployment", Group: "apps", Namespace: "openshift-ingress-operator", Name: "ingress-operator", Unmanaged: true}
+	monitoring := configv1.ComponentOverride{Kind: "Deployment", Group: "apps", Namespace: "openshift-monitoring", Name: "cluster-monitoring-operator", Unmanaged: true}
+	overrides := []configv1.ComponentOverride{dns, ingress}
+
+	expectClusterVersion := func(overrides []configv1.ComponentOverride) *configv1.ClusterVersion {
+		patched := &configv1.ClusterVersion{}
+		mockKubeClient.EXPECT().Get(gomock.Any(), types.NamespacedName{Name: "version"}, gomock.Any()).SetArg(2, configv1.ClusterVersion{
+			Spec: configv1.ClusterVersionSpec{Channel: "fast-4.4", Overrides: overrides},
+		})
+		mockKubeClient.EXPECT().Patch(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(
+			func(ctx interface{}, obj *configv1.ClusterVersion, patch client.Patch) error {
+				obj.DeepCopyInto(patched)
+				return nil
+			})
+		return patched
+	}
+
+	BeforeEach(func() {
+		mockCtrl = gomock.NewController(GinkgoT())
+		mockKubeClient = mocks.NewMockClient(mockCtrl)
+		mockUpdater = mocks.NewMockStatusWriter(mockCtrl)
+		mockKubeClient.EXPECT().Status().Return(mockUpdater).AnyTimes()
+		mockUpdater.EXPECT().Patch(gomock.Any(), gomock.Any(), gomock.Any()).AnyTimes()
+		upgradeConfig = testStructs.NewUpgradeConfigBuilder().GetUpgradeConfig()
+		logger = logf.Log.WithName("overrides test logger")
+	})
+
+	AfterEach(func() {
+		mockCtrl.Finish()
+	})
+
+	Context("When splitting the overrides with the policy", func() {
+		It("removes all of them without policy", func() {
+			kept, removed := splitOverrides(overrides, nil)
+			Expect(kept).To(BeEmpty())
+			Expect(removed).To(Equal(overrides))
+		})
+		It("keeps all of them with the Keep policy", func() {
+			kept, removed := splitOverrides(overrides, &upgradev1alpha1.OverridePolicy{Policy: upgradev1alpha1.OverridePolicyKeep})
+			Expect(kept).To(Equal(overrides))
+			Expect(removed).To(BeEmpty())
+		})
+		It("removes all of them with the ClearAll policy", func() {
+			kept, removed := splitOverrides(overrides, &upgradev1alpha1.OverridePolicy{Policy: upgradev1alpha1.OverridePolicyClearAll})
+			Expect(kept).To(BeEmpty())
+			Expect(removed).To(Equal(overrides))
+		})
+		It("removes the matching ones with the ClearMatching policy", func() {
+			kept, removed := splitOverrides(overrides, &upgradev1alpha1.OverridePolicy{
+				Policy:   upgradev1alpha1.OverridePolicyClearMatching,
+				Matching: []upgradev1alpha1.OverrideMatcher{{Namespace: "openshift-ingress-operator"}},
+			})
+			Expect(kept).To(Equal([]configv1.ComponentOverride{dns}))
+			Expect(removed).To(Equal([]configv1.ComponentOverride{ingress}))
+		})
+	})
+
+	Context("When merging overrides", func() {
+		It("appends the overrides of components not overridden yet", func() {
+			changed := dns
+			changed.Unmanaged = false
+			Expect(mergeOverrides([]configv1.ComponentOverride{dns}, []configv1.ComponentOverride{changed, ingress})).To(Equal(overrides))
+		})
+	})
+
+	Context("When commencing the upgrade", func() {
+		It("records the removed overrides the policy restores", func() {
+			upgradeConfig.Spec.OverridePolicy = &upgradev1alpha1.OverridePolicy{Policy: upgradev1alpha1.OverridePolicyClearAll, Restore: true}
+			upgradeConfig.Status.RemovedOverrides = []configv1.ComponentOverride{monitoring}
+			clusterVersion := expectClusterVersion(overrides)
+			done, err := CommenceUpgrade(mockKubeClient, &metrics.Counter{}, nil, upgradeConfig, logger)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(done).To(BeTrue())
+			Expect(clusterVersion.Spec.Overrides).To(BeEmpty())
+			Expect(clusterVersion.Spec.DesiredUpdate.Version).To(Equal(upgradeConfig.Spec.Desired.Version))
+			Expect(upgradeConfig.Status.RemovedOverrides).To(Equal([]configv1.ComponentOverride{monitoring, dns, ingress}))
+		})
+
+		It("forgets the removed overrides when the policy does not restore them", func() {
+			upgradeConfig.Spec.OverridePolicy = &upgradev1alpha1.OverridePolicy{Policy: upgradev1alpha1.OverridePolicyClearAll}
+			upgradeConfig.Status.RemovedOverrides = []configv1.ComponentOverride{monitoring}
+			clusterVersion := expectClusterVersion(overrides)
+			_, err := CommenceUpgrade(mockKubeClient, &metrics.Counter{}, nil, upgradeConfig, logger)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(clusterVersion.Spec.Overrides).To(BeEmpty())
+			Expect(upgradeConfig.Status.RemovedOverrides).To(BeEmpty())
+		})
+	})
+
+	Context("When restoring the overrides", func() {
+		BeforeEach(func() {
+			upgradeConfig.Spec.OverridePolicy = &upgradev1alpha1.OverridePolicy{Policy: upgradev1alpha1.OverridePolicyClearAll, Restore: true}
+		})
+
+		It("puts the removed overrides back and forgets them", func() {
+			upgradeConfig.Status.RemovedOverrides = []configv1.ComponentOverride{ingress}
+			clusterVersion := expectClusterVersion([]configv1.ComponentOverride{dns})
+			done, err := RestoreOverrides(mockKubeClient, &metrics.Counter{}, nil, upgradeConfig, logger)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(done).To(BeTrue())
+			Expect(clusterVersion.Spec.Overrides).To(Equal(overrides))
+			Expect(upgradeConfig.Status.RemovedOverrides).To(BeEmpty())
+		})
+
+		It("does nothing when no override was removed", func() {
+			mockKubeClient.EXPECT().Get(gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+			done, err := RestoreOverrides(mockKubeClient, &metrics.Counter{}, nil, upgradeConfig, logger)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(done).To(BeTrue())
+		})
+	})
+})
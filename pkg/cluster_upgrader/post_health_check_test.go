@@ -0,0 +1,114 @@
+package cluster_upgrader
+
+import (
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/golang/mock/gomock"
+	upgradev1alpha1 "github.com/openshift/managed-upgrade-operator/pkg/apis/upgrade/v1alpha1"
+	"github.com/openshift/managed-upgrade-operator/pkg/metrics"
+	"github.com/openshift/managed-upgrade-operator/pkg/operatorconfig"
+	"github.com/openshift/managed-upgrade-operator/util/mocks"
+	testStructs "github.com/openshift/managed-upgrade-operator/util/mocks/structs"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("PostClusterHealthCheck", func() {
+	var (
+		mockCtrl       *gomock.Controller
+		mockKubeClient *mocks.MockClient
+		mockUpdater    *mocks.MockStatusWriter
+		upgradeConfig  *upgradev1alpha1.UpgradeConfig
+		cfg            *operatorconfig.Config
+		logger         logr.Logger
+	)
+
+	run := func() (bool, error) {
+		operatorconfig.Set(cfg, "", "")
+		return PostClusterHealthCheck(mockKubeClient, &metrics.Counter{}, nil, upgradeConfig, logger)
+	}
+	settle := func() *upgradev1alpha1.HealthCheckSettle {
+		return upgradeConfig.Status.History.GetHistory(upgradeConfig.Spec.Desired.Version).Settle
+	}
+	ago := func(d time.Duration) *metav1.Time {
+		return &metav1.Time{Time: time.Now().Add(-d)}
+	}
+
+	BeforeEach(func() {
+		mockCtrl = gomock.NewController(GinkgoT())
+		mockKubeClient = mocks.NewMockClient(mockCtrl)
+		mockUpdater = mocks.NewMockStatusWriter(mockCtrl)
+		mockKubeClient.EXPECT().Status().Return(mockUpdater).AnyTimes()
+		mockUpdater.EXPECT().Patch(gomock.Any(), gomock.Any(), gomock.Any()).AnyTimes()
+		cfg = operatorconfig.DefaultConfig()
+		// No health check passes, an unknown one fails
+		cfg.HealthCheck.PostUpgrade = []string{}
+		cfg.HealthCheck.SettleMinutes = 5
+		upgradeConfig = testStructs.NewUpgradeConfigBuilder().GetUpgradeConfig()
+		upgradeConfig.Status.History = upgradev1alpha1.UpgradeHistories{{
+			Version:    upgradeConfig.Spec.Desired.Version,
+			Phase:      upgradev1alpha1.UpgradePhaseUpgrading,
+			Conditions: upgradev1alpha1.NewConditions(),
+		}}
+		logger = logf.Log.WithName("post health check test logger")
+	})
+
+	AfterEach(func() {
+		operatorconfig.Set(operatorconfig.DefaultConfig(), "", "")
+		mockCtrl.Finish()
+	})
+
+	Context("When the health checks pass", func() {
+		It("waits for them to pass for the settle period", func() {
+			settled, err := run()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(settled).To(BeFalse())
+			Expect(settle().StartTime).NotTo(BeNil())
+			Expect(settle().PassingSince).NotTo(BeNil())
+			Expect(settle().SettledTime).To(BeNil())
+		})
+
+		It("settles once they passed for the settle period and records how long it took", func() {
+			upgradeConfig.Status.History[0].Settle = &upgradev1alpha1.HealthCheckSettle{StartTime: ago(10 * time.Minute), PassingSince: ago(6 * time.Minute), Flaps: 1}
+			settled, err := run()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(settled).To(BeTrue())
+			Expect(settle().SettledTime).NotTo(BeNil())
+			Expect(settle().SettleSeconds).To(BeNumerically("~", 600, 5))
+			Expect(settle().Flaps).To(Equal(int32(1)))
+		})
+
+		It("settles right away without settle period", func() {
+			cfg.HealthCheck.SettleMinutes = 0
+			settled, err := run()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(settled).To(BeTrue())
+			Expect(settle().SettleSeconds).To(BeZero())
+		})
+	})
+
+	Context("When the health checks fail after passing", func() {
+		BeforeEach(func() {
+			cfg.HealthCheck.PostUpgrade = []string{"UnknownHealthCheck"}
+			upgradeConfig.Status.History[0].Settle = &upgradev1alpha1.HealthCheckSettle{StartTime: ago(10 * time.Minute), PassingSince: ago(2 * time.Minute)}
+		})
+
+		It("counts a flap and waits for them to pass again", func() {
+			settled, err := run()
+			Expect(err).To(HaveOccurred())
+			Expect(settled).To(BeFalse())
+			Expect(settle().Flaps).To(Equal(int32(1)))
+			Expect(settle().PassingSince).To(BeNil())
+		})
+
+		It("does not count a flap again while they keep failing", func() {
+			_, _ = run()
+			_, _ = run()
+			Expect(settle().Flaps).To(Equal(int32(1)))
+		})
+	})
+})
@@ -0,0 +1,69 @@
+package cluster_upgrader
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+	upgradev1alpha1 "github.com/openshift/managed-upgrade-operator/pkg/apis/upgrade/v1alpha1"
+	"github.com/openshift/managed-upgrade-operator/pkg/maintenance"
+	"github.com/openshift/managed-upgrade-operator/pkg/metrics"
+	"github.com/openshift/managed-upgrade-operator/pkg/operatorconfig"
+	"github.com/openshift/managed-upgrade-operator/pkg/prometheus"
+	"github.com/openshift/managed-upgrade-operator/pkg/regression"
+	"github.com/openshift/managed-upgrade-operator/pkg/upgradestatus"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// RegressionCheck returns the step comparing the SLO metrics, queried with clients of the builder, over a window before
+// the upgrade commenced and a window after it was verified, once the latter is over.
+// The comparison is recorded in the history, a regression fails the upgrade.
+func RegressionCheck(prometheusBuilder prometheus.PrometheusClientBuilder) UpgradeStep {
+	return func(c client.Client, metricsClient metrics.Metrics, m maintenance.Maintenance, upgradeConfig *upgradev1alpha1.UpgradeConfig, logger logr.Logger) (bool, error) {
+		return regressionCheck(c, prometheusBuilder, upgradeConfig, logger)
+	}
+}
+
+func regressionCheck(c client.Client, prometheusBuilder prometheus.PrometheusClientBuilder, upgradeConfig *upgradev1alpha1.UpgradeConfig, logger logr.Logger) (bool, error) {
+	cfg := operatorconfig.Get()
+	history := upgradeConfig.Status.History.GetHistory(upgradeConfig.Spec.Desired.Version)
+	if history == nil {
+		return false, fmt.Errorf("no history for version %s", upgradeConfig.Spec.Desired.Version)
+	}
+	commenced := history.Conditions.GetCondition(upgradev1alpha1.CommenceUpgrade)
+	verified := history.Conditions.GetCondition(upgradev1alpha1.PostUpgradeVerification)
+	if commenced == nil || commenced.StartTime == nil || verified == nil || verified.CompleteTime == nil {
+		logger.Info("the upgrade has no commence or verification time, the SLO metrics can not be compared")
+		return true, nil
+	}
+
+	afterEnd := verified.CompleteTime.Add(cfg.RegressionWindow())
+	if time.Now().Before(afterEnd) {
+		logger.Info(fmt.Sprintf("comparing the SLO metrics once the window after the upgrade is over at %s", afterEnd))
+		return false, nil
+	}
+
+	promClient, err := prometheusBuilder.NewClient()
+	if err != nil {
+		return false, err
+	}
+	comparison, err := regression.Compare(promClient, cfg.Regression, commenced.StartTime.Time, verified.CompleteTime.Time)
+	if err != nil {
+		return false, err
+	}
+	err = upgradestatus.Patch(c, upgradeConfig, upgradestatus.SetRegression(upgradeConfig.Spec.Desired.Version, *comparison))
+	if err != nil {
+		return false, err
+	}
+
+	regressed := regression.Regressed(comparison)
+	if len(regressed) > 0 {
+		messages := []string{}
+		for _, m := range regressed {
+			messages = append(messages, fmt.Sprintf("%s %s", m.Name, m.Message))
+		}
+		return false, &failedStepError{msg: fmt.Sprintf("%d of %d SLO metrics regressed: %s", len(regressed), len(comparison.Metrics), strings.Join(messages, "; "))}
+	}
+	return true, nil
+}
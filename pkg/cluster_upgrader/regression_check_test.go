@@ -0,0 +1,122 @@
+package cluster_upgrader
+
+import (
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/golang/mock/gomock"
+	upgradev1alpha1 "github.com/openshift/managed-upgrade-operator/pkg/apis/upgrade/v1alpha1"
+	"github.com/openshift/managed-upgrade-operator/pkg/metrics"
+	"github.com/openshift/managed-upgrade-operator/pkg/operatorconfig"
+	prometheusMocks "github.com/openshift/managed-upgrade-operator/pkg/prometheus/mocks"
+	"github.com/openshift/managed-upgrade-operator/util/mocks"
+	testStructs "github.com/openshift/managed-upgrade-operator/util/mocks/structs"
+	"github.com/prometheus/common/model"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("RegressionCheck", func() {
+	var (
+		mockCtrl              *gomock.Controller
+		mockKubeClient        *mocks.MockClient
+		mockUpdater           *mocks.MockStatusWriter
+		mockPrometheusBuilder *prometheusMocks.MockPrometheusClientBuilder
+		mockPrometheusClient  *prometheusMocks.MockPrometheusClient
+		upgradeConfig         *upgradev1alpha1.UpgradeConfig
+		verified              time.Time
+		logger                logr.Logger
+	)
+
+	series := func(value float64) model.Matrix {
+		return model.Matrix{&model.SampleStream{Values: []model.SamplePair{{Value: model.SampleValue(value)}}}}
+	}
+	expectWindows := func(before float64, after float64) {
+		mockPrometheusBuilder.EXPECT().NewClient().Return(mockPrometheusClient, nil)
+		gomock.InOrder(
+			mockPrometheusClient.EXPECT().QueryRange("api_error_rate", gomock.Any(), gomock.Any(), gomock.Any()).Return(series(before), nil),
+			mockPrometheusClient.EXPECT().QueryRange("api_error_rate", gomock.Any(), gomock.Any(), gomock.Any()).Return(series(after), nil),
+		)
+		mockUpdater.EXPECT().Patch(gomock.Any(), gomock.Any(), gomock.Any())
+	}
+	run := func() (bool, error) {
+		return RegressionCheck(mockPrometheusBuilder)(mockKubeClient, &metrics.Counter{}, nil, upgradeConfig, logger)
+	}
+
+	BeforeEach(func() {
+		mockCtrl = gomock.NewController(GinkgoT())
+		mockKubeClient = mocks.NewMockClient(mockCtrl)
+		mockUpdater = mocks.NewMockStatusWriter(mockCtrl)
+		mockPrometheusBuilder = prometheusMocks.NewMockPrometheusClientBuilder(mockCtrl)
+		mockPrometheusClient = prometheusMocks.NewMockPrometheusClient(mockCtrl)
+		mockKubeClient.EXPECT().Status().Return(mockUpdater).AnyTimes()
+		maxIncrease := 0.01
+		cfg := operatorconfig.DefaultConfig()
+		cfg.Regression.Metrics = []operatorconfig.RegressionMetric{{Name: "api-errors", Query: "api_error_rate", MaxIncrease: &maxIncrease}}
+		operatorconfig.Set(cfg, "", "")
+
+		verified = time.Now().Add(-time.Hour)
+		commenced := metav1.NewTime(verified.Add(-2 * time.Hour))
+		upgradeConfig = testStructs.NewUpgradeConfigBuilder().GetUpgradeConfig()
+		upgradeConfig.Status.History = upgradev1alpha1.UpgradeHistories{{
+			Version:    upgradeConfig.Spec.Desired.Version,
+			Phase:      upgradev1alpha1.UpgradePhaseUpgrading,
+			Conditions: upgradev1alpha1.NewConditions(),
+		}}
+		upgradeConfig.Status.History[0].Conditions.SetCondition(upgradev1alpha1.UpgradeCondition{
+			Type: upgradev1alpha1.CommenceUpgrade, Status: corev1.ConditionTrue, StartTime: &commenced,
+		})
+		logger = logf.Log.WithName("regression check test logger")
+	})
+
+	JustBeforeEach(func() {
+		upgradeConfig.Status.History[0].Conditions.SetCondition(upgradev1alpha1.UpgradeCondition{
+			Type: upgradev1alpha1.PostUpgradeVerification, Status: corev1.ConditionTrue, CompleteTime: &metav1.Time{Time: verified},
+		})
+	})
+
+	AfterEach(func() {
+		operatorconfig.Set(operatorconfig.DefaultConfig(), "", "")
+		mockCtrl.Finish()
+	})
+
+	Context("When the window after the upgrade is not over", func() {
+		BeforeEach(func() {
+			verified = time.Now().Add(-time.Minute)
+		})
+		It("waits without querying Prometheus", func() {
+			mockPrometheusBuilder.EXPECT().NewClient().Times(0)
+			done, err := run()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(done).To(BeFalse())
+		})
+	})
+
+	Context("When the SLO metrics did not regress", func() {
+		It("records the comparison and succeeds", func() {
+			expectWindows(0.02, 0.025)
+			done, err := run()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(done).To(BeTrue())
+			regression := upgradeConfig.Status.History.GetHistory(upgradeConfig.Spec.Desired.Version).Regression
+			Expect(regression.Metrics).To(HaveLen(1))
+			Expect(regression.Metrics[0].Regressed).To(BeFalse())
+		})
+	})
+
+	Context("When an SLO metric regressed", func() {
+		It("records the comparison and fails the upgrade", func() {
+			expectWindows(0.02, 0.5)
+			done, err := run()
+			Expect(done).To(BeFalse())
+			Expect(err).To(BeAssignableToTypeOf(&failedStepError{}))
+			Expect(err.Error()).To(ContainSubstring("1 of 1 SLO metrics regressed: api-errors"))
+			regression := upgradeConfig.Status.History.GetHistory(upgradeConfig.Spec.Desired.Version).Regression
+			Expect(regression.Metrics[0].Regressed).To(BeTrue())
+		})
+	})
+})
@@ -0,0 +1,125 @@
+package cluster_upgrader
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-logr/logr"
+	configv1 "github.com/openshift/api/config/v1"
+	upgradev1alpha1 "github.com/openshift/managed-upgrade-operator/pkg/apis/upgrade/v1alpha1"
+	"github.com/openshift/managed-upgrade-operator/pkg/upgradestatus"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Steps done before the upgrade is commenced which are not repeated when the upgrade is retargeted.
+// The approval is given for a version, so it is asked again for the new one.
+var retargetKeptSteps = []upgradev1alpha1.UpgradeConditionType{
+	upgradev1alpha1.UpgradePreHealthCheck,
+	upgradev1alpha1.UpgradeScaleUpExtraNodes,
+}
+
+// Reasons of the validation of a retargeted upgrade
+const (
+	ReasonRetargeted       = "Retargeted"
+	ReasonRetargetRejected = "RetargetRejected"
+)
+
+// RetargetUpgrade moves the upgrade in progress to another version over to the desired version of the UpgradeConfig.
+// Once the control plane is moving to the version in progress, the desired version must be an update available from it.
+// The upgrade in progress is superseded and the upgrade to the desired version picks up at CommenceUpgrade, keeping
+// the health check and extra nodes already done. A history of the desired version superseded earlier is started over
+// the same way. If the desired version is not available, its history is failed and the upgrade in progress carries on.
+// It returns the history of the desired version, or nil if there is no upgrade in progress.
+func RetargetUpgrade(c client.Client, upgradeConfig *upgradev1alpha1.UpgradeConfig, logger logr.Logger) (*upgradev1alpha1.UpgradeHistory, error) {
+	inFlight := inFlightUpgrade(upgradeConfig)
+	if inFlight == nil {
+		return nil, nil
+	}
+
+	desired := upgradeConfig.Spec.Desired.Version
+	history := upgradev1alpha1.UpgradeHistory{
+		Version:    desired,
+		Phase:      upgradev1alpha1.UpgradePhaseNew,
+		Conditions: upgradev1alpha1.NewConditions(),
+	}
+
+	if inFlight.Conditions.IsTrueFor(upgradev1alpha1.CommenceUpgrade) {
+		clusterVersion := &configv1.ClusterVersion{}
+		err := c.Get(context.TODO(), types.NamespacedName{Name: "version"}, clusterVersion)
+		if err != nil {
+			return nil, err
+		}
+		available, err := isAvailableUpdate(clusterVersion, inFlight.Version, desired, upgradeConfig.Spec.Desired.Channel)
+		if err != nil {
+			return nil, err
+		}
+		condition := newUpgradeCondition(ReasonRetargeted, fmt.Sprintf("%s is an available update from %s", desired, inFlight.Version), upgradev1alpha1.UpgradeValidated, corev1.ConditionTrue)
+		condition.StartTime = &metav1.Time{Time: time.Now()}
+		condition.CompleteTime = condition.StartTime.DeepCopy()
+		if !available {
+			logger.Info(fmt.Sprintf("cannot retarget the upgrade to %s to %s", inFlight.Version, desired))
+			condition.Status = corev1.ConditionFalse
+			condition.Reason = ReasonRetargetRejected
+			condition.Message = fmt.Sprintf("cannot retarget the upgrade to %s, %s is not an available update from it; set the desired version back to %s to resume it", inFlight.Version, desired, inFlight.Version)
+			history.Phase = upgradev1alpha1.UpgradePhaseFailed
+			history.StartTime = condition.StartTime.DeepCopy()
+			history.CompleteTime = condition.CompleteTime.DeepCopy()
+			history.Conditions.SetCondition(*condition)
+			err = upgradestatus.PatchHistory(c, upgradeConfig, history)
+			if err != nil {
+				return nil, err
+			}
+			return &history, nil
+		}
+		history.Conditions.SetCondition(*condition)
+	}
+
+	for _, key := range retargetKeptSteps {
+		condition := inFlight.Conditions.GetCondition(key)
+		if condition != nil && condition.IsTrue() {
+			history.Conditions.SetCondition(*condition)
+		}
+	}
+
+	logger.Info(fmt.Sprintf("retargeting the upgrade to %s to %s", inFlight.Version, desired))
+	inFlight.Phase = upgradev1alpha1.UpgradePhaseSuperseded
+	inFlight.CompleteTime = &metav1.Time{Time: time.Now()}
+	err := upgradestatus.Patch(c, upgradeConfig, upgradestatus.SetHistory(*inFlight), upgradestatus.SetHistory(history))
+	if err != nil {
+		return nil, err
+	}
+	return &history, nil
+}
+
+// isOperatorUpgrade returns true if the upgrade was started by the operator
+func isOperatorUpgrade(history *upgradev1alpha1.UpgradeHistory) bool {
+	return history.Source == "" || history.Source == upgradev1alpha1.UpgradeSourceOperator
+}
+
+// RejectedRetarget returns the upgrade in progress when it could not be retargeted to the desired version, nil otherwise.
+// The upgrade in progress is then to be driven to its own version.
+func RejectedRetarget(upgradeConfig *upgradev1alpha1.UpgradeConfig) *upgradev1alpha1.UpgradeHistory {
+	history := upgradeConfig.Status.History.GetHistory(upgradeConfig.Spec.Desired.Version)
+	if history == nil || history.Phase != upgradev1alpha1.UpgradePhaseFailed {
+		return nil
+	}
+	validated := history.Conditions.GetCondition(upgradev1alpha1.UpgradeValidated)
+	if validated == nil || validated.Reason != ReasonRetargetRejected {
+		return nil
+	}
+	return inFlightUpgrade(upgradeConfig)
+}
+
+// inFlightUpgrade returns the upgrade the operator is performing to another version than the desired one, if any
+func inFlightUpgrade(upgradeConfig *upgradev1alpha1.UpgradeConfig) *upgradev1alpha1.UpgradeHistory {
+	for _, h := range upgradeConfig.Status.History {
+		if !h.DryRun && isOperatorUpgrade(&h) && h.Phase == upgradev1alpha1.UpgradePhaseUpgrading && h.Version != upgradeConfig.Spec.Desired.Version {
+			return h.DeepCopy()
+		}
+	}
+	return nil
+}
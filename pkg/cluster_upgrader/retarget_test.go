@@ -0,0 +1,155 @@
+package cluster_upgrader
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/go-logr/logr"
+	"github.com/golang/mock/gomock"
+	configv1 "github.com/openshift/api/config/v1"
+	upgradev1alpha1 "github.com/openshift/managed-upgrade-operator/pkg/apis/upgrade/v1alpha1"
+	"github.com/openshift/managed-upgrade-operator/util/mocks"
+	testStructs "github.com/openshift/managed-upgrade-operator/util/mocks/structs"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+
+	. "github.com/onsi/ginkgo"
+	"github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("RetargetUpgrade", func() {
+	var (
+		mockCtrl       *gomock.Controller
+		mockKubeClient *mocks.MockClient
+		mockUpdater    *mocks.MockStatusWriter
+		server         *httptest.Server
+		upgradeConfig  *upgradev1alpha1.UpgradeConfig
+		logger         logr.Logger
+	)
+
+	// In the channel, 4.4.5 updates to 4.4.6 which updates to 4.4.7
+	graph := map[string]interface{}{
+		"nodes": []map[string]string{{"version": "4.4.5"}, {"version": "4.4.6"}, {"version": "4.4.7"}},
+		"edges": [][]int{{0, 1}, {1, 2}},
+	}
+	upgrade := func(version string, phase upgradev1alpha1.UpgradePhase, steps ...upgradev1alpha1.UpgradeConditionType) upgradev1alpha1.UpgradeHistory {
+		history := upgradev1alpha1.UpgradeHistory{Version: version, Phase: phase, Conditions: upgradev1alpha1.NewConditions()}
+		for _, step := range steps {
+			history.Conditions.SetCondition(upgradev1alpha1.UpgradeCondition{Type: step, Status: corev1.ConditionTrue})
+		}
+		return history
+	}
+	preUpgrade := []upgradev1alpha1.UpgradeConditionType{upgradev1alpha1.UpgradeValidated, upgradev1alpha1.UpgradePreHealthCheck, upgradev1alpha1.UpgradeScaleUpExtraNodes}
+	commenced := append(preUpgrade, upgradev1alpha1.CommenceUpgrade)
+
+	BeforeEach(func() {
+		mockCtrl = gomock.NewController(GinkgoT())
+		mockKubeClient = mocks.NewMockClient(mockCtrl)
+		mockUpdater = mocks.NewMockStatusWriter(mockCtrl)
+		mockKubeClient.EXPECT().Status().Return(mockUpdater).AnyTimes()
+		mockUpdater.EXPECT().Patch(gomock.Any(), gomock.Any(), gomock.Any()).AnyTimes()
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_ = json.NewEncoder(w).Encode(graph)
+		}))
+		mockKubeClient.EXPECT().Get(gomock.Any(), types.NamespacedName{Name: "version"}, gomock.Any()).SetArg(2, configv1.ClusterVersion{
+			Spec: configv1.ClusterVersionSpec{ClusterID: "5a8f0bd2-0c5d-4a42-a4c4-51a1c4ac3f4e", Upstream: configv1.URL(server.URL)},
+		}).AnyTimes()
+		upgradeConfig = testStructs.NewUpgradeConfigBuilder().GetUpgradeConfig()
+		logger = logf.Log.WithName("retarget test logger")
+	})
+
+	AfterEach(func() {
+		server.Close()
+		mockCtrl.Finish()
+	})
+
+	type retarget struct {
+		histories upgradev1alpha1.UpgradeHistories
+		desired   string
+		// Expected phase of the desired version and of the version in progress
+		desiredPhase  upgradev1alpha1.UpgradePhase
+		inFlightPhase upgradev1alpha1.UpgradePhase
+		// Expected reason of the validation of the desired version, empty if it is to be validated again
+		validation string
+	}
+
+	table.DescribeTable("retargets the upgrade in progress",
+		func(r retarget) {
+			upgradeConfig.Status.History = r.histories
+			upgradeConfig.Spec.Desired.Version = r.desired
+			inFlight := inFlightUpgrade(upgradeConfig)
+			history, err := RetargetUpgrade(mockKubeClient, upgradeConfig, logger)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(history.Phase).To(Equal(r.desiredPhase))
+			Expect(upgradeConfig.Status.History.GetHistory(r.desired).Phase).To(Equal(r.desiredPhase))
+			Expect(upgradeConfig.Status.History.GetHistory(inFlight.Version).Phase).To(Equal(r.inFlightPhase))
+
+			validated := history.Conditions.GetCondition(upgradev1alpha1.UpgradeValidated)
+			if len(r.validation) == 0 {
+				Expect(validated).To(BeNil())
+			} else {
+				Expect(validated.Reason).To(Equal(r.validation))
+			}
+			if r.desiredPhase == upgradev1alpha1.UpgradePhaseFailed {
+				Expect(RejectedRetarget(upgradeConfig).Version).To(Equal(inFlight.Version))
+				Expect(history.Conditions.GetCondition(upgradev1alpha1.UpgradePreHealthCheck)).To(BeNil())
+			} else {
+				Expect(RejectedRetarget(upgradeConfig)).To(BeNil())
+				Expect(history.Conditions.IsTrueFor(upgradev1alpha1.UpgradePreHealthCheck)).To(BeTrue())
+				Expect(history.Conditions.IsTrueFor(upgradev1alpha1.UpgradeScaleUpExtraNodes)).To(BeTrue())
+				Expect(history.Conditions.GetCondition(upgradev1alpha1.CommenceUpgrade)).To(BeNil())
+			}
+		},
+		table.Entry("before the upgrade is commenced", retarget{
+			histories:     upgradev1alpha1.UpgradeHistories{upgrade("4.4.6", upgradev1alpha1.UpgradePhaseUpgrading, preUpgrade...)},
+			desired:       "4.4.5",
+			desiredPhase:  upgradev1alpha1.UpgradePhaseNew,
+			inFlightPhase: upgradev1alpha1.UpgradePhaseSuperseded,
+		}),
+		table.Entry("to an update available from the version in progress", retarget{
+			histories:     upgradev1alpha1.UpgradeHistories{upgrade("4.4.6", upgradev1alpha1.UpgradePhaseUpgrading, commenced...)},
+			desired:       "4.4.7",
+			desiredPhase:  upgradev1alpha1.UpgradePhaseNew,
+			inFlightPhase: upgradev1alpha1.UpgradePhaseSuperseded,
+			validation:    ReasonRetargeted,
+		}),
+		table.Entry("to a version not available from the version in progress", retarget{
+			histories:     upgradev1alpha1.UpgradeHistories{upgrade("4.4.6", upgradev1alpha1.UpgradePhaseUpgrading, commenced...)},
+			desired:       "4.4.5",
+			desiredPhase:  upgradev1alpha1.UpgradePhaseFailed,
+			inFlightPhase: upgradev1alpha1.UpgradePhaseUpgrading,
+			validation:    ReasonRetargetRejected,
+		}),
+		table.Entry("back to the version superseded before the upgrade is commenced", retarget{
+			histories: upgradev1alpha1.UpgradeHistories{
+				upgrade("4.4.6", upgradev1alpha1.UpgradePhaseSuperseded, commenced...),
+				upgrade("4.4.7", upgradev1alpha1.UpgradePhaseUpgrading, preUpgrade...),
+			},
+			desired:       "4.4.6",
+			desiredPhase:  upgradev1alpha1.UpgradePhaseNew,
+			inFlightPhase: upgradev1alpha1.UpgradePhaseSuperseded,
+		}),
+		table.Entry("back to the version superseded once the upgrade is commenced", retarget{
+			histories: upgradev1alpha1.UpgradeHistories{
+				upgrade("4.4.6", upgradev1alpha1.UpgradePhaseSuperseded, commenced...),
+				upgrade("4.4.7", upgradev1alpha1.UpgradePhaseUpgrading, commenced...),
+			},
+			desired:       "4.4.6",
+			desiredPhase:  upgradev1alpha1.UpgradePhaseFailed,
+			inFlightPhase: upgradev1alpha1.UpgradePhaseUpgrading,
+			validation:    ReasonRetargetRejected,
+		}),
+	)
+
+	Context("When no upgrade is in progress", func() {
+		It("does not retarget", func() {
+			upgradeConfig.Status.History = upgradev1alpha1.UpgradeHistories{upgrade("4.4.6", upgradev1alpha1.UpgradePhaseUpgraded, commenced...)}
+			history, err := RetargetUpgrade(mockKubeClient, upgradeConfig, logger)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(history).To(BeNil())
+		})
+	})
+})
@@ -0,0 +1,104 @@
+package operatorconfig
+
+import (
+	"context"
+
+	"github.com/openshift/managed-upgrade-operator/pkg/healthcheck"
+	"github.com/openshift/managed-upgrade-operator/pkg/operatorconfig"
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+)
+
+var log = logf.Log.WithName("controller_operatorconfig")
+
+// Add creates a new OperatorConfig Controller and adds it to the Manager. The Manager will set fields on the Controller
+// and Start it when the Manager is Started.
+func Add(mgr manager.Manager) error {
+	namespace, err := operatorconfig.OperatorNamespace()
+	if err != nil {
+		return err
+	}
+	return add(mgr, newReconciler(mgr, namespace), namespace)
+}
+
+// newReconciler returns a new reconcile.Reconciler
+func newReconciler(mgr manager.Manager, namespace string) reconcile.Reconciler {
+	return &ReconcileOperatorConfig{
+		client:    mgr.GetClient(),
+		namespace: namespace,
+	}
+}
+
+// add adds a new Controller to mgr with r as the reconcile.Reconciler
+func add(mgr manager.Manager, r reconcile.Reconciler, namespace string) error {
+	c, err := controller.New("operatorconfig-controller", mgr, controller.Options{Reconciler: r})
+	if err != nil {
+		return err
+	}
+
+	// Only the operator ConfigMap is of interest
+	isConfigMap := func(meta interface {
+		GetNamespace() string
+		GetName() string
+	}) bool {
+		return meta.GetNamespace() == namespace && meta.GetName() == operatorconfig.ConfigMapName
+	}
+	return c.Watch(&source.Kind{Type: &corev1.ConfigMap{}}, &handler.EnqueueRequestForObject{}, predicate.Funcs{
+		CreateFunc:  func(e event.CreateEvent) bool { return isConfigMap(e.Meta) },
+		UpdateFunc:  func(e event.UpdateEvent) bool { return isConfigMap(e.MetaNew) },
+		DeleteFunc:  func(e event.DeleteEvent) bool { return isConfigMap(e.Meta) },
+		GenericFunc: func(e event.GenericEvent) bool { return isConfigMap(e.Meta) },
+	})
+}
+
+// blank assignment to verify that ReconcileOperatorConfig implements reconcile.Reconciler
+var _ reconcile.Reconciler = &ReconcileOperatorConfig{}
+
+// ReconcileOperatorConfig loads the operator configuration from its ConfigMap whenever it changes
+type ReconcileOperatorConfig struct {
+	client    client.Client
+	namespace string
+}
+
+// Reconcile makes the configuration of the ConfigMap effective. An invalid configuration is reported and the
+// previous one stays effective, a deleted ConfigMap brings back the defaults.
+func (r *ReconcileOperatorConfig) Reconcile(request reconcile.Request) (reconcile.Result, error) {
+	reqLogger := log.WithValues("Request.Namespace", request.Namespace, "Request.Name", request.Name)
+	reqLogger.Info("Reconciling operator config")
+
+	cm := &corev1.ConfigMap{}
+	err := r.client.Get(context.TODO(), types.NamespacedName{Namespace: r.namespace, Name: operatorconfig.ConfigMapName}, cm)
+	if err != nil {
+		if kerrors.IsNotFound(err) {
+			reqLogger.Info("operator configmap not found, using the default configuration")
+			operatorconfig.Reset()
+			return reconcile.Result{}, nil
+		}
+		return reconcile.Result{}, err
+	}
+
+	cfg, err := operatorconfig.Parse(cm)
+	if err == nil {
+		// The health checks are registered outside of the configuration package
+		err = healthcheck.Validate(cfg.HealthCheckNames())
+	}
+	if err != nil {
+		// Retrying does not help, the ConfigMap has to be fixed which triggers a new reconcile
+		reqLogger.Error(err, "invalid operator configuration, keeping the previous one")
+		operatorconfig.SetError(err)
+		return reconcile.Result{}, nil
+	}
+	reqLogger.Info("applying the operator configuration", "resourceVersion", cm.ResourceVersion)
+	operatorconfig.Set(cfg, cm.Namespace+"/"+cm.Name, cm.ResourceVersion)
+	return reconcile.Result{}, nil
+}
@@ -0,0 +1,92 @@
+package operatorconfig
+
+import (
+	"github.com/golang/mock/gomock"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/openshift/managed-upgrade-operator/pkg/operatorconfig"
+	"github.com/openshift/managed-upgrade-operator/util/mocks"
+	corev1 "k8s.io/api/core/v1"
+	k8serrs "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+var _ = Describe("OperatorConfigController", func() {
+	var (
+		mockCtrl       *gomock.Controller
+		mockKubeClient *mocks.MockClient
+		reconciler     *ReconcileOperatorConfig
+		configMapName  types.NamespacedName
+		cm             corev1.ConfigMap
+	)
+
+	BeforeEach(func() {
+		mockCtrl = gomock.NewController(GinkgoT())
+		mockKubeClient = mocks.NewMockClient(mockCtrl)
+		configMapName = types.NamespacedName{Namespace: "test-namespace", Name: operatorconfig.ConfigMapName}
+		reconciler = &ReconcileOperatorConfig{mockKubeClient, configMapName.Namespace}
+		cm = corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Namespace: configMapName.Namespace, Name: configMapName.Name, ResourceVersion: "2"},
+			Data:       map[string]string{operatorconfig.ConfigKey: "scale:\n  timeoutMinutes: 45\n"},
+		}
+	})
+
+	AfterEach(func() {
+		mockCtrl.Finish()
+		operatorconfig.Reset()
+	})
+
+	Context("When the ConfigMap is valid", func() {
+		It("applies the configuration", func() {
+			mockKubeClient.EXPECT().Get(gomock.Any(), configMapName, gomock.Any()).SetArg(2, cm)
+			result, err := reconciler.Reconcile(reconcile.Request{NamespacedName: configMapName})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.Requeue).To(BeFalse())
+			Expect(operatorconfig.Get().Scale.TimeoutMinutes).To(Equal(45))
+			Expect(operatorconfig.GetStatus().ResourceVersion).To(Equal("2"))
+		})
+	})
+
+	Context("When the ConfigMap is invalid", func() {
+		It("keeps the previous configuration and reports the error", func() {
+			mockKubeClient.EXPECT().Get(gomock.Any(), configMapName, gomock.Any()).SetArg(2, cm)
+			_, err := reconciler.Reconcile(reconcile.Request{NamespacedName: configMapName})
+			Expect(err).NotTo(HaveOccurred())
+
+			cm.ResourceVersion = "3"
+			cm.Data[operatorconfig.ConfigKey] = "scale:\n  timeoutMinutes: -1\n"
+			mockKubeClient.EXPECT().Get(gomock.Any(), configMapName, gomock.Any()).SetArg(2, cm)
+			_, err = reconciler.Reconcile(reconcile.Request{NamespacedName: configMapName})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(operatorconfig.Get().Scale.TimeoutMinutes).To(Equal(45))
+			Expect(operatorconfig.GetStatus().ResourceVersion).To(Equal("2"))
+			Expect(operatorconfig.GetStatus().Error).To(ContainSubstring("scale.timeoutMinutes"))
+		})
+
+		It("rejects the unknown health checks", func() {
+			cm.Data[operatorconfig.ConfigKey] = "healthCheck:\n  preUpgrade: [NodesReady, NodeReady]\n"
+			mockKubeClient.EXPECT().Get(gomock.Any(), configMapName, gomock.Any()).SetArg(2, cm)
+			_, err := reconciler.Reconcile(reconcile.Request{NamespacedName: configMapName})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(operatorconfig.Get()).To(Equal(operatorconfig.DefaultConfig()))
+			Expect(operatorconfig.GetStatus().Error).To(ContainSubstring("unknown health checks NodeReady"))
+		})
+	})
+
+	Context("When the ConfigMap is deleted", func() {
+		It("goes back to the defaults", func() {
+			mockKubeClient.EXPECT().Get(gomock.Any(), configMapName, gomock.Any()).SetArg(2, cm)
+			_, err := reconciler.Reconcile(reconcile.Request{NamespacedName: configMapName})
+			Expect(err).NotTo(HaveOccurred())
+
+			mockKubeClient.EXPECT().Get(gomock.Any(), configMapName, gomock.Any()).Return(k8serrs.NewNotFound(schema.GroupResource{Resource: "configmaps"}, configMapName.Name))
+			_, err = reconciler.Reconcile(reconcile.Request{NamespacedName: configMapName})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(operatorconfig.Get()).To(Equal(operatorconfig.DefaultConfig()))
+			Expect(operatorconfig.GetStatus().Source).To(BeEmpty())
+		})
+	})
+})
@@ -0,0 +1,75 @@
+package upgradeconfig
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/go-logr/logr"
+	upgradev1alpha1 "github.com/openshift/managed-upgrade-operator/pkg/apis/upgrade/v1alpha1"
+	"github.com/openshift/managed-upgrade-operator/pkg/upgradestatus"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// arbitrate makes sure only one UpgradeConfig drives the upgrades of the cluster. The UpgradeConfig whose upgrade
+// is in progress is active, or else the oldest UpgradeConfig, by name if created at the same time.
+// The others get a Conflict condition naming the active one.
+// Dry run UpgradeConfigs never change the cluster so they are left out. It returns true if the instance is active.
+func (r *ReconcileUpgradeConfig) arbitrate(reqLogger logr.Logger, instance *upgradev1alpha1.UpgradeConfig) (bool, error) {
+	ucList := &upgradev1alpha1.UpgradeConfigList{}
+	err := r.client.List(context.TODO(), ucList)
+	if err != nil {
+		return false, err
+	}
+
+	active := activeUpgradeConfig(ucList.Items)
+	current := instance.Status.Conditions.GetCondition(upgradev1alpha1.Conflict)
+	if active == nil || active.Name == instance.Name {
+		if current == nil || !current.IsTrue() {
+			return true, nil
+		}
+		condition := *current
+		condition.Status = corev1.ConditionFalse
+		condition.Reason = "Active"
+		condition.Message = "UpgradeConfig is the active one"
+		return true, upgradestatus.Patch(r.client, instance, upgradestatus.SetCondition(condition))
+	}
+
+	reqLogger.Info(fmt.Sprintf("upgradeconfig %s is the active one, not upgrading", active.Name))
+	msg := fmt.Sprintf("UpgradeConfig %s is the active one, only one UpgradeConfig upgrades the cluster", active.Name)
+	if current != nil && current.IsTrue() && current.Message == msg {
+		return false, nil
+	}
+	condition := upgradev1alpha1.UpgradeCondition{
+		Type:    upgradev1alpha1.Conflict,
+		Status:  corev1.ConditionTrue,
+		Reason:  "NotActive",
+		Message: msg,
+	}
+	return false, upgradestatus.Patch(r.client, instance, upgradestatus.SetCondition(condition))
+}
+
+// activeUpgradeConfig returns the UpgradeConfig whose upgrade is in progress, so it is not abandoned midway,
+// or else the oldest UpgradeConfig. Dry runs are left out.
+func activeUpgradeConfig(ucs []upgradev1alpha1.UpgradeConfig) *upgradev1alpha1.UpgradeConfig {
+	var active *upgradev1alpha1.UpgradeConfig
+	for i := range ucs {
+		uc := &ucs[i]
+		if uc.IsDryRun() || uc.DeletionTimestamp != nil {
+			continue
+		}
+		if active == nil || precedes(uc, active) {
+			active = uc
+		}
+	}
+	return active
+}
+
+// precedes returns true if the UpgradeConfig takes precedence over the other one: an upgrade in progress first,
+// then the oldest, by name if created at the same time
+func precedes(uc, other *upgradev1alpha1.UpgradeConfig) bool {
+	if uc.IsUpgrading() != other.IsUpgrading() {
+		return uc.IsUpgrading()
+	}
+	return uc.CreationTimestamp.Before(&other.CreationTimestamp) ||
+		(uc.CreationTimestamp.Equal(&other.CreationTimestamp) && uc.Name < other.Name)
+}
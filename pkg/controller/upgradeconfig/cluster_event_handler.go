@@ -0,0 +1,134 @@
+package upgradeconfig
+
+import (
+	"context"
+	"time"
+
+	upgradev1alpha1 "github.com/openshift/managed-upgrade-operator/pkg/apis/upgrade/v1alpha1"
+	"github.com/openshift/managed-upgrade-operator/pkg/cluster_upgrader"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/workqueue"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+const (
+	// Events on cluster resources arriving within this delay are coalesced into a single reconcile
+	clusterEventDebounce = 10 * time.Second
+
+	// Annotation set by the machine-config-daemon on the node it is updating
+	machineConfigStateAnnotation = "machineconfiguration.openshift.io/state"
+)
+
+// enqueueActiveUpgradeConfigs enqueues the UpgradeConfigs with an upgrade in progress when a cluster resource changes.
+// The dry runs are never enqueued.
+// Requests are delayed so that a burst of events only triggers one reconcile.
+type enqueueActiveUpgradeConfigs struct {
+	client client.Client
+	delay  time.Duration
+	// Enqueue the UpgradeConfigs without an upgrade in progress too, dry runs excepted
+	all bool
+}
+
+var _ handler.EventHandler = &enqueueActiveUpgradeConfigs{}
+
+func (e *enqueueActiveUpgradeConfigs) Create(evt event.CreateEvent, q workqueue.RateLimitingInterface) {
+	e.enqueue(q)
+}
+
+func (e *enqueueActiveUpgradeConfigs) Update(evt event.UpdateEvent, q workqueue.RateLimitingInterface) {
+	e.enqueue(q)
+}
+
+func (e *enqueueActiveUpgradeConfigs) Delete(evt event.DeleteEvent, q workqueue.RateLimitingInterface) {
+	e.enqueue(q)
+}
+
+func (e *enqueueActiveUpgradeConfigs) Generic(evt event.GenericEvent, q workqueue.RateLimitingInterface) {
+	e.enqueue(q)
+}
+
+func (e *enqueueActiveUpgradeConfigs) enqueue(q workqueue.RateLimitingInterface) {
+	ucList := &upgradev1alpha1.UpgradeConfigList{}
+	err := e.client.List(context.TODO(), ucList)
+	if err != nil {
+		log.Error(err, "failed to list upgradeconfigs for cluster event")
+		return
+	}
+	for _, uc := range ucList.Items {
+		// A dry run is only run again when its spec or its trigger annotations change
+		if uc.IsDryRun() || (!e.all && !isUpgradeActive(&uc)) {
+			continue
+		}
+		// The delaying queue keeps the earliest time of an item already waiting, coalescing the events
+		q.AddAfter(reconcile.Request{NamespacedName: types.NamespacedName{Name: uc.Name, Namespace: uc.Namespace}}, e.delay)
+	}
+}
+
+// isUpgradeActive returns true if the UpgradeConfig's desired version, or an upgrade started outside the operator,
+// is not upgraded or failed yet
+func isUpgradeActive(uc *upgradev1alpha1.UpgradeConfig) bool {
+	if uc.IsDryRun() {
+		return false
+	}
+	for _, h := range uc.Status.History {
+		if h.Source == upgradev1alpha1.UpgradeSourceExternal && h.Phase == upgradev1alpha1.UpgradePhaseUpgrading {
+			return true
+		}
+	}
+	history := uc.Status.History.GetHistory(uc.Spec.Desired.Version)
+	if history == nil {
+		return true
+	}
+	return history.Phase != upgradev1alpha1.UpgradePhaseUpgraded && history.Phase != upgradev1alpha1.UpgradePhaseFailed
+}
+
+// upgradeMachineSetPredicate only lets through the machinesets created for the upgrade
+var upgradeMachineSetPredicate = predicate.Funcs{
+	CreateFunc: func(e event.CreateEvent) bool {
+		return e.Meta != nil && e.Meta.GetLabels()[cluster_upgrader.LABEL_UPGRADE] == "true"
+	},
+	UpdateFunc: func(e event.UpdateEvent) bool {
+		return e.MetaNew != nil && e.MetaNew.GetLabels()[cluster_upgrader.LABEL_UPGRADE] == "true"
+	},
+	DeleteFunc: func(e event.DeleteEvent) bool {
+		return e.Meta != nil && e.Meta.GetLabels()[cluster_upgrader.LABEL_UPGRADE] == "true"
+	},
+	GenericFunc: func(e event.GenericEvent) bool {
+		return false
+	},
+}
+
+// NodeChangedPredicate ignores the node heartbeats, only letting through changes of
+// the node readiness, schedulability or machine config state
+type NodeChangedPredicate struct {
+	predicate.Funcs
+}
+
+// Update implements UpdateEvent filter for node readiness and machine config changes
+func (NodeChangedPredicate) Update(e event.UpdateEvent) bool {
+	oldNode, ok := e.ObjectOld.(*corev1.Node)
+	if !ok {
+		return false
+	}
+	newNode, ok := e.ObjectNew.(*corev1.Node)
+	if !ok {
+		return false
+	}
+	return oldNode.Spec.Unschedulable != newNode.Spec.Unschedulable ||
+		oldNode.Annotations[machineConfigStateAnnotation] != newNode.Annotations[machineConfigStateAnnotation] ||
+		nodeReadyStatus(oldNode) != nodeReadyStatus(newNode)
+}
+
+func nodeReadyStatus(node *corev1.Node) corev1.ConditionStatus {
+	for _, c := range node.Status.Conditions {
+		if c.Type == corev1.NodeReady {
+			return c.Status
+		}
+	}
+	return corev1.ConditionUnknown
+}
@@ -0,0 +1,133 @@
+package upgradeconfig
+
+import (
+	"github.com/golang/mock/gomock"
+	upgradev1alpha1 "github.com/openshift/managed-upgrade-operator/pkg/apis/upgrade/v1alpha1"
+	"github.com/openshift/managed-upgrade-operator/util/mocks"
+	testStructs "github.com/openshift/managed-upgrade-operator/util/mocks/structs"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/workqueue"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("ClusterEventHandler", func() {
+
+	Context("enqueueActiveUpgradeConfigs", func() {
+		var (
+			mockCtrl       *gomock.Controller
+			mockKubeClient *mocks.MockClient
+			queue          workqueue.RateLimitingInterface
+			handler        *enqueueActiveUpgradeConfigs
+		)
+
+		BeforeEach(func() {
+			mockCtrl = gomock.NewController(GinkgoT())
+			mockKubeClient = mocks.NewMockClient(mockCtrl)
+			queue = workqueue.NewRateLimitingQueue(workqueue.DefaultControllerRateLimiter())
+			handler = &enqueueActiveUpgradeConfigs{client: mockKubeClient}
+		})
+
+		AfterEach(func() {
+			queue.ShutDown()
+			mockCtrl.Finish()
+		})
+
+		It("only enqueues the UpgradeConfigs with an upgrade in progress", func() {
+			upgrading := testStructs.NewUpgradeConfigBuilder().WithNamespacedName(types.NamespacedName{Name: "upgrading"}).WithPhase(upgradev1alpha1.UpgradePhaseUpgrading).GetUpgradeConfig()
+			upgraded := testStructs.NewUpgradeConfigBuilder().WithNamespacedName(types.NamespacedName{Name: "upgraded"}).WithPhase(upgradev1alpha1.UpgradePhaseUpgraded).GetUpgradeConfig()
+			mockKubeClient.EXPECT().List(gomock.Any(), gomock.Any()).SetArg(1, upgradev1alpha1.UpgradeConfigList{
+				Items: []upgradev1alpha1.UpgradeConfig{*upgrading, *upgraded},
+			})
+			handler.Update(event.UpdateEvent{}, queue)
+			Expect(queue.Len()).To(Equal(1))
+			item, _ := queue.Get()
+			Expect(item).To(Equal(reconcile.Request{NamespacedName: types.NamespacedName{Name: "upgrading"}}))
+		})
+
+		It("enqueues the UpgradeConfigs tracking an external upgrade", func() {
+			upgraded := testStructs.NewUpgradeConfigBuilder().WithNamespacedName(types.NamespacedName{Name: "upgraded"}).WithPhase(upgradev1alpha1.UpgradePhaseUpgraded).GetUpgradeConfig()
+			upgraded.Status.History = append(upgraded.Status.History, upgradev1alpha1.UpgradeHistory{
+				Version: "an external version",
+				Phase:   upgradev1alpha1.UpgradePhaseUpgrading,
+				Source:  upgradev1alpha1.UpgradeSourceExternal,
+			})
+			mockKubeClient.EXPECT().List(gomock.Any(), gomock.Any()).SetArg(1, upgradev1alpha1.UpgradeConfigList{
+				Items: []upgradev1alpha1.UpgradeConfig{*upgraded},
+			})
+			handler.Update(event.UpdateEvent{}, queue)
+			Expect(queue.Len()).To(Equal(1))
+		})
+
+		It("enqueues every UpgradeConfig when asked to", func() {
+			handler.all = true
+			upgraded := testStructs.NewUpgradeConfigBuilder().WithNamespacedName(types.NamespacedName{Name: "upgraded"}).WithPhase(upgradev1alpha1.UpgradePhaseUpgraded).GetUpgradeConfig()
+			mockKubeClient.EXPECT().List(gomock.Any(), gomock.Any()).SetArg(1, upgradev1alpha1.UpgradeConfigList{
+				Items: []upgradev1alpha1.UpgradeConfig{*upgraded},
+			})
+			handler.Update(event.UpdateEvent{}, queue)
+			Expect(queue.Len()).To(Equal(1))
+		})
+
+		It("never enqueues the dry runs", func() {
+			handler.all = true
+			dryRun := testStructs.NewUpgradeConfigBuilder().WithNamespacedName(types.NamespacedName{Name: "dry-run"}).WithPhase(upgradev1alpha1.UpgradePhaseUpgraded).GetUpgradeConfig()
+			dryRun.Spec.DryRun = true
+			mockKubeClient.EXPECT().List(gomock.Any(), gomock.Any()).SetArg(1, upgradev1alpha1.UpgradeConfigList{
+				Items: []upgradev1alpha1.UpgradeConfig{*dryRun},
+			})
+			handler.Update(event.UpdateEvent{}, queue)
+			Expect(queue.Len()).To(BeZero())
+		})
+	})
+
+	Context("NodeChangedPredicate", func() {
+		var (
+			ncp     NodeChangedPredicate
+			oldNode *corev1.Node
+			newNode *corev1.Node
+		)
+
+		BeforeEach(func() {
+			oldNode = &corev1.Node{
+				Status: corev1.NodeStatus{
+					Conditions: []corev1.NodeCondition{{Type: corev1.NodeReady, Status: corev1.ConditionTrue}},
+				},
+			}
+			newNode = oldNode.DeepCopy()
+		})
+
+		update := func() bool {
+			return ncp.Update(event.UpdateEvent{MetaOld: oldNode.GetObjectMeta(), ObjectOld: oldNode, MetaNew: newNode.GetObjectMeta(), ObjectNew: newNode})
+		}
+
+		Context("When only the heartbeat changed", func() {
+			It("will not return true", func() {
+				newNode.ResourceVersion = "2"
+				Expect(update()).To(BeFalse())
+			})
+		})
+		Context("When the node readiness changed", func() {
+			It("will return true", func() {
+				newNode.Status.Conditions[0].Status = corev1.ConditionFalse
+				Expect(update()).To(BeTrue())
+			})
+		})
+		Context("When the machine config state changed", func() {
+			It("will return true", func() {
+				newNode.Annotations = map[string]string{machineConfigStateAnnotation: "Working"}
+				Expect(update()).To(BeTrue())
+			})
+		})
+		Context("When the node is cordoned", func() {
+			It("will return true", func() {
+				newNode.Spec.Unschedulable = true
+				Expect(update()).To(BeTrue())
+			})
+		})
+	})
+})
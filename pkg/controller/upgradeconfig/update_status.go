@@ -1,14 +1,94 @@
 package upgradeconfig
 
 import (
+	"context"
+
 	"github.com/go-logr/logr"
 	upgradev1alpha1 "github.com/openshift/managed-upgrade-operator/pkg/apis/upgrade/v1alpha1"
+	"github.com/openshift/managed-upgrade-operator/pkg/upgradestatus"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
-//TODO
-func (r *ReconcileUpgradeConfig) updateStatusPending(eqLogger logr.Logger, u *upgradev1alpha1.UpgradeConfig) error {
+// updateStatusPending marks the new upgrade of the desired version as pending until its scheduled time
+func (r *ReconcileUpgradeConfig) updateStatusPending(reqLogger logr.Logger, u *upgradev1alpha1.UpgradeConfig) error {
+	history := u.Status.History.GetHistory(u.Spec.Desired.Version)
+	if history == nil || history.Phase == upgradev1alpha1.UpgradePhasePending {
+		return nil
+	}
+	reqLogger.Info("the upgrade is pending until its scheduled time")
+	history.Phase = upgradev1alpha1.UpgradePhasePending
+	return upgradestatus.PatchHistory(r.client, u, *history)
+}
 
-	return nil
+// updateScheduleCondition sets the InvalidSchedule condition with the error parsing the upgrade time,
+// and clears it once the upgrade time is valid
+func (r *ReconcileUpgradeConfig) updateScheduleCondition(u *upgradev1alpha1.UpgradeConfig, scheduleErr error) error {
+	current := u.Status.Conditions.GetCondition(upgradev1alpha1.InvalidSchedule)
+	condition := upgradev1alpha1.UpgradeCondition{
+		Type:    upgradev1alpha1.InvalidSchedule,
+		Status:  corev1.ConditionFalse,
+		Reason:  "Valid",
+		Message: "the upgrade time is valid",
+	}
+	if scheduleErr != nil {
+		condition.Status = corev1.ConditionTrue
+		condition.Reason = "InvalidUpgradeAt"
+		condition.Message = scheduleErr.Error()
+	}
+	if current == nil && scheduleErr == nil {
+		return nil
+	}
+	if current != nil && current.Status == condition.Status && current.Message == condition.Message {
+		return nil
+	}
+	return upgradestatus.Patch(r.client, u, upgradestatus.SetCondition(condition))
+}
 
+// updateDryRunCondition sets the DryRunIgnored condition while the dry run asked for is ignored because the upgrade
+// is in progress, and clears it once it is not
+func (r *ReconcileUpgradeConfig) updateDryRunCondition(u *upgradev1alpha1.UpgradeConfig) error {
+	ignored := u.Spec.DryRun && !u.IsDryRun()
+	if u.Status.Conditions.IsTrueFor(upgradev1alpha1.DryRunIgnored) == ignored {
+		return nil
+	}
+	condition := upgradev1alpha1.UpgradeCondition{
+		Type:    upgradev1alpha1.DryRunIgnored,
+		Status:  corev1.ConditionFalse,
+		Reason:  "NotUpgrading",
+		Message: "no upgrade is in progress",
+	}
+	if ignored {
+		condition.Status = corev1.ConditionTrue
+		condition.Reason = "Upgrading"
+		condition.Message = "the dry run is ignored while the upgrade is in progress, the upgrade carries on"
+	}
+	return upgradestatus.Patch(r.client, u, upgradestatus.SetCondition(condition))
 }
 
+// retryUpgrade resets the failed history of the desired version so the steps which did not succeed are performed again,
+// then removes the retry annotation
+func (r *ReconcileUpgradeConfig) retryUpgrade(reqLogger logr.Logger, u *upgradev1alpha1.UpgradeConfig) error {
+	history := u.Status.History.GetHistory(u.Spec.Desired.Version)
+	if history == nil {
+		return nil
+	}
+
+	conditions := upgradev1alpha1.Conditions{}
+	for _, c := range history.Conditions {
+		if c.IsTrue() {
+			conditions = append(conditions, c)
+		}
+	}
+	history.Conditions = conditions
+	history.Phase = upgradev1alpha1.UpgradePhaseNew
+	history.CompleteTime = nil
+	err := upgradestatus.PatchHistory(r.client, u, *history)
+	if err != nil {
+		return err
+	}
+
+	patch := client.MergeFrom(u.DeepCopy())
+	delete(u.Annotations, upgradev1alpha1.RetryAnnotation)
+	return r.client.Patch(context.TODO(), u, patch)
+}
@@ -4,15 +4,25 @@ import (
 	"context"
 	"time"
 
+	"github.com/go-logr/logr"
+	configv1 "github.com/openshift/api/config/v1"
+	machineapi "github.com/openshift/machine-api-operator/pkg/apis/machine/v1beta1"
+	machineconfigapi "github.com/openshift/machine-config-operator/pkg/apis/machineconfiguration.openshift.io/v1"
 	upgradev1alpha1 "github.com/openshift/managed-upgrade-operator/pkg/apis/upgrade/v1alpha1"
 	"github.com/openshift/managed-upgrade-operator/pkg/cluster_upgrader"
+	"github.com/openshift/managed-upgrade-operator/pkg/metrics"
+	"github.com/openshift/managed-upgrade-operator/pkg/monitor"
+	"github.com/openshift/managed-upgrade-operator/pkg/upgradestatus"
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/event"
 	"sigs.k8s.io/controller-runtime/pkg/handler"
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
 	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 	"sigs.k8s.io/controller-runtime/pkg/source"
 )
@@ -31,6 +41,7 @@ func newReconciler(mgr manager.Manager) reconcile.Reconciler {
 		client:                 mgr.GetClient(),
 		scheme:                 mgr.GetScheme(),
 		clusterUpgraderBuilder: cluster_upgrader.NewBuilder(),
+		metricsClient:          &metrics.Counter{},
 	}
 }
 
@@ -42,8 +53,38 @@ func add(mgr manager.Manager, r reconcile.Reconciler) error {
 		return err
 	}
 
-	// Watch for changes to primary resource UpgradeConfig, status change will not trigger a reconcile
-	err = c.Watch(&source.Kind{Type: &upgradev1alpha1.UpgradeConfig{}}, &handler.EnqueueRequestForObject{}, StatusChangedPredicate{})
+	// Watch for changes to primary resource UpgradeConfig, only spec and trigger annotation changes will trigger a reconcile
+	err = c.Watch(&source.Kind{Type: &upgradev1alpha1.UpgradeConfig{}}, &handler.EnqueueRequestForObject{}, UpgradeConfigChangedPredicate{})
+	if err != nil {
+		return err
+	}
+
+	// Another UpgradeConfig may become active when an UpgradeConfig is created or deleted
+	err = c.Watch(&source.Kind{Type: &upgradev1alpha1.UpgradeConfig{}}, &enqueueActiveUpgradeConfigs{client: mgr.GetClient(), delay: clusterEventDebounce, all: true}, predicate.Funcs{
+		UpdateFunc:  func(e event.UpdateEvent) bool { return false },
+		GenericFunc: func(e event.GenericEvent) bool { return false },
+	})
+	if err != nil {
+		return err
+	}
+
+	// Watch the cluster resources reporting the upgrade progress, so the active UpgradeConfig is reconciled
+	// as soon as they change instead of waiting for the next sync
+	clusterEvents := &enqueueActiveUpgradeConfigs{client: mgr.GetClient(), delay: clusterEventDebounce}
+	// ClusterVersion changes reach every UpgradeConfig, so upgrades started outside the operator are noticed
+	err = c.Watch(&source.Kind{Type: &configv1.ClusterVersion{}}, &enqueueActiveUpgradeConfigs{client: mgr.GetClient(), delay: clusterEventDebounce, all: true})
+	if err != nil {
+		return err
+	}
+	err = c.Watch(&source.Kind{Type: &machineconfigapi.MachineConfigPool{}}, clusterEvents)
+	if err != nil {
+		return err
+	}
+	err = c.Watch(&source.Kind{Type: &machineapi.MachineSet{}}, clusterEvents, upgradeMachineSetPredicate)
+	if err != nil {
+		return err
+	}
+	err = c.Watch(&source.Kind{Type: &corev1.Node{}}, clusterEvents, NodeChangedPredicate{})
 	if err != nil {
 		return err
 	}
@@ -60,6 +101,7 @@ type ReconcileUpgradeConfig struct {
 	client                 client.Client
 	scheme                 *runtime.Scheme
 	clusterUpgraderBuilder cluster_upgrader.ClusterUpgraderBuilder
+	metricsClient          metrics.Metrics
 }
 
 // Reconcile reads that state of the cluster for a UpgradeConfig object and makes changes based on the state read
@@ -85,32 +127,110 @@ func (r *ReconcileUpgradeConfig) Reconcile(request reconcile.Request) (reconcile
 		return reconcile.Result{}, err
 	}
 
-	// If cluster is already upgrading with different version, we should wait until it completed
-	upgrading, err := cluster_upgrader.IsClusterUpgrading(r.client, instance.Spec.Desired.Version)
+	// A dry run only reports what the upgrade would do, it never upgrades the cluster.
+	// It is ignored while the upgrade is in progress.
+	err = r.updateDryRunCondition(instance)
+	if err != nil {
+		return reconcile.Result{}, err
+	}
+	if instance.IsDryRun() {
+		upgrader, err := r.clusterUpgraderBuilder.NewClient(r.client)
+		if err != nil {
+			return reconcile.Result{}, err
+		}
+		reqLogger.Info("dry running the upgrade")
+		err = upgrader.DryRun(instance, reqLogger)
+		if err != nil {
+			reqLogger.Error(err, "Failed to dry run the upgrade")
+		}
+		return reconcile.Result{}, nil
+	}
+
+	// Only one UpgradeConfig may upgrade the cluster
+	active, err := r.arbitrate(reqLogger, instance)
+	if err != nil {
+		return reconcile.Result{}, err
+	}
+	if !active {
+		return reconcile.Result{}, nil
+	}
+
+	if instance.Annotations[upgradev1alpha1.PauseAnnotation] == "true" {
+		reqLogger.Info("upgrade is paused")
+		return reconcile.Result{}, nil
+	}
+
+	// The worker upgrade paused by the monitor continues once explicitly resumed
+	if _, ok := instance.Annotations[upgradev1alpha1.ResumeAnnotation]; ok {
+		err = monitor.Resume(r.client, r.metricsClient, instance, reqLogger)
+		if err != nil {
+			return reconcile.Result{}, err
+		}
+	}
+
+	// The forbidden upgrade started outside the operator is reported until explicitly acknowledged
+	if _, ok := instance.Annotations[upgradev1alpha1.ExternalUpgradeAcknowledgedAnnotation]; ok {
+		err = cluster_upgrader.AcknowledgeExternalUpgrade(r.client, r.metricsClient, instance, reqLogger)
+		if err != nil {
+			return reconcile.Result{}, err
+		}
+	}
+
+	// On first install, backfill the history with the upgrades the cluster went through before
+	err = cluster_upgrader.ImportHistory(r.client, instance, reqLogger)
+	if err != nil {
+		return reconcile.Result{}, err
+	}
+
+	// If cluster is already upgrading to a version started outside the operator, it is recorded in the history
+	// and we should wait until it completed
+	upgrading, err := cluster_upgrader.TrackExternalUpgrade(r.client, r.metricsClient, instance, reqLogger)
 	if err != nil {
 		return reconcile.Result{}, err
 	}
 	if upgrading {
-		reqLogger.Info("cluster is upgrading with different version, cannot upgrade now")
+		reqLogger.Info("cluster is upgrading to a version started outside the operator, cannot upgrade now")
 		return reconcile.Result{}, nil
 	}
 
 	var history upgradev1alpha1.UpgradeHistory
 	found := false
 	for _, h := range instance.Status.History {
-		if h.Version == instance.Spec.Desired.Version {
+		if h.Version == instance.Spec.Desired.Version && !h.DryRun {
 			history = h
 			found = true
 		}
 	}
-	if !found {
-		history = upgradev1alpha1.UpgradeHistory{Version: instance.Spec.Desired.Version, Phase: upgradev1alpha1.UpgradePhaseNew}
-		history.Conditions = upgradev1alpha1.NewConditions()
-		instance.Status.History = append([]upgradev1alpha1.UpgradeHistory{history}, instance.Status.History...)
-		err := r.client.Status().Update(context.TODO(), instance)
+	if !found || history.Phase == upgradev1alpha1.UpgradePhaseSuperseded {
+		// The desired version changed while upgrading, the upgrade in progress is retargeted to it.
+		// A version superseded earlier is started over the same way.
+		retargeted, err := cluster_upgrader.RetargetUpgrade(r.client, instance, reqLogger)
+		if err != nil {
+			return reconcile.Result{}, err
+		}
+		if retargeted != nil {
+			history = *retargeted
+		} else {
+			history = upgradev1alpha1.UpgradeHistory{Version: instance.Spec.Desired.Version, Phase: upgradev1alpha1.UpgradePhaseNew}
+			history.Conditions = upgradev1alpha1.NewConditions()
+			err = upgradestatus.PatchHistory(r.client, instance, history)
+			if err != nil {
+				return reconcile.Result{}, err
+			}
+		}
+	}
+
+	// The upgrade in progress carries on to its own version when it could not be retargeted to the desired one
+	if inFlight := cluster_upgrader.RejectedRetarget(instance); inFlight != nil {
+		upgrader, err := r.clusterUpgraderBuilder.NewClient(r.client)
 		if err != nil {
 			return reconcile.Result{}, err
 		}
+		reqLogger.Info("the upgrade in progress could not be retargeted, carrying on with it", "version", inFlight.Version)
+		carryOn := instance.DeepCopy()
+		carryOn.Spec.Desired.Version = inFlight.Version
+		err = upgrader.UpgradeCluster(carryOn, reqLogger)
+		return upgradeResult(err, reqLogger), nil
 	}
 
 	status := history.Phase
@@ -118,9 +238,16 @@ func (r *ReconcileUpgradeConfig) Reconcile(request reconcile.Request) (reconcile
 
 	switch status {
 	case "", upgradev1alpha1.UpgradePhaseNew, upgradev1alpha1.UpgradePhasePending:
-		// TODO verify if it's time to do upgrade, if no, set to "pending", if it's yes, perform upgrade, and set status to "upgrading"
 		reqLogger.Info("checking whether it's ready to do upgrade")
-		ready := cluster_upgrader.IsReadyToUpgrade(instance)
+		ready, wait, scheduleErr := cluster_upgrader.IsReadyToUpgrade(instance)
+		err = r.updateScheduleCondition(instance, scheduleErr)
+		if err != nil {
+			return reconcile.Result{}, err
+		}
+		if scheduleErr != nil {
+			reqLogger.Error(scheduleErr, "cannot schedule the upgrade")
+			return reconcile.Result{}, nil
+		}
 		if ready {
 			upgrader, err := r.clusterUpgraderBuilder.NewClient(r.client)
 			if err != nil {
@@ -128,17 +255,16 @@ func (r *ReconcileUpgradeConfig) Reconcile(request reconcile.Request) (reconcile
 			}
 			reqLogger.Info("it's ready to start upgrade now", "time", time.Now())
 			err = upgrader.UpgradeCluster(instance, reqLogger)
-			if err != nil {
-				reqLogger.Error(err, "Failed to upgrade cluster")
-			}
+			return upgradeResult(err, reqLogger), nil
 
 		} else {
+			reqLogger.Info("the upgrade is scheduled later", "upgradeAt", instance.Spec.UpgradeAt)
 			err := r.updateStatusPending(reqLogger, instance)
 			if err != nil {
-				// TODO updateStatusPending implements nothing so far so below logged message to be changed when implemented
-				reqLogger.Info("Failed to set pending status for upgrade!")
+				reqLogger.Error(err, "Failed to set pending status for upgrade")
+				return reconcile.Result{}, err
 			}
-			return reconcile.Result{}, nil
+			return reconcile.Result{RequeueAfter: wait}, nil
 		}
 	case upgradev1alpha1.UpgradePhaseUpgrading:
 		upgrader, err := r.clusterUpgraderBuilder.NewClient(r.client)
@@ -147,13 +273,19 @@ func (r *ReconcileUpgradeConfig) Reconcile(request reconcile.Request) (reconcile
 		}
 		reqLogger.Info("it's upgrading now")
 		err = upgrader.UpgradeCluster(instance, reqLogger)
-		if err != nil {
-			reqLogger.Error(err, "Failed to upgrade cluster")
-		}
+		return upgradeResult(err, reqLogger), nil
 	case upgradev1alpha1.UpgradePhaseUpgraded:
 		reqLogger.Info("cluster is already upgraded")
 		return reconcile.Result{}, nil
 	case upgradev1alpha1.UpgradePhaseFailed:
+		if _, ok := instance.Annotations[upgradev1alpha1.RetryAnnotation]; ok {
+			reqLogger.Info("retrying the failed upgrade")
+			err := r.retryUpgrade(reqLogger, instance)
+			if err != nil {
+				return reconcile.Result{}, err
+			}
+			return reconcile.Result{Requeue: true}, nil
+		}
 		reqLogger.Info("the cluster failed the upgrade")
 		return reconcile.Result{}, nil
 	default:
@@ -162,3 +294,16 @@ func (r *ReconcileUpgradeConfig) Reconcile(request reconcile.Request) (reconcile
 
 	return reconcile.Result{}, nil
 }
+
+// upgradeResult returns the result of the reconcile which performed the upgrade steps.
+// An approval gate asking to be retried later requeues the UpgradeConfig for then.
+func upgradeResult(err error, logger logr.Logger) reconcile.Result {
+	if retry, ok := err.(*cluster_upgrader.GateRetryError); ok {
+		logger.Info(retry.Error())
+		return reconcile.Result{RequeueAfter: retry.RetryAfter}
+	}
+	if err != nil {
+		logger.Error(err, "Failed to upgrade cluster")
+	}
+	return reconcile.Result{}
+}
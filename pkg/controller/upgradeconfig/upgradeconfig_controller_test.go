@@ -2,6 +2,8 @@ package upgradeconfig
 
 import (
 	"fmt"
+	"time"
+
 	"github.com/golang/mock/gomock"
 	"github.com/hashicorp/go-multierror"
 	"github.com/onsi/gomega/gstruct"
@@ -10,12 +12,16 @@ import (
 	machineapi "github.com/openshift/machine-api-operator/pkg/apis/machine/v1beta1"
 	machineconfigapi "github.com/openshift/machine-config-operator/pkg/apis/machineconfiguration.openshift.io/v1"
 	upgradev1alpha1 "github.com/openshift/managed-upgrade-operator/pkg/apis/upgrade/v1alpha1"
+	"github.com/openshift/managed-upgrade-operator/pkg/cluster_upgrader"
+	"github.com/openshift/managed-upgrade-operator/pkg/metrics"
 	"github.com/openshift/managed-upgrade-operator/util/mocks"
 
 	mockUpgrader "github.com/openshift/managed-upgrade-operator/pkg/cluster_upgrader/mocks"
 	testStructs "github.com/openshift/managed-upgrade-operator/util/mocks/structs"
 
+	corev1 "k8s.io/api/core/v1"
 	k8serrs "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/types"
@@ -57,6 +63,7 @@ var _ = Describe("UpgradeConfigController", func() {
 			mockKubeClient,
 			testScheme,
 			mockClusterUpgraderBuilder,
+			&metrics.Counter{},
 		}
 	})
 
@@ -93,8 +100,198 @@ var _ = Describe("UpgradeConfigController", func() {
 		})
 
 		Context("When an UpgradeConfig exists", func() {
+			var otherUpgradeConfigs []upgradev1alpha1.UpgradeConfig
+			BeforeEach(func() {
+				otherUpgradeConfigs = []upgradev1alpha1.UpgradeConfig{}
+			})
 			JustBeforeEach(func() {
 				mockKubeClient.EXPECT().Get(gomock.Any(), upgradeConfigName, gomock.Any()).SetArg(2, *upgradeConfig).Times(1)
+				mockKubeClient.EXPECT().List(gomock.Any(), gomock.Any()).SetArg(1, upgradev1alpha1.UpgradeConfigList{
+					Items: append([]upgradev1alpha1.UpgradeConfig{*upgradeConfig}, otherUpgradeConfigs...),
+				}).AnyTimes()
+			})
+
+			Context("When an older UpgradeConfig exists", func() {
+				BeforeEach(func() {
+					upgradeConfig.CreationTimestamp = metav1.Now()
+					older := testStructs.NewUpgradeConfigBuilder().WithNamespacedName(types.NamespacedName{Name: "older"}).GetUpgradeConfig()
+					older.CreationTimestamp = metav1.NewTime(upgradeConfig.CreationTimestamp.Add(-time.Hour))
+					otherUpgradeConfigs = append(otherUpgradeConfigs, *older)
+				})
+				It("Sets the Conflict condition naming the active one and does not upgrade", func() {
+					matcher := testStructs.NewUpgradeConfigMatcher()
+					mockKubeClient.EXPECT().Get(gomock.Any(), types.NamespacedName{Name: "version"}, gomock.Any()).Times(0)
+					mockKubeClient.EXPECT().Status().Return(mockUpdater)
+					mockUpdater.EXPECT().Patch(gomock.Any(), matcher, gomock.Any())
+					mockClusterUpgraderBuilder.EXPECT().NewClient(gomock.Any()).Times(0)
+					result, err := reconciler.Reconcile(reconcile.Request{NamespacedName: upgradeConfigName})
+					Expect(err).NotTo(HaveOccurred())
+					Expect(result.Requeue).To(BeFalse())
+					condition := matcher.ActualUpgradeConfig.Status.Conditions.GetCondition(upgradev1alpha1.Conflict)
+					Expect(condition).NotTo(BeNil())
+					Expect(condition.IsTrue()).To(BeTrue())
+					Expect(condition.Message).To(ContainSubstring("older"))
+				})
+
+				Context("When the Conflict condition is already set", func() {
+					BeforeEach(func() {
+						upgradeConfig.Status.Conditions = upgradev1alpha1.NewConditions(upgradev1alpha1.UpgradeCondition{
+							Type:    upgradev1alpha1.Conflict,
+							Status:  corev1.ConditionTrue,
+							Message: "UpgradeConfig older is the active one, only one UpgradeConfig upgrades the cluster",
+						})
+					})
+					It("Does not update the status again", func() {
+						mockKubeClient.EXPECT().Status().Times(0)
+						mockClusterUpgraderBuilder.EXPECT().NewClient(gomock.Any()).Times(0)
+						_, err := reconciler.Reconcile(reconcile.Request{NamespacedName: upgradeConfigName})
+						Expect(err).NotTo(HaveOccurred())
+					})
+				})
+
+				Context("When the older UpgradeConfig is a dry run", func() {
+					BeforeEach(func() {
+						otherUpgradeConfigs[0].Spec.DryRun = true
+						upgradeConfig.Status.History = []upgradev1alpha1.UpgradeHistory{
+							{Version: upgradeConfig.Spec.Desired.Version, Phase: upgradev1alpha1.UpgradePhaseUpgraded},
+						}
+					})
+					It("Is ignored by the arbitration", func() {
+						mockKubeClient.EXPECT().Get(gomock.Any(), types.NamespacedName{Name: "version"}, gomock.Any()).SetArg(2, configv1.ClusterVersion{}).Times(1)
+						mockKubeClient.EXPECT().Status().Times(0)
+						_, err := reconciler.Reconcile(reconcile.Request{NamespacedName: upgradeConfigName})
+						Expect(err).NotTo(HaveOccurred())
+					})
+				})
+			})
+
+			Context("When a younger UpgradeConfig is upgrading", func() {
+				BeforeEach(func() {
+					upgradeConfig.CreationTimestamp = metav1.NewTime(time.Now().Add(-time.Hour))
+					upgradeConfig.Status.History = []upgradev1alpha1.UpgradeHistory{
+						{Version: upgradeConfig.Spec.Desired.Version, Phase: upgradev1alpha1.UpgradePhaseNew},
+					}
+					younger := testStructs.NewUpgradeConfigBuilder().WithNamespacedName(types.NamespacedName{Name: "a-younger"}).WithPhase(upgradev1alpha1.UpgradePhaseUpgrading).GetUpgradeConfig()
+					younger.CreationTimestamp = metav1.Now()
+					otherUpgradeConfigs = append(otherUpgradeConfigs, *younger)
+				})
+				It("Lets the upgrade in progress complete", func() {
+					matcher := testStructs.NewUpgradeConfigMatcher()
+					mockKubeClient.EXPECT().Status().Return(mockUpdater)
+					mockUpdater.EXPECT().Patch(gomock.Any(), matcher, gomock.Any())
+					mockClusterUpgraderBuilder.EXPECT().NewClient(gomock.Any()).Times(0)
+					_, err := reconciler.Reconcile(reconcile.Request{NamespacedName: upgradeConfigName})
+					Expect(err).NotTo(HaveOccurred())
+					Expect(matcher.ActualUpgradeConfig.Status.Conditions.GetCondition(upgradev1alpha1.Conflict).Message).To(ContainSubstring("a-younger"))
+				})
+
+				Context("When the older UpgradeConfig leaves the dry run", func() {
+					BeforeEach(func() {
+						upgradeConfig.Status.History = nil
+						upgradeConfig.Status.DryRun = &upgradev1alpha1.UpgradeHistory{Version: upgradeConfig.Spec.Desired.Version, Phase: upgradev1alpha1.UpgradePhaseUpgraded, DryRun: true}
+					})
+					It("Does not take over", func() {
+						matcher := testStructs.NewUpgradeConfigMatcher()
+						mockKubeClient.EXPECT().Status().Return(mockUpdater)
+						mockUpdater.EXPECT().Patch(gomock.Any(), matcher, gomock.Any())
+						mockClusterUpgraderBuilder.EXPECT().NewClient(gomock.Any()).Times(0)
+						_, err := reconciler.Reconcile(reconcile.Request{NamespacedName: upgradeConfigName})
+						Expect(err).NotTo(HaveOccurred())
+						Expect(matcher.ActualUpgradeConfig.Status.Conditions.IsTrueFor(upgradev1alpha1.Conflict)).To(BeTrue())
+					})
+				})
+			})
+
+			Context("When a younger UpgradeConfig exists", func() {
+				BeforeEach(func() {
+					upgradeConfig.CreationTimestamp = metav1.NewTime(time.Now().Add(-time.Hour))
+					upgradeConfig.Status.History = []upgradev1alpha1.UpgradeHistory{
+						{Version: upgradeConfig.Spec.Desired.Version, Phase: upgradev1alpha1.UpgradePhaseUpgraded},
+					}
+					upgradeConfig.Status.Conditions = upgradev1alpha1.NewConditions(upgradev1alpha1.UpgradeCondition{
+						Type:   upgradev1alpha1.Conflict,
+						Status: corev1.ConditionTrue,
+					})
+					younger := testStructs.NewUpgradeConfigBuilder().WithNamespacedName(types.NamespacedName{Name: "a-younger"}).GetUpgradeConfig()
+					younger.CreationTimestamp = metav1.Now()
+					otherUpgradeConfigs = append(otherUpgradeConfigs, *younger)
+				})
+				It("Is active and clears its Conflict condition", func() {
+					matcher := testStructs.NewUpgradeConfigMatcher()
+					mockKubeClient.EXPECT().Get(gomock.Any(), types.NamespacedName{Name: "version"}, gomock.Any()).SetArg(2, configv1.ClusterVersion{}).Times(1)
+					mockKubeClient.EXPECT().Status().Return(mockUpdater)
+					mockUpdater.EXPECT().Patch(gomock.Any(), matcher, gomock.Any())
+					_, err := reconciler.Reconcile(reconcile.Request{NamespacedName: upgradeConfigName})
+					Expect(err).NotTo(HaveOccurred())
+					Expect(matcher.ActualUpgradeConfig.Status.Conditions.IsFalseFor(upgradev1alpha1.Conflict)).To(BeTrue())
+				})
+			})
+
+			Context("When UpgradeConfigs are created at the same time", func() {
+				BeforeEach(func() {
+					other := testStructs.NewUpgradeConfigBuilder().WithNamespacedName(types.NamespacedName{Name: "a-first-by-name"}).GetUpgradeConfig()
+					other.CreationTimestamp = upgradeConfig.CreationTimestamp
+					otherUpgradeConfigs = append(otherUpgradeConfigs, *other)
+				})
+				It("Elects the first one by name", func() {
+					matcher := testStructs.NewUpgradeConfigMatcher()
+					mockKubeClient.EXPECT().Status().Return(mockUpdater)
+					mockUpdater.EXPECT().Patch(gomock.Any(), matcher, gomock.Any())
+					mockClusterUpgraderBuilder.EXPECT().NewClient(gomock.Any()).Times(0)
+					_, err := reconciler.Reconcile(reconcile.Request{NamespacedName: upgradeConfigName})
+					Expect(err).NotTo(HaveOccurred())
+					Expect(matcher.ActualUpgradeConfig.Status.Conditions.GetCondition(upgradev1alpha1.Conflict).Message).To(ContainSubstring("a-first-by-name"))
+				})
+			})
+
+			Context("When the UpgradeConfig asks for a dry run", func() {
+				BeforeEach(func() {
+					upgradeConfig.Spec.DryRun = true
+				})
+				It("dry runs the upgrade without checking or upgrading the cluster", func() {
+					mockKubeClient.EXPECT().Get(gomock.Any(), types.NamespacedName{Name: "version"}, gomock.Any()).Times(0)
+					mockClusterUpgrader.EXPECT().UpgradeCluster(gomock.Any(), gomock.Any()).Times(0)
+					mockClusterUpgrader.EXPECT().DryRun(gomock.Any(), gomock.Any()).Times(1)
+					mockClusterUpgraderBuilder.EXPECT().NewClient(gomock.Any()).Return(mockClusterUpgrader, nil)
+					result, err := reconciler.Reconcile(reconcile.Request{NamespacedName: upgradeConfigName})
+					Expect(err).NotTo(HaveOccurred())
+					Expect(result.Requeue).To(BeFalse())
+					Expect(result.RequeueAfter).To(BeZero())
+				})
+
+				Context("When its upgrade is in progress", func() {
+					BeforeEach(func() {
+						upgradeConfig.Status.History = []upgradev1alpha1.UpgradeHistory{
+							{Version: upgradeConfig.Spec.Desired.Version, Phase: upgradev1alpha1.UpgradePhaseUpgrading},
+						}
+					})
+					It("sets the DryRunIgnored condition and carries on with the upgrade", func() {
+						matcher := testStructs.NewUpgradeConfigMatcher()
+						mockKubeClient.EXPECT().Get(gomock.Any(), types.NamespacedName{Name: "version"}, gomock.Any()).SetArg(2, configv1.ClusterVersion{}).Times(1)
+						mockKubeClient.EXPECT().Status().Return(mockUpdater)
+						mockUpdater.EXPECT().Patch(gomock.Any(), matcher, gomock.Any())
+						mockClusterUpgrader.EXPECT().DryRun(gomock.Any(), gomock.Any()).Times(0)
+						mockClusterUpgrader.EXPECT().UpgradeCluster(gomock.Any(), gomock.Any()).Times(1)
+						mockClusterUpgraderBuilder.EXPECT().NewClient(gomock.Any()).Return(mockClusterUpgrader, nil)
+						_, err := reconciler.Reconcile(reconcile.Request{NamespacedName: upgradeConfigName})
+						Expect(err).NotTo(HaveOccurred())
+						Expect(matcher.ActualUpgradeConfig.Status.Conditions.IsTrueFor(upgradev1alpha1.DryRunIgnored)).To(BeTrue())
+					})
+				})
+			})
+
+			Context("When the upgrade is paused", func() {
+				BeforeEach(func() {
+					upgradeConfig.Annotations = map[string]string{upgradev1alpha1.PauseAnnotation: "true"}
+				})
+				It("does not proceed with upgrading the cluster", func() {
+					mockKubeClient.EXPECT().Get(gomock.Any(), types.NamespacedName{Name: "version"}, gomock.Any()).Times(0)
+					mockClusterUpgraderBuilder.EXPECT().NewClient(gomock.Any()).Times(0)
+					result, err := reconciler.Reconcile(reconcile.Request{NamespacedName: upgradeConfigName})
+					Expect(err).NotTo(HaveOccurred())
+					Expect(result.Requeue).To(BeFalse())
+					Expect(result.RequeueAfter).To(BeZero())
+				})
 			})
 
 			Context("When getting a clusterversion fails", func() {
@@ -110,15 +307,27 @@ var _ = Describe("UpgradeConfigController", func() {
 				})
 			})
 
-			Context("When a cluster is upgrading", func() {
+			Context("When a cluster is upgrading to a version started outside the operator", func() {
+				var externalVersion = "not the same version"
+				BeforeEach(func() {
+					upgradeConfig.Status.History = []upgradev1alpha1.UpgradeHistory{
+						{
+							Version: "a previous version",
+							Phase:   upgradev1alpha1.UpgradePhaseUpgraded,
+						},
+					}
+				})
 				JustBeforeEach(func() {
 					mockKubeClient.EXPECT().Get(gomock.Any(), types.NamespacedName{Name: "version"}, gomock.Any()).SetArg(2, configv1.ClusterVersion{
 						Spec: configv1.ClusterVersionSpec{
 							DesiredUpdate: &configv1.Update{
-								Version: "not the same version",
+								Version: externalVersion,
 							},
 						},
 						Status: configv1.ClusterVersionStatus{
+							Desired: configv1.Update{
+								Version: externalVersion,
+							},
 							Conditions: []configv1.ClusterOperatorStatusCondition{
 								{
 									Type:   configv1.OperatorProgressing,
@@ -129,11 +338,191 @@ var _ = Describe("UpgradeConfigController", func() {
 					}).Times(1)
 				})
 
-				It("Returns an empty result and no error", func() {
+				It("Records the external upgrade and does not upgrade the cluster", func() {
+					matcher := testStructs.NewUpgradeConfigMatcher()
+					mockKubeClient.EXPECT().Status().Return(mockUpdater)
+					mockUpdater.EXPECT().Patch(gomock.Any(), matcher, gomock.Any())
+					mockClusterUpgraderBuilder.EXPECT().NewClient(gomock.Any()).Times(0)
 					result, err := reconciler.Reconcile(reconcile.Request{NamespacedName: upgradeConfigName})
 					Expect(err).NotTo(HaveOccurred())
 					Expect(result.Requeue).To(BeFalse())
 					Expect(result.RequeueAfter).To(BeZero())
+					history := matcher.ActualUpgradeConfig.Status.History.GetHistory(externalVersion)
+					Expect(history).NotTo(BeNil())
+					Expect(history.Source).To(Equal(upgradev1alpha1.UpgradeSourceExternal))
+					Expect(history.Phase).To(Equal(upgradev1alpha1.UpgradePhaseUpgrading))
+					Expect(history.Conditions.GetCondition(upgradev1alpha1.ExternalUpgradeDetected).Reason).To(Equal("Adopted"))
+				})
+
+				Context("When the policy forbids external upgrades", func() {
+					BeforeEach(func() {
+						upgradeConfig.Spec.ExternalUpgradePolicy = upgradev1alpha1.ExternalUpgradePolicyForbid
+					})
+					It("Records the external upgrade as forbidden", func() {
+						matcher := testStructs.NewUpgradeConfigMatcher()
+						mockKubeClient.EXPECT().Status().Return(mockUpdater)
+						mockUpdater.EXPECT().Patch(gomock.Any(), matcher, gomock.Any())
+						mockClusterUpgraderBuilder.EXPECT().NewClient(gomock.Any()).Times(0)
+						_, err := reconciler.Reconcile(reconcile.Request{NamespacedName: upgradeConfigName})
+						Expect(err).NotTo(HaveOccurred())
+						history := matcher.ActualUpgradeConfig.Status.History.GetHistory(externalVersion)
+						Expect(history.Conditions.GetCondition(upgradev1alpha1.ExternalUpgradeDetected).Reason).To(Equal("Forbidden"))
+						Expect(matcher.ActualUpgradeConfig.Status.Conditions.IsTrueFor(upgradev1alpha1.ExternalUpgradeForbidden)).To(BeTrue())
+					})
+				})
+
+				Context("When the external upgrade is already recorded", func() {
+					BeforeEach(func() {
+						upgradeConfig.Status.History = []upgradev1alpha1.UpgradeHistory{
+							{
+								Version: externalVersion,
+								Phase:   upgradev1alpha1.UpgradePhaseUpgrading,
+								Source:  upgradev1alpha1.UpgradeSourceExternal,
+							},
+						}
+					})
+					It("Waits for it to complete", func() {
+						mockKubeClient.EXPECT().Status().Times(0)
+						mockClusterUpgraderBuilder.EXPECT().NewClient(gomock.Any()).Times(0)
+						result, err := reconciler.Reconcile(reconcile.Request{NamespacedName: upgradeConfigName})
+						Expect(err).NotTo(HaveOccurred())
+						Expect(result.Requeue).To(BeFalse())
+					})
+				})
+			})
+
+			Context("When the UpgradeConfig has no history yet", func() {
+				JustBeforeEach(func() {
+					now := time.Now()
+					at := func(hours int) *metav1.Time {
+						return &metav1.Time{Time: now.Add(time.Duration(hours) * time.Hour)}
+					}
+					mockKubeClient.EXPECT().Get(gomock.Any(), types.NamespacedName{Name: "version"}, gomock.Any()).SetArg(2, configv1.ClusterVersion{
+						Status: configv1.ClusterVersionStatus{
+							Desired: configv1.Update{Version: "4.4.3"},
+							History: []configv1.UpdateHistory{
+								{Version: "4.4.3", State: configv1.CompletedUpdate, StartedTime: *at(-3), CompletionTime: at(-2)},
+								{Version: "4.4.2", State: configv1.PartialUpdate, StartedTime: *at(-5), CompletionTime: at(-3)},
+								{Version: "4.4.1", State: configv1.CompletedUpdate, StartedTime: *at(-9), CompletionTime: at(-8)},
+							},
+						},
+					}).AnyTimes()
+				})
+				It("Imports the clusterversion history before adding the desired version", func() {
+					matcher := testStructs.NewUpgradeConfigMatcher()
+					mockKubeClient.EXPECT().Status().Return(mockUpdater).AnyTimes()
+					mockUpdater.EXPECT().Patch(gomock.Any(), matcher, gomock.Any()).Times(2)
+					mockClusterUpgrader.EXPECT().UpgradeCluster(gomock.Any(), gomock.Any()).Times(1)
+					mockClusterUpgraderBuilder.EXPECT().NewClient(gomock.Any()).Return(mockClusterUpgrader, nil)
+					_, err := reconciler.Reconcile(reconcile.Request{NamespacedName: upgradeConfigName})
+					Expect(err).NotTo(HaveOccurred())
+					histories := matcher.ActualUpgradeConfig.Status.History
+					Expect(histories).To(HaveLen(3))
+					Expect(histories[0].Version).To(Equal(upgradeConfig.Spec.Desired.Version))
+					Expect(histories[1].Version).To(Equal("4.4.3"))
+					Expect(histories[1].Source).To(Equal(upgradev1alpha1.UpgradeSourceImported))
+					Expect(histories[1].Phase).To(Equal(upgradev1alpha1.UpgradePhaseUpgraded))
+					Expect(histories[1].CompleteTime).NotTo(BeNil())
+					Expect(histories[2].Version).To(Equal("4.4.2"))
+					Expect(histories[2].Phase).To(Equal(upgradev1alpha1.UpgradePhaseFailed))
+				})
+			})
+
+			Context("When the desired version changes while upgrading", func() {
+				var inFlightVersion = "an in flight version"
+				BeforeEach(func() {
+					upgradeConfig.Status.History = []upgradev1alpha1.UpgradeHistory{
+						{
+							Version: inFlightVersion,
+							Phase:   upgradev1alpha1.UpgradePhaseUpgrading,
+							Conditions: upgradev1alpha1.NewConditions(
+								upgradev1alpha1.UpgradeCondition{Type: upgradev1alpha1.UpgradeValidated, Status: corev1.ConditionTrue},
+								upgradev1alpha1.UpgradeCondition{Type: upgradev1alpha1.UpgradePreHealthCheck, Status: corev1.ConditionTrue},
+								upgradev1alpha1.UpgradeCondition{Type: upgradev1alpha1.UpgradeScaleUpExtraNodes, Status: corev1.ConditionTrue},
+							),
+						},
+					}
+				})
+				JustBeforeEach(func() {
+					mockKubeClient.EXPECT().Get(gomock.Any(), types.NamespacedName{Name: "version"}, gomock.Any()).SetArg(2, configv1.ClusterVersion{})
+				})
+
+				Context("When the upgrade was not commenced yet", func() {
+					It("Supersedes it and keeps the pre-upgrade steps", func() {
+						matcher := testStructs.NewUpgradeConfigMatcher()
+						mockKubeClient.EXPECT().Status().Return(mockUpdater)
+						mockUpdater.EXPECT().Patch(gomock.Any(), matcher, gomock.Any())
+						mockClusterUpgrader.EXPECT().UpgradeCluster(gomock.Any(), gomock.Any()).Times(1)
+						mockClusterUpgraderBuilder.EXPECT().NewClient(gomock.Any()).Return(mockClusterUpgrader, nil)
+						_, err := reconciler.Reconcile(reconcile.Request{NamespacedName: upgradeConfigName})
+						Expect(err).NotTo(HaveOccurred())
+						old := matcher.ActualUpgradeConfig.Status.History.GetHistory(inFlightVersion)
+						Expect(old.Phase).To(Equal(upgradev1alpha1.UpgradePhaseSuperseded))
+						Expect(old.CompleteTime).NotTo(BeNil())
+						history := matcher.ActualUpgradeConfig.Status.History.GetHistory(upgradeConfig.Spec.Desired.Version)
+						Expect(history.Phase).To(Equal(upgradev1alpha1.UpgradePhaseNew))
+						Expect(history.Conditions.IsTrueFor(upgradev1alpha1.UpgradePreHealthCheck)).To(BeTrue())
+						Expect(history.Conditions.IsTrueFor(upgradev1alpha1.UpgradeScaleUpExtraNodes)).To(BeTrue())
+						// The new version is validated against the current version
+						Expect(history.Conditions.GetCondition(upgradev1alpha1.UpgradeValidated)).To(BeNil())
+					})
+				})
+
+				Context("When the upgrade was commenced", func() {
+					BeforeEach(func() {
+						upgradeConfig.Status.History[0].Conditions.SetCondition(upgradev1alpha1.UpgradeCondition{Type: upgradev1alpha1.CommenceUpgrade, Status: corev1.ConditionTrue})
+					})
+					It("Checks the desired version is an available update from the version in progress", func() {
+						// The empty clusterversion has no cluster id to ask cincinnati with
+						mockKubeClient.EXPECT().Get(gomock.Any(), types.NamespacedName{Name: "version"}, gomock.Any()).SetArg(2, configv1.ClusterVersion{})
+						mockKubeClient.EXPECT().Status().Times(0)
+						mockClusterUpgraderBuilder.EXPECT().NewClient(gomock.Any()).Times(0)
+						_, err := reconciler.Reconcile(reconcile.Request{NamespacedName: upgradeConfigName})
+						Expect(err).To(HaveOccurred())
+					})
+				})
+			})
+
+			Context("When an external upgrade completed", func() {
+				var externalVersion = "an external version"
+				BeforeEach(func() {
+					upgradeConfig.Status.History = []upgradev1alpha1.UpgradeHistory{
+						{
+							Version: upgradeConfig.Spec.Desired.Version,
+							Phase:   upgradev1alpha1.UpgradePhaseUpgraded,
+						},
+						{
+							Version:    externalVersion,
+							Phase:      upgradev1alpha1.UpgradePhaseUpgrading,
+							Source:     upgradev1alpha1.UpgradeSourceExternal,
+							Conditions: upgradev1alpha1.NewConditions(upgradev1alpha1.UpgradeCondition{Type: upgradev1alpha1.ExternalUpgradeDetected, Status: corev1.ConditionTrue}),
+						},
+					}
+				})
+				JustBeforeEach(func() {
+					mockKubeClient.EXPECT().Get(gomock.Any(), types.NamespacedName{Name: "version"}, gomock.Any()).SetArg(2, configv1.ClusterVersion{
+						Status: configv1.ClusterVersionStatus{
+							Desired: configv1.Update{
+								Version: externalVersion,
+							},
+							History: []configv1.UpdateHistory{
+								{
+									Version: externalVersion,
+									State:   configv1.CompletedUpdate,
+								},
+							},
+						},
+					}).Times(1)
+				})
+				It("Marks the external upgrade as upgraded", func() {
+					matcher := testStructs.NewUpgradeConfigMatcher()
+					mockKubeClient.EXPECT().Status().Return(mockUpdater)
+					mockUpdater.EXPECT().Patch(gomock.Any(), matcher, gomock.Any())
+					_, err := reconciler.Reconcile(reconcile.Request{NamespacedName: upgradeConfigName})
+					Expect(err).NotTo(HaveOccurred())
+					history := matcher.ActualUpgradeConfig.Status.History.GetHistory(externalVersion)
+					Expect(history.Phase).To(Equal(upgradev1alpha1.UpgradePhaseUpgraded))
+					Expect(history.CompleteTime).NotTo(BeNil())
 				})
 			})
 
@@ -153,7 +542,7 @@ var _ = Describe("UpgradeConfigController", func() {
 					It("Returns an error", func() {
 						fakeError := k8serrs.NewInternalError(fmt.Errorf("a fake error"))
 						mockKubeClient.EXPECT().Status().Return(mockUpdater)
-						mockUpdater.EXPECT().Update(gomock.Any(), gomock.Any()).Return(fakeError)
+						mockUpdater.EXPECT().Patch(gomock.Any(), gomock.Any(), gomock.Any()).Return(fakeError)
 						result, err := reconciler.Reconcile(reconcile.Request{NamespacedName: upgradeConfigName})
 						Expect(err).To(Equal(fakeError))
 						Expect(result.Requeue).To(BeFalse())
@@ -165,7 +554,7 @@ var _ = Describe("UpgradeConfigController", func() {
 					It("Adds it successfully", func() {
 						matcher := testStructs.NewUpgradeConfigMatcher()
 						mockKubeClient.EXPECT().Status().Return(mockUpdater).AnyTimes()
-						mockUpdater.EXPECT().Update(gomock.Any(), matcher).AnyTimes()
+						mockUpdater.EXPECT().Patch(gomock.Any(), matcher, gomock.Any()).AnyTimes()
 						mockClusterUpgrader.EXPECT().UpgradeCluster(gomock.Any(), gomock.Any()).Times(1)
 						mockClusterUpgraderBuilder.EXPECT().NewClient(gomock.Any()).Return(mockClusterUpgrader, nil)
 						_, err := reconciler.Reconcile(reconcile.Request{NamespacedName: upgradeConfigName})
@@ -186,8 +575,13 @@ var _ = Describe("UpgradeConfigController", func() {
 						Version: version,
 					},
 				}
+			})
+			JustBeforeEach(func() {
 				mockKubeClient.EXPECT().Get(gomock.Any(), upgradeConfigName, gomock.Any()).SetArg(2, *upgradeConfig).Times(1)
 				mockKubeClient.EXPECT().Get(gomock.Any(), types.NamespacedName{Name: "version"}, gomock.Any()).SetArg(2, configv1.ClusterVersion{}).Times(1)
+				mockKubeClient.EXPECT().List(gomock.Any(), gomock.Any()).SetArg(1, upgradev1alpha1.UpgradeConfigList{
+					Items: []upgradev1alpha1.UpgradeConfig{*upgradeConfig},
+				}).AnyTimes()
 			})
 
 			Context("When the upgrade phase is New", func() {
@@ -195,8 +589,70 @@ var _ = Describe("UpgradeConfigController", func() {
 					upgradeConfig.Status.History[0].Phase = upgradev1alpha1.UpgradePhaseNew
 				})
 
-				Context("When the cluster is not ready to upgrade", func() {
-					// TODO: This is never true at the moment - no readiness check implemented
+				Context("When the upgrade is scheduled later", func() {
+					BeforeEach(func() {
+						upgradeConfig.Spec.UpgradeAt = time.Now().Add(time.Hour).Format(time.RFC3339)
+					})
+					It("Marks the upgrade pending until its scheduled time", func() {
+						matcher := testStructs.NewUpgradeConfigMatcher()
+						mockKubeClient.EXPECT().Status().Return(mockUpdater)
+						mockUpdater.EXPECT().Patch(gomock.Any(), matcher, gomock.Any())
+						mockClusterUpgraderBuilder.EXPECT().NewClient(gomock.Any()).Times(0)
+						result, err := reconciler.Reconcile(reconcile.Request{NamespacedName: upgradeConfigName})
+						Expect(err).NotTo(HaveOccurred())
+						Expect(result.RequeueAfter).To(BeNumerically(">", 59*time.Minute))
+						Expect(matcher.ActualUpgradeConfig.Status.History.GetHistory(version).Phase).To(Equal(upgradev1alpha1.UpgradePhasePending))
+					})
+				})
+
+				Context("When the upgrade time is invalid", func() {
+					BeforeEach(func() {
+						upgradeConfig.Spec.UpgradeAt = "tomorrow"
+					})
+					It("Sets the InvalidSchedule condition and does not upgrade", func() {
+						matcher := testStructs.NewUpgradeConfigMatcher()
+						mockKubeClient.EXPECT().Status().Return(mockUpdater)
+						mockUpdater.EXPECT().Patch(gomock.Any(), matcher, gomock.Any())
+						mockClusterUpgraderBuilder.EXPECT().NewClient(gomock.Any()).Times(0)
+						result, err := reconciler.Reconcile(reconcile.Request{NamespacedName: upgradeConfigName})
+						Expect(err).NotTo(HaveOccurred())
+						Expect(result.RequeueAfter).To(BeZero())
+						condition := matcher.ActualUpgradeConfig.Status.Conditions.GetCondition(upgradev1alpha1.InvalidSchedule)
+						Expect(condition.IsTrue()).To(BeTrue())
+						Expect(condition.Message).To(ContainSubstring(`invalid upgrade time tomorrow`))
+					})
+				})
+
+				Context("When the invalid upgrade time was fixed", func() {
+					BeforeEach(func() {
+						upgradeConfig.Status.Conditions = upgradev1alpha1.Conditions{{
+							Type:   upgradev1alpha1.InvalidSchedule,
+							Status: corev1.ConditionTrue,
+							Reason: "InvalidUpgradeAt",
+						}}
+					})
+					It("Clears the InvalidSchedule condition and upgrades", func() {
+						matcher := testStructs.NewUpgradeConfigMatcher()
+						mockKubeClient.EXPECT().Status().Return(mockUpdater)
+						mockUpdater.EXPECT().Patch(gomock.Any(), matcher, gomock.Any())
+						mockClusterUpgrader.EXPECT().UpgradeCluster(gomock.Any(), gomock.Any()).Times(1)
+						mockClusterUpgraderBuilder.EXPECT().NewClient(gomock.Any()).Return(mockClusterUpgrader, nil)
+						_, err := reconciler.Reconcile(reconcile.Request{NamespacedName: upgradeConfigName})
+						Expect(err).NotTo(HaveOccurred())
+						Expect(matcher.ActualUpgradeConfig.Status.Conditions.IsFalseFor(upgradev1alpha1.InvalidSchedule)).To(BeTrue())
+					})
+				})
+
+				Context("When the scheduled time has passed", func() {
+					BeforeEach(func() {
+						upgradeConfig.Spec.UpgradeAt = time.Now().Add(-time.Hour).Format(time.RFC3339)
+					})
+					It("Invokes the upgrader", func() {
+						mockClusterUpgrader.EXPECT().UpgradeCluster(gomock.Any(), gomock.Any()).Times(1)
+						mockClusterUpgraderBuilder.EXPECT().NewClient(gomock.Any()).Return(mockClusterUpgrader, nil)
+						_, err := reconciler.Reconcile(reconcile.Request{NamespacedName: upgradeConfigName})
+						Expect(err).NotTo(HaveOccurred())
+					})
 				})
 
 				Context("When the cluster is ready to upgrade", func() {
@@ -254,8 +710,17 @@ var _ = Describe("UpgradeConfigController", func() {
 					})
 				})
 
-				Context("When the cluster is not ready to upgrade", func() {
-					// TODO: This is never true at the moment - no readiness check implemented
+				Context("When the upgrade is scheduled later", func() {
+					BeforeEach(func() {
+						upgradeConfig.Spec.UpgradeAt = time.Now().Add(time.Hour).Format(time.RFC3339)
+					})
+					It("Keeps waiting without updating the status", func() {
+						mockKubeClient.EXPECT().Status().Times(0)
+						mockClusterUpgraderBuilder.EXPECT().NewClient(gomock.Any()).Times(0)
+						result, err := reconciler.Reconcile(reconcile.Request{NamespacedName: upgradeConfigName})
+						Expect(err).NotTo(HaveOccurred())
+						Expect(result.RequeueAfter).To(BeNumerically(">", 59*time.Minute))
+					})
 				})
 			})
 
@@ -298,6 +763,17 @@ var _ = Describe("UpgradeConfigController", func() {
 						Expect(result.RequeueAfter).To(BeZero())
 					})
 				})
+
+				Context("When an approval gate asks to retry later", func() {
+					It("requeues the UpgradeConfig for when the gate may be asked again", func() {
+						retry := &cluster_upgrader.GateRetryError{Gate: "change-management", RetryAfter: 10 * time.Minute}
+						mockClusterUpgrader.EXPECT().UpgradeCluster(gomock.Any(), gomock.Any()).Times(1).Return(retry)
+						mockClusterUpgraderBuilder.EXPECT().NewClient(gomock.Any()).Return(mockClusterUpgrader, nil)
+						result, err := reconciler.Reconcile(reconcile.Request{NamespacedName: upgradeConfigName})
+						Expect(err).NotTo(HaveOccurred())
+						Expect(result.RequeueAfter).To(Equal(10 * time.Minute))
+					})
+				})
 			})
 
 			Context("When the upgrade phase is Upgraded", func() {
@@ -326,6 +802,35 @@ var _ = Describe("UpgradeConfigController", func() {
 				})
 			})
 
+			Context("When the upgrade phase is Failed and a retry is requested", func() {
+				JustBeforeEach(func() {
+					upgradeConfig.Status.History[0].Phase = upgradev1alpha1.UpgradePhaseFailed
+					upgradeConfig.Status.History[0].Conditions = upgradev1alpha1.Conditions{
+						{Type: upgradev1alpha1.UpgradePreHealthCheck, Status: corev1.ConditionFalse},
+						{Type: upgradev1alpha1.UpgradeValidated, Status: corev1.ConditionTrue},
+					}
+					// The annotations map is shared with the UpgradeConfig returned by the mocked Get
+					upgradeConfig.Annotations[upgradev1alpha1.RetryAnnotation] = "true"
+				})
+				It("resets the failed steps and removes the retry annotation", func() {
+					matcher := testStructs.NewUpgradeConfigMatcher()
+					mockClusterUpgraderBuilder.EXPECT().NewClient(gomock.Any()).Times(0)
+					mockKubeClient.EXPECT().Status().Return(mockUpdater)
+					mockUpdater.EXPECT().Patch(gomock.Any(), matcher, gomock.Any())
+					mockKubeClient.EXPECT().Patch(gomock.Any(), gomock.Any(), gomock.Any()).Do(
+						func(ctx interface{}, obj *upgradev1alpha1.UpgradeConfig, patch interface{}) {
+							Expect(obj.Annotations).NotTo(HaveKey(upgradev1alpha1.RetryAnnotation))
+						})
+					result, err := reconciler.Reconcile(reconcile.Request{NamespacedName: upgradeConfigName})
+					Expect(err).NotTo(HaveOccurred())
+					Expect(result.Requeue).To(BeTrue())
+					history := matcher.ActualUpgradeConfig.Status.History.GetHistory(version)
+					Expect(history.Phase).To(Equal(upgradev1alpha1.UpgradePhaseNew))
+					Expect(history.Conditions).To(HaveLen(1))
+					Expect(history.Conditions[0].Type).To(Equal(upgradev1alpha1.UpgradeValidated))
+				})
+			})
+
 			Context("When the upgrade phase is Unknown", func() {
 				JustBeforeEach(func() {
 					upgradeConfig.Status.History[0].Phase = upgradev1alpha1.UpgradePhaseUnknown
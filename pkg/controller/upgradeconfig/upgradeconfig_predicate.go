@@ -0,0 +1,57 @@
+package upgradeconfig
+
+import (
+	upgradev1alpha1 "github.com/openshift/managed-upgrade-operator/pkg/apis/upgrade/v1alpha1"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+)
+
+// Annotations whose changes trigger a reconcile
+var triggerAnnotations = []string{
+	upgradev1alpha1.RetryAnnotation,
+	upgradev1alpha1.PauseAnnotation,
+	upgradev1alpha1.ResumeAnnotation,
+	upgradev1alpha1.ExternalUpgradeAcknowledgedAnnotation,
+	upgradev1alpha1.ApprovedByAnnotation,
+	upgradev1alpha1.ApprovedVersionAnnotation,
+}
+
+// UpgradeConfigChangedPredicate lets through the spec changes and the changes of the trigger annotations,
+// ignoring the status updates made by the operator itself
+type UpgradeConfigChangedPredicate struct {
+	predicate.Funcs
+}
+
+// Update implements UpdateEvent filter for validating generation and trigger annotation changes
+func (UpgradeConfigChangedPredicate) Update(e event.UpdateEvent) bool {
+	if e.MetaOld == nil {
+		log.Error(nil, "Update event has no old metadata", "event", e)
+		return false
+	}
+	if e.ObjectOld == nil {
+		log.Error(nil, "Update event has no old runtime object to update", "event", e)
+		return false
+	}
+	if e.ObjectNew == nil {
+		log.Error(nil, "Update event has no new runtime object for update", "event", e)
+		return false
+	}
+	if e.MetaNew == nil {
+		log.Error(nil, "Update event has no new metadata", "event", e)
+		return false
+	}
+
+	// The status is a subresource, only spec changes increase the generation
+	if e.MetaNew.GetGeneration() != e.MetaOld.GetGeneration() {
+		return true
+	}
+
+	oldAnnotations := e.MetaOld.GetAnnotations()
+	newAnnotations := e.MetaNew.GetAnnotations()
+	for _, a := range triggerAnnotations {
+		if oldAnnotations[a] != newAnnotations[a] {
+			return true
+		}
+	}
+	return false
+}
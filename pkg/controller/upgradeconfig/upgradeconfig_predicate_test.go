@@ -0,0 +1,136 @@
+package upgradeconfig
+
+import (
+	upgradev1alpha1 "github.com/openshift/managed-upgrade-operator/pkg/apis/upgrade/v1alpha1"
+	testStructs "github.com/openshift/managed-upgrade-operator/util/mocks/structs"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("UpgradeConfigController", func() {
+
+	var (
+		upgradeConfigName types.NamespacedName
+		upgradeConfig     *upgradev1alpha1.UpgradeConfig
+	)
+
+	BeforeEach(func() {
+		upgradeConfigName = types.NamespacedName{
+			Name:      "test-upgradeconfig",
+			Namespace: "test-namespace",
+		}
+		upgradeConfig = testStructs.NewUpgradeConfigBuilder().WithNamespacedName(upgradeConfigName).GetUpgradeConfig()
+	})
+
+	Context("Update", func() {
+		var (
+			ucp UpgradeConfigChangedPredicate
+			uc1 *upgradev1alpha1.UpgradeConfig
+			uc2 *upgradev1alpha1.UpgradeConfig
+		)
+
+		BeforeEach(func() {
+			uc1 = testStructs.NewUpgradeConfigBuilder().WithNamespacedName(upgradeConfigName).GetUpgradeConfig()
+			uc1.Generation = 1
+			uc2 = uc1.DeepCopy()
+		})
+
+		update := func() bool {
+			return ucp.Update(event.UpdateEvent{MetaOld: uc1.GetObjectMeta(), ObjectOld: uc1, MetaNew: uc2.GetObjectMeta(), ObjectNew: uc2})
+		}
+
+		Context("When the old object meta doesn't exist", func() {
+			It("will not return true", func() {
+				result := ucp.Update(event.UpdateEvent{MetaOld: nil, ObjectOld: upgradeConfig, MetaNew: upgradeConfig.GetObjectMeta(), ObjectNew: upgradeConfig})
+				Expect(result).To(BeFalse())
+			})
+		})
+		Context("When the old object doesn't exist", func() {
+			It("will not return true", func() {
+				result := ucp.Update(event.UpdateEvent{MetaOld: upgradeConfig.GetObjectMeta(), ObjectOld: nil, MetaNew: upgradeConfig.GetObjectMeta(), ObjectNew: upgradeConfig})
+				Expect(result).To(BeFalse())
+			})
+		})
+		Context("When the new object meta doesn't exist", func() {
+			It("will not return true", func() {
+				result := ucp.Update(event.UpdateEvent{MetaOld: upgradeConfig.GetObjectMeta(), ObjectOld: upgradeConfig, MetaNew: nil, ObjectNew: upgradeConfig})
+				Expect(result).To(BeFalse())
+			})
+		})
+		Context("When the new object doesn't exist", func() {
+			It("will not return true", func() {
+				result := ucp.Update(event.UpdateEvent{MetaOld: upgradeConfig.GetObjectMeta(), ObjectOld: upgradeConfig, MetaNew: upgradeConfig.GetObjectMeta(), ObjectNew: nil})
+				Expect(result).To(BeFalse())
+			})
+		})
+		Context("When nothing changed", func() {
+			It("will not return true", func() {
+				Expect(update()).To(BeFalse())
+			})
+		})
+		Context("When only the status changed", func() {
+			It("will not return true", func() {
+				uc2.Status.History = []upgradev1alpha1.UpgradeHistory{{Version: "something else"}}
+				uc2.ResourceVersion = "2"
+				Expect(update()).To(BeFalse())
+			})
+		})
+		Context("When the spec changed", func() {
+			It("will return true", func() {
+				uc2.Spec.Desired.Version = "something else"
+				uc2.Generation = 2
+				Expect(update()).To(BeTrue())
+			})
+		})
+		Context("When the spec and the status changed together", func() {
+			It("will return true", func() {
+				uc2.Spec.Desired.Version = "something else"
+				uc2.Generation = 2
+				uc2.Status.History = []upgradev1alpha1.UpgradeHistory{{Version: "something else"}}
+				Expect(update()).To(BeTrue())
+			})
+		})
+		Context("When a retry is requested", func() {
+			It("will return true", func() {
+				uc2.Annotations = map[string]string{upgradev1alpha1.RetryAnnotation: "true"}
+				Expect(update()).To(BeTrue())
+			})
+		})
+		Context("When the upgrade is approved", func() {
+			It("will return true", func() {
+				uc2.Annotations = map[string]string{
+					upgradev1alpha1.ApprovedByAnnotation:      "someone@example.com",
+					upgradev1alpha1.ApprovedVersionAnnotation: "fakeVersion",
+				}
+				Expect(update()).To(BeTrue())
+			})
+		})
+		Context("When the upgrade is paused", func() {
+			It("will return true", func() {
+				uc2.Annotations = map[string]string{upgradev1alpha1.PauseAnnotation: "true"}
+				Expect(update()).To(BeTrue())
+			})
+		})
+		Context("When the upgrade is resumed", func() {
+			It("will return true", func() {
+				uc1.Annotations = map[string]string{upgradev1alpha1.PauseAnnotation: "true"}
+				Expect(update()).To(BeTrue())
+			})
+		})
+		Context("When the worker upgrade paused by the monitor is resumed", func() {
+			It("will return true", func() {
+				uc2.Annotations = map[string]string{upgradev1alpha1.ResumeAnnotation: "true"}
+				Expect(update()).To(BeTrue())
+			})
+		})
+		Context("When an unrelated annotation changed", func() {
+			It("will not return true", func() {
+				uc2.Annotations = map[string]string{"kubectl.kubernetes.io/last-applied-configuration": "{}"}
+				Expect(update()).To(BeFalse())
+			})
+		})
+	})
+})
@@ -0,0 +1,137 @@
+package estimator
+
+import (
+	"math"
+	"time"
+
+	machineconfigapi "github.com/openshift/machine-config-operator/pkg/apis/machineconfiguration.openshift.io/v1"
+	upgradev1alpha1 "github.com/openshift/managed-upgrade-operator/pkg/apis/upgrade/v1alpha1"
+	"github.com/openshift/managed-upgrade-operator/pkg/operatorconfig"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+// Pool describe the nodes of a MachineConfigPool which are to be upgraded
+type Pool struct {
+	// Number of nodes to upgrade
+	Nodes int32
+	// Number of nodes upgraded at the same time
+	MaxUnavailable int32
+}
+
+// Batches returns in how many rounds the nodes of the pool are upgraded
+func (p Pool) Batches() int32 {
+	if p.Nodes <= 0 {
+		return 0
+	}
+	maxUnavailable := p.MaxUnavailable
+	if maxUnavailable < 1 {
+		maxUnavailable = 1
+	}
+	return int32(math.Ceil(float64(p.Nodes) / float64(maxUnavailable)))
+}
+
+// NewPool returns the pool of the MachineConfigPool. If pendingOnly is set, only the nodes not yet updated are counted.
+func NewPool(mcp *machineconfigapi.MachineConfigPool, pendingOnly bool) Pool {
+	nodes := mcp.Status.MachineCount
+	if pendingOnly {
+		nodes = mcp.Status.MachineCount - mcp.Status.UpdatedMachineCount
+	}
+	maxUnavailable := 1
+	if mcp.Spec.MaxUnavailable != nil {
+		value, err := intstr.GetValueFromIntOrPercent(mcp.Spec.MaxUnavailable, int(mcp.Status.MachineCount), false)
+		if err == nil && value > 0 {
+			maxUnavailable = value
+		}
+	}
+	return Pool{Nodes: nodes, MaxUnavailable: int32(maxUnavailable)}
+}
+
+// Estimate predicts how long each stage of an upgrade of the pools takes.
+// Durations are learned from the completed upgrades in the history, falling back to the configured defaults when there is none.
+func Estimate(masters Pool, workers Pool, histories upgradev1alpha1.UpgradeHistories) *upgradev1alpha1.UpgradeEstimate {
+	cfg := operatorconfig.Get()
+	controlPlane := cfg.ControlPlaneDuration()
+	masterBatch := cfg.NodeDuration()
+	workerBatch := cfg.NodeDuration()
+
+	samples := pastDurations(histories, masters.Batches(), workers.Batches())
+	if len(samples.controlPlane) > 0 {
+		controlPlane = average(samples.controlPlane)
+	}
+	if len(samples.masterBatch) > 0 {
+		masterBatch = average(samples.masterBatch)
+	}
+	if len(samples.workerBatch) > 0 {
+		workerBatch = average(samples.workerBatch)
+	}
+
+	return &upgradev1alpha1.UpgradeEstimate{
+		ControlPlaneMinutes: minutes(controlPlane),
+		MasterNodesMinutes:  minutes(masterBatch * time.Duration(masters.Batches())),
+		WorkerNodesMinutes:  minutes(workerBatch * time.Duration(workers.Batches())),
+		MasterBatches:       masters.Batches(),
+		WorkerBatches:       workers.Batches(),
+		Samples:             int32(len(samples.controlPlane)),
+	}
+}
+
+type samples struct {
+	controlPlane []time.Duration
+	masterBatch  []time.Duration
+	workerBatch  []time.Duration
+}
+
+// pastDurations returns the stage durations of the completed upgrades. Node durations are divided by the
+// number of batches recorded in the upgrade's estimate, or by the current number of batches if there is none.
+// Upgrades not performed by the operator only tell how long the ClusterVersion took to complete, which is
+// accounted as the control plane duration.
+func pastDurations(histories upgradev1alpha1.UpgradeHistories, masterBatches int32, workerBatches int32) samples {
+	s := samples{}
+	for _, h := range histories {
+		if h.DryRun || h.Phase != upgradev1alpha1.UpgradePhaseUpgraded {
+			continue
+		}
+		if h.Source == upgradev1alpha1.UpgradeSourceImported || h.Source == upgradev1alpha1.UpgradeSourceExternal {
+			if h.StartTime != nil && h.CompleteTime != nil {
+				s.controlPlane = append(s.controlPlane, h.CompleteTime.Sub(h.StartTime.Time))
+			}
+			continue
+		}
+		commenced := h.Conditions.GetCondition(upgradev1alpha1.CommenceUpgrade)
+		controlPlane := h.Conditions.GetCondition(upgradev1alpha1.ControlPlaneUpgraded)
+		masters := h.Conditions.GetCondition(upgradev1alpha1.AllMasterNodesUpgraded)
+		workers := h.Conditions.GetCondition(upgradev1alpha1.AllWorkerNodesUpgraded)
+		if commenced == nil || controlPlane == nil || masters == nil || workers == nil ||
+			commenced.CompleteTime == nil || controlPlane.CompleteTime == nil ||
+			masters.CompleteTime == nil || workers.CompleteTime == nil {
+			continue
+		}
+
+		mb, wb := masterBatches, workerBatches
+		if h.Estimate != nil {
+			mb, wb = h.Estimate.MasterBatches, h.Estimate.WorkerBatches
+		}
+		if mb < 1 {
+			mb = 1
+		}
+		if wb < 1 {
+			wb = 1
+		}
+		s.controlPlane = append(s.controlPlane, controlPlane.CompleteTime.Sub(commenced.CompleteTime.Time))
+		s.masterBatch = append(s.masterBatch, masters.CompleteTime.Sub(controlPlane.CompleteTime.Time)/time.Duration(mb))
+		s.workerBatch = append(s.workerBatch, workers.CompleteTime.Sub(controlPlane.CompleteTime.Time)/time.Duration(wb))
+	}
+	return s
+}
+
+func average(durations []time.Duration) time.Duration {
+	var total time.Duration
+	for _, d := range durations {
+		total += d
+	}
+	return total / time.Duration(len(durations))
+}
+
+func minutes(d time.Duration) int32 {
+	return int32(math.Ceil(d.Minutes()))
+}
@@ -0,0 +1,125 @@
+package estimator
+
+import (
+	"time"
+
+	machineconfigapi "github.com/openshift/machine-config-operator/pkg/apis/machineconfiguration.openshift.io/v1"
+	upgradev1alpha1 "github.com/openshift/managed-upgrade-operator/pkg/apis/upgrade/v1alpha1"
+	"github.com/openshift/managed-upgrade-operator/pkg/operatorconfig"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Estimator", func() {
+
+	Context("NewPool", func() {
+		var mcp *machineconfigapi.MachineConfigPool
+		BeforeEach(func() {
+			mcp = &machineconfigapi.MachineConfigPool{}
+			mcp.Status.MachineCount = 10
+			mcp.Status.UpdatedMachineCount = 4
+		})
+		It("defaults maxUnavailable to 1", func() {
+			Expect(NewPool(mcp, false)).To(Equal(Pool{Nodes: 10, MaxUnavailable: 1}))
+		})
+		It("only counts the pending nodes when asked to", func() {
+			Expect(NewPool(mcp, true).Nodes).To(Equal(int32(6)))
+		})
+		It("resolves a percentage maxUnavailable against the pool size", func() {
+			mu := intstr.FromString("20%")
+			mcp.Spec.MaxUnavailable = &mu
+			Expect(NewPool(mcp, false).MaxUnavailable).To(Equal(int32(2)))
+		})
+	})
+
+	Context("Batches", func() {
+		It("rounds up", func() {
+			Expect(Pool{Nodes: 7, MaxUnavailable: 2}.Batches()).To(Equal(int32(4)))
+		})
+		It("is zero without nodes", func() {
+			Expect(Pool{Nodes: 0, MaxUnavailable: 2}.Batches()).To(BeZero())
+		})
+	})
+
+	Context("Estimate", func() {
+		var masters, workers Pool
+		BeforeEach(func() {
+			masters = Pool{Nodes: 3, MaxUnavailable: 1}
+			workers = Pool{Nodes: 6, MaxUnavailable: 2}
+		})
+
+		Context("When there is no past upgrade", func() {
+			It("uses the default durations", func() {
+				estimate := Estimate(masters, workers, nil)
+				Expect(estimate.ControlPlaneMinutes).To(Equal(int32(90)))
+				Expect(estimate.MasterNodesMinutes).To(Equal(int32(24)))
+				Expect(estimate.WorkerNodesMinutes).To(Equal(int32(24)))
+				Expect(estimate.WorkerBatches).To(Equal(int32(3)))
+				Expect(estimate.Samples).To(BeZero())
+				Expect(estimate.TotalMinutes()).To(Equal(int32(114)))
+			})
+
+			It("uses the configured durations", func() {
+				cfg := operatorconfig.DefaultConfig()
+				cfg.Estimate.ControlPlaneMinutes = 60
+				cfg.Estimate.NodeMinutes = 10
+				operatorconfig.Set(cfg, "", "")
+				defer operatorconfig.Reset()
+				estimate := Estimate(masters, workers, nil)
+				Expect(estimate.ControlPlaneMinutes).To(Equal(int32(60)))
+				Expect(estimate.MasterNodesMinutes).To(Equal(int32(30)))
+			})
+		})
+
+		Context("When there are past upgrades", func() {
+			It("learns the durations from them", func() {
+				start := time.Now().Add(-24 * time.Hour)
+				at := func(minutes int) *metav1.Time {
+					return &metav1.Time{Time: start.Add(time.Duration(minutes) * time.Minute)}
+				}
+				histories := upgradev1alpha1.UpgradeHistories{
+					{
+						Version: "4.4.1",
+						Phase:   upgradev1alpha1.UpgradePhaseUpgraded,
+						Conditions: upgradev1alpha1.Conditions{
+							{Type: upgradev1alpha1.CommenceUpgrade, CompleteTime: at(0)},
+							{Type: upgradev1alpha1.ControlPlaneUpgraded, CompleteTime: at(60)},
+							{Type: upgradev1alpha1.AllMasterNodesUpgraded, CompleteTime: at(90)},
+							{Type: upgradev1alpha1.AllWorkerNodesUpgraded, CompleteTime: at(120)},
+						},
+						Estimate: &upgradev1alpha1.UpgradeEstimate{MasterBatches: 3, WorkerBatches: 6},
+					},
+					{
+						Version: "4.4.2",
+						Phase:   upgradev1alpha1.UpgradePhaseFailed,
+					},
+				}
+				estimate := Estimate(masters, workers, histories)
+				Expect(estimate.Samples).To(Equal(int32(1)))
+				Expect(estimate.ControlPlaneMinutes).To(Equal(int32(60)))
+				Expect(estimate.MasterNodesMinutes).To(Equal(int32(30)))
+				Expect(estimate.WorkerNodesMinutes).To(Equal(int32(30)))
+			})
+
+			It("learns the control plane duration from the imported upgrades", func() {
+				start := time.Now().Add(-24 * time.Hour)
+				histories := upgradev1alpha1.UpgradeHistories{
+					{
+						Version:      "4.4.1",
+						Phase:        upgradev1alpha1.UpgradePhaseUpgraded,
+						Source:       upgradev1alpha1.UpgradeSourceImported,
+						StartTime:    &metav1.Time{Time: start},
+						CompleteTime: &metav1.Time{Time: start.Add(50 * time.Minute)},
+					},
+				}
+				estimate := Estimate(masters, workers, histories)
+				Expect(estimate.Samples).To(Equal(int32(1)))
+				Expect(estimate.ControlPlaneMinutes).To(Equal(int32(50)))
+				Expect(estimate.MasterNodesMinutes).To(Equal(int32(24)))
+			})
+		})
+	})
+})
@@ -0,0 +1,38 @@
+package gates
+
+import (
+	"time"
+
+	upgradev1alpha1 "github.com/openshift/managed-upgrade-operator/pkg/apis/upgrade/v1alpha1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Decision is the answer an approval gate gives for an upgrade step
+type Decision string
+
+const (
+	DecisionApprove    Decision = "approve"
+	DecisionDeny       Decision = "deny"
+	DecisionRetryAfter Decision = "retry-after"
+)
+
+// Result holds the decision of an approval gate
+type Result struct {
+	Decision   Decision
+	RetryAfter time.Duration
+	Reason     string
+}
+
+//go:generate mockgen -destination=mocks/gate.go -package=mocks github.com/openshift/managed-upgrade-operator/pkg/gates Gate
+type Gate interface {
+	Evaluate(gate upgradev1alpha1.UpgradeGate, upgradeConfig *upgradev1alpha1.UpgradeConfig) (*Result, error)
+}
+
+//go:generate mockgen -destination=mocks/gateBuilder.go -package=mocks github.com/openshift/managed-upgrade-operator/pkg/gates GateBuilder
+type GateBuilder interface {
+	NewClient(client client.Client) (Gate, error)
+}
+
+func NewBuilder() GateBuilder {
+	return &webhookGateBuilder{}
+}
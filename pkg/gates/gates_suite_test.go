@@ -0,0 +1,13 @@
+package gates
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestGates(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Gates Suite")
+}
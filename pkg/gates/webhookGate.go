@@ -0,0 +1,217 @@
+package gates
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	upgradev1alpha1 "github.com/openshift/managed-upgrade-operator/pkg/apis/upgrade/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// Header carrying the HMAC-SHA256 signature of the request
+	SignatureHeader = "X-Managed-Upgrade-Signature"
+	// Header carrying the unix timestamp included in the signature
+	TimestampHeader = "X-Managed-Upgrade-Timestamp"
+	// Key of the gate secret holding the HMAC key
+	SecretKey = "key"
+
+	defaultTimeout    = 30 * time.Second
+	defaultRetryAfter = 5 * time.Minute
+)
+
+var (
+	// The earliest time each gate may be asked again after it replied retry-after
+	retryMutex  sync.Mutex
+	retryAfters = map[string]time.Time{}
+)
+
+// GateRequest is the upgrade context POSTed to the gate endpoint
+type GateRequest struct {
+	UpgradeConfig string                               `json:"upgradeConfig"`
+	Gate          string                               `json:"gate"`
+	Step          upgradev1alpha1.UpgradeConditionType `json:"step"`
+	Version       string                               `json:"version"`
+	Channel       string                               `json:"channel"`
+	Phase         upgradev1alpha1.UpgradePhase         `json:"phase,omitempty"`
+	Conditions    upgradev1alpha1.Conditions           `json:"conditions,omitempty"`
+}
+
+// GateResponse is the body the gate endpoint replies with
+type GateResponse struct {
+	Decision          Decision `json:"decision"`
+	RetryAfterSeconds int64    `json:"retryAfterSeconds,omitempty"`
+	Reason            string   `json:"reason,omitempty"`
+}
+
+type webhookGateBuilder struct{}
+
+func (wgb *webhookGateBuilder) NewClient(c client.Client) (Gate, error) {
+	return &webhookGate{client: c}, nil
+}
+
+type webhookGate struct {
+	client client.Client
+}
+
+// Evaluate POSTs the upgrade context to the gate endpoint and returns its decision
+func (wg *webhookGate) Evaluate(gate upgradev1alpha1.UpgradeGate, upgradeConfig *upgradev1alpha1.UpgradeConfig) (*Result, error) {
+	retryKey := upgradeConfig.Name + "/" + gate.Name
+	if wait := retryRemaining(retryKey); wait > 0 {
+		return &Result{Decision: DecisionRetryAfter, RetryAfter: wait, Reason: "waiting for retry period requested by gate"}, nil
+	}
+
+	body, err := json.Marshal(newGateRequest(gate, upgradeConfig))
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, gate.URL, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	if gate.SecretRef != nil {
+		key, err := wg.getKey(gate.SecretRef)
+		if err != nil {
+			return nil, err
+		}
+		timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+		req.Header.Set(TimestampHeader, timestamp)
+		req.Header.Set(SignatureHeader, "sha256="+Sign(key, timestamp, body))
+	}
+
+	timeout := defaultTimeout
+	if gate.TimeoutSeconds > 0 {
+		timeout = time.Duration(gate.TimeoutSeconds) * time.Second
+	}
+	hclient := http.Client{Timeout: timeout}
+	resp, err := hclient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to call gate %s: %v", gate.Name, err)
+	}
+	defer resp.Body.Close()
+
+	result, err := parseResponse(resp)
+	if err != nil {
+		return nil, fmt.Errorf("invalid response from gate %s: %v", gate.Name, err)
+	}
+	if result.Decision == DecisionRetryAfter {
+		setRetry(retryKey, time.Now().Add(result.RetryAfter))
+	} else {
+		clearRetry(retryKey)
+	}
+	return result, nil
+}
+
+func (wg *webhookGate) getKey(ref *corev1.SecretReference) ([]byte, error) {
+	secret := &corev1.Secret{}
+	err := wg.client.Get(context.TODO(), types.NamespacedName{Namespace: ref.Namespace, Name: ref.Name}, secret)
+	if err != nil {
+		return nil, fmt.Errorf("unable to fetch gate secret %s/%s: %v", ref.Namespace, ref.Name, err)
+	}
+	key, ok := secret.Data[SecretKey]
+	if !ok || len(key) == 0 {
+		return nil, fmt.Errorf("gate secret %s/%s has no %s", ref.Namespace, ref.Name, SecretKey)
+	}
+	return key, nil
+}
+
+// Sign returns the hex encoded HMAC-SHA256 of the timestamp and body, joined by a '.'
+func Sign(key []byte, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func newGateRequest(gate upgradev1alpha1.UpgradeGate, upgradeConfig *upgradev1alpha1.UpgradeConfig) *GateRequest {
+	gr := &GateRequest{
+		UpgradeConfig: upgradeConfig.Name,
+		Gate:          gate.Name,
+		Step:          gate.Step,
+		Version:       upgradeConfig.Spec.Desired.Version,
+		Channel:       upgradeConfig.Spec.Desired.Channel,
+	}
+	history := upgradeConfig.Status.History.GetHistory(upgradeConfig.Spec.Desired.Version)
+	if history != nil {
+		gr.Phase = history.Phase
+		gr.Conditions = history.Conditions
+	}
+	return gr
+}
+
+// parseResponse reads the decision from the gate reply. A 429 or 503 with a
+// Retry-After header is treated as a retry-after decision.
+func parseResponse(resp *http.Response) (*Result, error) {
+	if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusServiceUnavailable {
+		return &Result{Decision: DecisionRetryAfter, RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After")), Reason: resp.Status}, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	gr := &GateResponse{}
+	err = json.Unmarshal(body, gr)
+	if err != nil {
+		return nil, err
+	}
+
+	switch gr.Decision {
+	case DecisionApprove, DecisionDeny:
+		return &Result{Decision: gr.Decision, Reason: gr.Reason}, nil
+	case DecisionRetryAfter:
+		retryAfter := time.Duration(gr.RetryAfterSeconds) * time.Second
+		if retryAfter <= 0 {
+			retryAfter = parseRetryAfter(resp.Header.Get("Retry-After"))
+		}
+		return &Result{Decision: DecisionRetryAfter, RetryAfter: retryAfter, Reason: gr.Reason}, nil
+	default:
+		return nil, fmt.Errorf("unknown decision %q", gr.Decision)
+	}
+}
+
+func parseRetryAfter(value string) time.Duration {
+	if seconds, err := strconv.ParseInt(value, 10, 64); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if t, err := http.ParseTime(value); err == nil && time.Until(t) > 0 {
+		return time.Until(t)
+	}
+	return defaultRetryAfter
+}
+
+func retryRemaining(key string) time.Duration {
+	retryMutex.Lock()
+	defer retryMutex.Unlock()
+	return time.Until(retryAfters[key])
+}
+
+func setRetry(key string, at time.Time) {
+	retryMutex.Lock()
+	defer retryMutex.Unlock()
+	retryAfters[key] = at
+}
+
+func clearRetry(key string) {
+	retryMutex.Lock()
+	defer retryMutex.Unlock()
+	delete(retryAfters, key)
+}
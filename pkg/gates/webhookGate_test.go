@@ -0,0 +1,147 @@
+package gates
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	upgradev1alpha1 "github.com/openshift/managed-upgrade-operator/pkg/apis/upgrade/v1alpha1"
+	"github.com/openshift/managed-upgrade-operator/util/mocks"
+	testStructs "github.com/openshift/managed-upgrade-operator/util/mocks/structs"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("WebhookGate", func() {
+	var (
+		mockCtrl       *gomock.Controller
+		mockKubeClient *mocks.MockClient
+		upgradeConfig  *upgradev1alpha1.UpgradeConfig
+		gate           upgradev1alpha1.UpgradeGate
+		server         *httptest.Server
+		handler        http.HandlerFunc
+		webhook        Gate
+	)
+
+	BeforeEach(func() {
+		mockCtrl = gomock.NewController(GinkgoT())
+		mockKubeClient = mocks.NewMockClient(mockCtrl)
+		upgradeConfig = testStructs.NewUpgradeConfigBuilder().GetUpgradeConfig()
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			handler(w, r)
+		}))
+		gate = upgradev1alpha1.UpgradeGate{
+			Name: "change-management",
+			Step: upgradev1alpha1.AllWorkerNodesUpgraded,
+			URL:  server.URL,
+		}
+		webhook, _ = NewBuilder().NewClient(mockKubeClient)
+		retryAfters = map[string]time.Time{}
+	})
+
+	AfterEach(func() {
+		server.Close()
+		mockCtrl.Finish()
+	})
+
+	reply := func(status int, resp GateResponse) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(status)
+			_ = json.NewEncoder(w).Encode(resp)
+		}
+	}
+
+	Context("When the gate approves", func() {
+		It("returns an approve decision", func() {
+			handler = reply(http.StatusOK, GateResponse{Decision: DecisionApprove, Reason: "CHG0001"})
+			result, err := webhook.Evaluate(gate, upgradeConfig)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.Decision).To(Equal(DecisionApprove))
+			Expect(result.Reason).To(Equal("CHG0001"))
+		})
+	})
+
+	Context("When the gate denies", func() {
+		It("returns a deny decision", func() {
+			handler = reply(http.StatusOK, GateResponse{Decision: DecisionDeny})
+			result, err := webhook.Evaluate(gate, upgradeConfig)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.Decision).To(Equal(DecisionDeny))
+		})
+	})
+
+	Context("When the gate asks to retry later", func() {
+		It("does not call the gate again before the retry period elapsed", func() {
+			calls := 0
+			handler = func(w http.ResponseWriter, r *http.Request) {
+				calls++
+				reply(http.StatusOK, GateResponse{Decision: DecisionRetryAfter, RetryAfterSeconds: 600})(w, r)
+			}
+			result, err := webhook.Evaluate(gate, upgradeConfig)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.Decision).To(Equal(DecisionRetryAfter))
+			Expect(result.RetryAfter).To(Equal(600 * time.Second))
+
+			result, err = webhook.Evaluate(gate, upgradeConfig)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.Decision).To(Equal(DecisionRetryAfter))
+			Expect(calls).To(Equal(1))
+		})
+
+		It("asks the gate again once it answered", func() {
+			retryAfters[upgradeConfig.Name+"/"+gate.Name] = time.Now().Add(-time.Second)
+			handler = reply(http.StatusOK, GateResponse{Decision: DecisionApprove})
+			_, err := webhook.Evaluate(gate, upgradeConfig)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(retryAfters).To(BeEmpty())
+		})
+
+		It("honours the Retry-After header of a 503", func() {
+			handler = func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Retry-After", "120")
+				w.WriteHeader(http.StatusServiceUnavailable)
+			}
+			result, err := webhook.Evaluate(gate, upgradeConfig)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.Decision).To(Equal(DecisionRetryAfter))
+			Expect(result.RetryAfter).To(Equal(120 * time.Second))
+		})
+	})
+
+	Context("When the gate replies with an unknown decision", func() {
+		It("returns an error", func() {
+			handler = reply(http.StatusOK, GateResponse{Decision: "maybe"})
+			_, err := webhook.Evaluate(gate, upgradeConfig)
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Context("When the gate has a signing secret", func() {
+		var key = []byte("sekret")
+		BeforeEach(func() {
+			gate.SecretRef = &corev1.SecretReference{Namespace: "test-namespace", Name: "gate-secret"}
+			mockKubeClient.EXPECT().Get(gomock.Any(), types.NamespacedName{Namespace: "test-namespace", Name: "gate-secret"}, gomock.Any()).SetArg(2, corev1.Secret{
+				Data: map[string][]byte{SecretKey: key},
+			}).Times(1)
+		})
+		It("signs the request body", func() {
+			var signature, expected string
+			handler = func(w http.ResponseWriter, r *http.Request) {
+				body, _ := ioutil.ReadAll(r.Body)
+				signature = r.Header.Get(SignatureHeader)
+				expected = "sha256=" + Sign(key, r.Header.Get(TimestampHeader), body)
+				reply(http.StatusOK, GateResponse{Decision: DecisionApprove})(w, r)
+			}
+			_, err := webhook.Evaluate(gate, upgradeConfig)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(signature).NotTo(BeEmpty())
+			Expect(signature).To(Equal(expected))
+		})
+	})
+})
@@ -0,0 +1,338 @@
+package healthcheck
+
+import (
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/golang/mock/gomock"
+	configv1 "github.com/openshift/api/config/v1"
+	machineconfigapi "github.com/openshift/machine-config-operator/pkg/apis/machineconfiguration.openshift.io/v1"
+	upgradev1alpha1 "github.com/openshift/managed-upgrade-operator/pkg/apis/upgrade/v1alpha1"
+	"github.com/openshift/managed-upgrade-operator/pkg/operatorconfig"
+	prometheusMocks "github.com/openshift/managed-upgrade-operator/pkg/prometheus/mocks"
+	"github.com/openshift/managed-upgrade-operator/util/mocks"
+	testStructs "github.com/openshift/managed-upgrade-operator/util/mocks/structs"
+	"github.com/prometheus/common/model"
+	certificatesv1beta1 "k8s.io/api/certificates/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Built-in health checks", func() {
+	var (
+		mockCtrl       *gomock.Controller
+		mockKubeClient *mocks.MockClient
+		logger         logr.Logger
+	)
+
+	BeforeEach(func() {
+		mockCtrl = gomock.NewController(GinkgoT())
+		mockKubeClient = mocks.NewMockClient(mockCtrl)
+		logger = logf.Log.WithName("healthcheck test logger")
+	})
+
+	AfterEach(func() {
+		mockCtrl.Finish()
+	})
+
+	Context("ClusterOperators", func() {
+		var (
+			operators     configv1.ClusterOperatorList
+			upgradeConfig *upgradev1alpha1.UpgradeConfig
+			longAgo       metav1.Time
+		)
+
+		BeforeEach(func() {
+			longAgo = metav1.NewTime(time.Date(2020, 6, 1, 10, 0, 0, 0, time.UTC))
+			operators = configv1.ClusterOperatorList{
+				Items: []configv1.ClusterOperator{
+					{ObjectMeta: metav1.ObjectMeta{Name: "dns"}, Status: configv1.ClusterOperatorStatus{Conditions: []configv1.ClusterOperatorStatusCondition{
+						{Type: configv1.OperatorAvailable, Status: configv1.ConditionTrue, LastTransitionTime: longAgo},
+						{Type: configv1.OperatorUpgradeable, Status: configv1.ConditionFalse, LastTransitionTime: longAgo, Message: "dns is not upgradeable"},
+					}}},
+					{ObjectMeta: metav1.ObjectMeta{Name: "ingress"}, Status: configv1.ClusterOperatorStatus{Conditions: []configv1.ClusterOperatorStatusCondition{
+						{Type: configv1.OperatorDegraded, Status: configv1.ConditionTrue, LastTransitionTime: longAgo, Message: "ingress controller is degraded"},
+						{Type: configv1.OperatorAvailable, Status: configv1.ConditionFalse, LastTransitionTime: longAgo, Message: "no ingress controller is available"},
+					}}},
+					{ObjectMeta: metav1.ObjectMeta{Name: "console"}, Status: configv1.ClusterOperatorStatus{Conditions: []configv1.ClusterOperatorStatusCondition{
+						{Type: configv1.OperatorAvailable, Status: configv1.ConditionFalse, LastTransitionTime: metav1.Now(), Message: "console is unreachable"},
+					}}},
+				},
+			}
+			upgradeConfig = testStructs.NewUpgradeConfigBuilder().GetUpgradeConfig()
+			upgradeConfig.Spec.Desired.Version = "4.5.2"
+		})
+
+		AfterEach(func() {
+			operatorconfig.Reset()
+		})
+
+		expectClusterVersion := func(current string) {
+			mockKubeClient.EXPECT().Get(gomock.Any(), gomock.Any(), gomock.Any()).SetArg(2, configv1.ClusterVersion{
+				Status: configv1.ClusterVersionStatus{History: []configv1.UpdateHistory{{State: configv1.CompletedUpdate, Version: current}}},
+			})
+		}
+
+		It("Fails on degraded and unavailable operators and reports every evaluated condition", func() {
+			mockKubeClient.EXPECT().List(gomock.Any(), gomock.Any()).SetArg(1, operators)
+			result, err := (&clusterOperators{}).Check(mockKubeClient, operatorconfig.PostUpgrade, upgradeConfig, logger)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.Status).To(Equal(StatusFail))
+			Expect(result.Message).To(Equal("failing operators: ingress,console"))
+			Expect(result.Evidence).To(Equal([]string{
+				"dns: Available=True since 2020-06-01T10:00:00Z (ok)",
+				"ingress: Degraded=True since 2020-06-01T10:00:00Z (failing): ingress controller is degraded",
+				"ingress: Available=False since 2020-06-01T10:00:00Z (failing): no ingress controller is available",
+				"console: Available=False since " + operators.Items[2].Status.Conditions[0].LastTransitionTime.UTC().Format(time.RFC3339) + " (failing): console is unreachable",
+			}))
+		})
+
+		It("Only warns on the conditions which changed within the stable period and on the ignored operators before the upgrade", func() {
+			cfg := operatorconfig.DefaultConfig()
+			cfg.HealthCheck.Operators.StableMinutes = 10
+			cfg.HealthCheck.Operators.IgnoredOperators.PreUpgrade = []string{"ingress"}
+			operatorconfig.Set(cfg, "test", "1")
+			mockKubeClient.EXPECT().List(gomock.Any(), gomock.Any()).SetArg(1, operators)
+			result, err := (&clusterOperators{}).Check(mockKubeClient, operatorconfig.PreUpgrade, nil, logger)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.Status).To(Equal(StatusWarn))
+			Expect(result.Message).To(Equal("operators ignored or not stable yet: ingress,console"))
+			Expect(result.Evidence).To(ContainElement(ContainSubstring("ingress: Degraded=True since 2020-06-01T10:00:00Z (ignored)")))
+			Expect(result.Evidence).To(ContainElement(ContainSubstring("console: Available=False since")))
+			Expect(result.Evidence).To(ContainElement(ContainSubstring("(not stable yet): console is unreachable")))
+		})
+
+		It("Fails on the conditions which changed within the stable period after the upgrade", func() {
+			cfg := operatorconfig.DefaultConfig()
+			cfg.HealthCheck.Operators.StableMinutes = 10
+			cfg.HealthCheck.Operators.IgnoredOperators.PostUpgrade = []string{"ingress"}
+			operatorconfig.Set(cfg, "test", "1")
+			mockKubeClient.EXPECT().List(gomock.Any(), gomock.Any()).SetArg(1, operators)
+			result, err := (&clusterOperators{}).Check(mockKubeClient, operatorconfig.PostUpgrade, upgradeConfig, logger)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.Status).To(Equal(StatusFail))
+			Expect(result.Message).To(Equal("failing operators: console"))
+			Expect(result.Evidence).To(ContainElement(ContainSubstring("(failing): console is unreachable")))
+		})
+
+		It("Fails on the operators which recovered within the stable period after the upgrade", func() {
+			cfg := operatorconfig.DefaultConfig()
+			cfg.HealthCheck.Operators.StableMinutes = 10
+			operatorconfig.Set(cfg, "test", "1")
+			operators.Items = operators.Items[2:]
+			operators.Items[0].Status.Conditions[0].Status = configv1.ConditionTrue
+			mockKubeClient.EXPECT().List(gomock.Any(), gomock.Any()).SetArg(1, operators)
+			result, err := (&clusterOperators{}).Check(mockKubeClient, operatorconfig.PostUpgrade, upgradeConfig, logger)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.Status).To(Equal(StatusFail))
+			Expect(result.Message).To(Equal("failing operators: console"))
+			Expect(result.Evidence).To(ContainElement(ContainSubstring("console: Available=True since")))
+			Expect(result.Evidence).To(ContainElement(ContainSubstring("(not stable yet)")))
+		})
+
+		It("Fails on the operators which are not upgradeable before a minor version upgrade", func() {
+			operators.Items = operators.Items[:1]
+			expectClusterVersion("4.4.11")
+			mockKubeClient.EXPECT().List(gomock.Any(), gomock.Any()).SetArg(1, operators)
+			result, err := (&clusterOperators{}).Check(mockKubeClient, operatorconfig.PreUpgrade, upgradeConfig, logger)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.Status).To(Equal(StatusFail))
+			Expect(result.Message).To(Equal("failing operators: dns"))
+			Expect(result.Evidence).To(ContainElement("dns: Upgradeable=False since 2020-06-01T10:00:00Z (failing): dns is not upgradeable"))
+		})
+
+		It("Ignores Upgradeable before a patch version upgrade", func() {
+			operators.Items = operators.Items[:1]
+			expectClusterVersion("4.5.1")
+			mockKubeClient.EXPECT().List(gomock.Any(), gomock.Any()).SetArg(1, operators)
+			result, err := (&clusterOperators{}).Check(mockKubeClient, operatorconfig.PreUpgrade, upgradeConfig, logger)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.Status).To(Equal(StatusPass))
+			Expect(result.Evidence).To(Equal([]string{"dns: Available=True since 2020-06-01T10:00:00Z (ok)"}))
+		})
+	})
+
+	Context("NodesReady", func() {
+		It("Fails on the nodes which are not ready", func() {
+			mockKubeClient.EXPECT().List(gomock.Any(), gomock.Any()).SetArg(1, corev1.NodeList{
+				Items: []corev1.Node{
+					{ObjectMeta: metav1.ObjectMeta{Name: "ready"}, Status: corev1.NodeStatus{Conditions: []corev1.NodeCondition{
+						{Type: corev1.NodeReady, Status: corev1.ConditionTrue},
+					}}},
+					{ObjectMeta: metav1.ObjectMeta{Name: "not-ready"}, Status: corev1.NodeStatus{Conditions: []corev1.NodeCondition{
+						{Type: corev1.NodeReady, Status: corev1.ConditionFalse, Message: "kubelet stopped posting node status"},
+					}}},
+					{ObjectMeta: metav1.ObjectMeta{Name: "unknown"}},
+				},
+			})
+			result, err := (&nodesReady{}).Check(mockKubeClient, operatorconfig.PreUpgrade, nil, logger)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.Status).To(Equal(StatusFail))
+			Expect(result.Message).To(Equal("nodes not ready: not-ready,unknown"))
+			Expect(result.Evidence).To(Equal([]string{
+				"not-ready: Ready=False: kubelet stopped posting node status",
+				"unknown: no Ready condition",
+			}))
+		})
+	})
+
+	Context("PendingCSRs", func() {
+		It("Fails on the CSRs pending for longer than the grace period", func() {
+			old := metav1.NewTime(time.Now().Add(-time.Hour))
+			recent := metav1.NewTime(time.Now())
+			mockKubeClient.EXPECT().List(gomock.Any(), gomock.Any()).SetArg(1, certificatesv1beta1.CertificateSigningRequestList{
+				Items: []certificatesv1beta1.CertificateSigningRequest{
+					{ObjectMeta: metav1.ObjectMeta{Name: "approved", CreationTimestamp: old}, Status: certificatesv1beta1.CertificateSigningRequestStatus{
+						Conditions: []certificatesv1beta1.CertificateSigningRequestCondition{{Type: certificatesv1beta1.CertificateApproved}},
+					}},
+					{ObjectMeta: metav1.ObjectMeta{Name: "pending", CreationTimestamp: old}},
+					{ObjectMeta: metav1.ObjectMeta{Name: "just-created", CreationTimestamp: recent}},
+				},
+			})
+			result, err := (&pendingCSRs{}).Check(mockKubeClient, operatorconfig.PreUpgrade, nil, logger)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.Status).To(Equal(StatusFail))
+			Expect(result.Message).To(HaveSuffix(": pending"))
+		})
+	})
+
+	Context("EtcdMembers", func() {
+		var (
+			pods                  corev1.PodList
+			hasLeader             model.Vector
+			mockPrometheusBuilder *prometheusMocks.MockPrometheusClientBuilder
+			mockPrometheusClient  *prometheusMocks.MockPrometheusClient
+		)
+
+		BeforeEach(func() {
+			mockKubeClient.EXPECT().List(gomock.Any(), gomock.AssignableToTypeOf(&corev1.NodeList{}), gomock.Any()).SetArg(1, corev1.NodeList{
+				Items: []corev1.Node{
+					{ObjectMeta: metav1.ObjectMeta{Name: "master-0"}},
+					{ObjectMeta: metav1.ObjectMeta{Name: "master-1"}},
+				},
+			})
+			pods = corev1.PodList{
+				Items: []corev1.Pod{
+					{ObjectMeta: metav1.ObjectMeta{Name: "etcd-master-0"}, Spec: corev1.PodSpec{NodeName: "master-0"}, Status: corev1.PodStatus{Conditions: []corev1.PodCondition{
+						{Type: corev1.PodReady, Status: corev1.ConditionTrue},
+					}}},
+					{ObjectMeta: metav1.ObjectMeta{Name: "etcd-master-1"}, Spec: corev1.PodSpec{NodeName: "master-1"}, Status: corev1.PodStatus{Conditions: []corev1.PodCondition{
+						{Type: corev1.PodReady, Status: corev1.ConditionTrue},
+					}}},
+				},
+			}
+			hasLeader = model.Vector{
+				{Metric: model.Metric{"pod": "etcd-master-0"}, Value: 1},
+				{Metric: model.Metric{"pod": "etcd-master-1"}, Value: 1},
+			}
+			mockPrometheusBuilder = prometheusMocks.NewMockPrometheusClientBuilder(mockCtrl)
+			mockPrometheusClient = prometheusMocks.NewMockPrometheusClient(mockCtrl)
+			mockPrometheusBuilder.EXPECT().NewClient().Return(mockPrometheusClient, nil)
+		})
+
+		check := func() (*Result, error) {
+			mockKubeClient.EXPECT().List(gomock.Any(), gomock.AssignableToTypeOf(&corev1.PodList{}), gomock.Any(), gomock.Any()).SetArg(1, pods)
+			mockPrometheusClient.EXPECT().Query(etcdHasLeaderQuery, time.Time{}).Return(hasLeader, nil)
+			return (&etcdMembers{prometheusBuilder: mockPrometheusBuilder}).Check(mockKubeClient, operatorconfig.PreUpgrade, nil, logger)
+		}
+
+		It("Passes when every master runs a ready member which has a leader", func() {
+			result, err := check()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.Status).To(Equal(StatusPass))
+			Expect(result.Evidence).To(Equal([]string{"query: " + etcdHasLeaderQuery}))
+		})
+
+		It("Fails when a member is not ready", func() {
+			pods.Items[1].Status.Conditions[0].Status = corev1.ConditionFalse
+			result, err := check()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.Status).To(Equal(StatusFail))
+			Expect(result.Message).To(Equal("1 of 2 etcd members are not healthy, on masters master-1"))
+			Expect(result.Evidence).To(ContainElement("master-1: no ready etcd member"))
+		})
+
+		It("Fails when a ready member has no leader", func() {
+			hasLeader[0].Value = 0
+			result, err := check()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.Status).To(Equal(StatusFail))
+			Expect(result.Message).To(Equal("1 of 2 etcd members are not healthy, on masters master-0"))
+			Expect(result.Evidence).To(ContainElement("master-0: etcd member etcd-master-0 has no leader"))
+		})
+
+		It("Fails when a ready member does not report whether it has a leader", func() {
+			hasLeader = hasLeader[:1]
+			result, err := check()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.Status).To(Equal(StatusFail))
+			Expect(result.Message).To(Equal("1 of 2 etcd members are not healthy, on masters master-1"))
+		})
+	})
+
+	Context("CriticalAlerts", func() {
+		var (
+			mockPrometheusBuilder *prometheusMocks.MockPrometheusClientBuilder
+			mockPrometheusClient  *prometheusMocks.MockPrometheusClient
+		)
+
+		BeforeEach(func() {
+			mockPrometheusBuilder = prometheusMocks.NewMockPrometheusClientBuilder(mockCtrl)
+			mockPrometheusClient = prometheusMocks.NewMockPrometheusClient(mockCtrl)
+			mockPrometheusBuilder.EXPECT().NewClient().Return(mockPrometheusClient, nil)
+		})
+
+		It("Fails on the firing alerts and describes them with their labels", func() {
+			query := operatorconfig.Get().HealthCheck.Alerts.Query(operatorconfig.PreUpgrade)
+			mockPrometheusClient.EXPECT().Query(query, time.Time{}).Return(model.Vector{
+				{Metric: model.Metric{
+					"__name__":   "ALERTS",
+					"alertname":  "KubePodNotReady",
+					"alertstate": "firing",
+					"severity":   "critical",
+					"namespace":  "openshift-dns",
+					"pod":        "dns-1",
+				}},
+			}, nil)
+			result, err := (&criticalAlerts{prometheusBuilder: mockPrometheusBuilder}).Check(mockKubeClient, operatorconfig.PreUpgrade, nil, logger)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.Status).To(Equal(StatusFail))
+			Expect(result.Message).To(Equal("there are 1 critical alerts: KubePodNotReady"))
+			Expect(result.Evidence).To(Equal([]string{
+				"query: " + query,
+				`KubePodNotReady{namespace="openshift-dns",pod="dns-1",severity="critical"}`,
+			}))
+		})
+
+		It("Passes when no alert is firing", func() {
+			mockPrometheusClient.EXPECT().Query(gomock.Any(), gomock.Any()).Return(model.Vector{}, nil)
+			result, err := (&criticalAlerts{prometheusBuilder: mockPrometheusBuilder}).Check(mockKubeClient, operatorconfig.PostUpgrade, nil, logger)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.Status).To(Equal(StatusPass))
+		})
+	})
+
+	Context("MachineConfigPools", func() {
+		It("Fails on the degraded pools", func() {
+			mockKubeClient.EXPECT().List(gomock.Any(), gomock.Any()).SetArg(1, machineconfigapi.MachineConfigPoolList{
+				Items: []machineconfigapi.MachineConfigPool{
+					{ObjectMeta: metav1.ObjectMeta{Name: "master"}},
+					{ObjectMeta: metav1.ObjectMeta{Name: "worker"}, Status: machineconfigapi.MachineConfigPoolStatus{
+						Conditions: []machineconfigapi.MachineConfigPoolCondition{
+							{Type: machineconfigapi.MachineConfigPoolDegraded, Status: corev1.ConditionTrue, Reason: "NodeDegraded", Message: "1 node is reporting degraded status"},
+						},
+					}},
+				},
+			})
+			result, err := (&machineConfigPools{}).Check(mockKubeClient, operatorconfig.PreUpgrade, nil, logger)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.Status).To(Equal(StatusFail))
+			Expect(result.Message).To(Equal("degraded machineconfigpools: worker"))
+			Expect(result.Evidence).To(Equal([]string{"worker: NodeDegraded: 1 node is reporting degraded status"}))
+		})
+	})
+})
@@ -0,0 +1,151 @@
+package healthcheck
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/blang/semver"
+	"github.com/go-logr/logr"
+	configv1 "github.com/openshift/api/config/v1"
+	upgradev1alpha1 "github.com/openshift/managed-upgrade-operator/pkg/apis/upgrade/v1alpha1"
+	"github.com/openshift/managed-upgrade-operator/pkg/operatorconfig"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// clusterOperators fails if any ClusterOperator is degraded or unavailable, or not upgradeable before a minor version upgrade.
+// The conditions of the ignored operators only warn. Before the upgrade, the failing conditions which changed within
+// the stable period only warn too. After the upgrade, the healthy conditions must have been stable for the period.
+type clusterOperators struct{}
+
+func (co *clusterOperators) Name() string {
+	return ClusterOperators
+}
+
+func (co *clusterOperators) Check(c client.Client, phase operatorconfig.HealthCheckPhase, upgradeConfig *upgradev1alpha1.UpgradeConfig, logger logr.Logger) (*Result, error) {
+	cfg := operatorconfig.Get()
+	// The failing status of each evaluated condition
+	failingStatus := map[configv1.ClusterStatusConditionType]configv1.ConditionStatus{
+		configv1.OperatorAvailable: configv1.ConditionFalse,
+		configv1.OperatorDegraded:  configv1.ConditionTrue,
+	}
+	if phase == operatorconfig.PreUpgrade && upgradeConfig != nil {
+		minor, err := isMinorUpgrade(c, upgradeConfig.Spec.Desired.Version)
+		if err != nil {
+			return nil, err
+		}
+		if minor {
+			failingStatus[configv1.OperatorUpgradeable] = configv1.ConditionFalse
+		}
+	}
+	ignored := map[string]bool{}
+	for _, name := range cfg.HealthCheck.Operators.IgnoredOperators.Of(phase) {
+		ignored[name] = true
+	}
+	stablePeriod := cfg.OperatorStablePeriod()
+
+	operatorList := &configv1.ClusterOperatorList{}
+	err := c.List(context.TODO(), operatorList)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	failingOperators := []string{}
+	warningOperators := []string{}
+	evidence := []string{}
+	for _, co := range operatorList.Items {
+		failing := false
+		warning := false
+		for _, condition := range co.Status.Conditions {
+			status, evaluated := failingStatus[condition.Type]
+			if !evaluated {
+				continue
+			}
+			verdict := "ok"
+			healthy := condition.Status != status
+			recent := now.Sub(condition.LastTransitionTime.Time) < stablePeriod
+			switch {
+			case healthy && (phase == operatorconfig.PreUpgrade || !recent):
+				// ok
+			case ignored[co.Name]:
+				verdict = "ignored"
+				warning = true
+			case healthy:
+				// The operator may still be settling after the upgrade
+				verdict = "not stable yet"
+				failing = true
+			case recent && phase == operatorconfig.PreUpgrade:
+				verdict = "not stable yet"
+				warning = true
+			default:
+				verdict = "failing"
+				failing = true
+			}
+			evidence = append(evidence, describeCondition(co.Name, condition, verdict))
+		}
+		if failing {
+			failingOperators = append(failingOperators, co.Name)
+		} else if warning {
+			warningOperators = append(warningOperators, co.Name)
+		}
+	}
+
+	if len(failingOperators) > 0 {
+		return fail(evidence, "failing operators: %s", strings.Join(failingOperators, ",")), nil
+	}
+	if len(warningOperators) > 0 {
+		return &Result{
+			Status:   StatusWarn,
+			Message:  fmt.Sprintf("operators ignored or not stable yet: %s", strings.Join(warningOperators, ",")),
+			Evidence: evidence,
+		}, nil
+	}
+	result := pass("all %d operators are healthy", len(operatorList.Items))
+	result.Evidence = evidence
+	return result, nil
+}
+
+// describeCondition describes the condition of the operator and its verdict,
+// like ingress: Degraded=True since 2020-06-01T10:00:00Z (failing): ingress controller is degraded
+func describeCondition(operator string, condition configv1.ClusterOperatorStatusCondition, verdict string) string {
+	description := fmt.Sprintf("%s: %s=%s", operator, condition.Type, condition.Status)
+	if !condition.LastTransitionTime.IsZero() {
+		description += " since " + condition.LastTransitionTime.UTC().Format(time.RFC3339)
+	}
+	description += fmt.Sprintf(" (%s)", verdict)
+	if len(condition.Message) > 0 {
+		description += ": " + condition.Message
+	}
+	return description
+}
+
+// isMinorUpgrade returns true if the version is another minor version than the current version of the cluster
+func isMinorUpgrade(c client.Client, version string) (bool, error) {
+	clusterVersion := &configv1.ClusterVersion{}
+	err := c.Get(context.TODO(), types.NamespacedName{Name: "version"}, clusterVersion)
+	if err != nil {
+		return false, err
+	}
+	current := ""
+	for _, history := range clusterVersion.Status.History {
+		if history.State == configv1.CompletedUpdate {
+			current = history.Version
+			break
+		}
+	}
+	if len(current) == 0 {
+		return false, fmt.Errorf("unable to find the current version of the cluster")
+	}
+	from, err := semver.Parse(current)
+	if err != nil {
+		return false, err
+	}
+	to, err := semver.Parse(version)
+	if err != nil {
+		return false, err
+	}
+	return from.Major != to.Major || from.Minor != to.Minor, nil
+}
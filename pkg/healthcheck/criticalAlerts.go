@@ -0,0 +1,76 @@
+package healthcheck
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+	upgradev1alpha1 "github.com/openshift/managed-upgrade-operator/pkg/apis/upgrade/v1alpha1"
+	"github.com/openshift/managed-upgrade-operator/pkg/operatorconfig"
+	"github.com/openshift/managed-upgrade-operator/pkg/prometheus"
+	"github.com/prometheus/common/model"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// criticalAlerts fails while alerts are firing, they are filtered as configured in the health check configuration
+type criticalAlerts struct {
+	prometheusBuilder prometheus.PrometheusClientBuilder
+}
+
+func (ca *criticalAlerts) Name() string {
+	return CriticalAlerts
+}
+
+func (ca *criticalAlerts) Check(c client.Client, phase operatorconfig.HealthCheckPhase, upgradeConfig *upgradev1alpha1.UpgradeConfig, logger logr.Logger) (*Result, error) {
+	promClient, err := ca.prometheusBuilder.NewClient()
+	if err != nil {
+		return nil, err
+	}
+	query := operatorconfig.Get().HealthCheck.Alerts.Query(phase)
+	alerts, err := promClient.Query(query, time.Time{})
+	if err != nil {
+		return nil, err
+	}
+
+	// The query comes first so that the results can be reproduced
+	evidence := []string{"query: " + query}
+	if len(alerts) > 0 {
+		for _, sample := range alerts {
+			evidence = append(evidence, describeAlert(sample.Metric))
+		}
+		return fail(evidence, "there are %d critical alerts: %s", len(alerts), strings.Join(alertNames(alerts), ",")), nil
+	}
+	result := pass("no critical alert is firing")
+	result.Evidence = evidence
+	return result, nil
+}
+
+// describeAlert returns the alert name followed by its labels, like KubePodNotReady{namespace="openshift-dns",pod="dns-1"}
+func describeAlert(metric model.Metric) string {
+	labels := []string{}
+	for name, value := range metric {
+		if name == model.MetricNameLabel || name == model.AlertNameLabel || name == "alertstate" {
+			continue
+		}
+		labels = append(labels, fmt.Sprintf("%s=%q", name, value))
+	}
+	sort.Strings(labels)
+	return fmt.Sprintf("%s{%s}", metric[model.AlertNameLabel], strings.Join(labels, ","))
+}
+
+// alertNames returns the distinct names of the alerts, sorted
+func alertNames(alerts model.Vector) []string {
+	seen := map[string]bool{}
+	names := []string{}
+	for _, s := range alerts {
+		name := string(s.Metric[model.AlertNameLabel])
+		if !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,46 @@
+package healthcheck
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+	upgradev1alpha1 "github.com/openshift/managed-upgrade-operator/pkg/apis/upgrade/v1alpha1"
+	"github.com/openshift/managed-upgrade-operator/pkg/operatorconfig"
+	certificatesv1beta1 "k8s.io/api/certificates/v1beta1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// CSRs are usually approved within seconds, only the ones pending for longer are a problem
+const csrGracePeriod = 5 * time.Minute
+
+// pendingCSRs fails if CertificateSigningRequests are neither approved nor denied after the grace period,
+// the nodes they are for can not join or renew their certificates
+type pendingCSRs struct{}
+
+func (pc *pendingCSRs) Name() string {
+	return PendingCSRs
+}
+
+func (pc *pendingCSRs) Check(c client.Client, phase operatorconfig.HealthCheckPhase, upgradeConfig *upgradev1alpha1.UpgradeConfig, logger logr.Logger) (*Result, error) {
+	csrList := &certificatesv1beta1.CertificateSigningRequestList{}
+	err := c.List(context.TODO(), csrList)
+	if err != nil {
+		return nil, err
+	}
+
+	pending := []string{}
+	evidence := []string{}
+	for _, csr := range csrList.Items {
+		if len(csr.Status.Conditions) == 0 && time.Since(csr.CreationTimestamp.Time) > csrGracePeriod {
+			pending = append(pending, csr.Name)
+			evidence = append(evidence, fmt.Sprintf("%s: requested by %s, pending since %s", csr.Name, csr.Spec.Username, csr.CreationTimestamp.UTC().Format(time.RFC3339)))
+		}
+	}
+	if len(pending) > 0 {
+		return fail(evidence, "certificate signing requests pending for more than %s: %s", csrGracePeriod, strings.Join(pending, ",")), nil
+	}
+	return pass("no certificate signing request is pending"), nil
+}
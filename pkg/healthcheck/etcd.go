@@ -0,0 +1,95 @@
+package healthcheck
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"time"
+
+	"github.com/go-logr/logr"
+	upgradev1alpha1 "github.com/openshift/managed-upgrade-operator/pkg/apis/upgrade/v1alpha1"
+	"github.com/openshift/managed-upgrade-operator/pkg/operatorconfig"
+	"github.com/openshift/managed-upgrade-operator/pkg/prometheus"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	etcdNamespace = "openshift-etcd"
+	masterLabel   = "node-role.kubernetes.io/master"
+	// Reported by every etcd member, by pod, 1 while the member sees a leader
+	etcdHasLeaderQuery = `etcd_server_has_leader{namespace="openshift-etcd"}`
+)
+
+// etcdMembers fails unless every master runs a ready etcd member which sees a leader
+type etcdMembers struct {
+	prometheusBuilder prometheus.PrometheusClientBuilder
+}
+
+func (em *etcdMembers) Name() string {
+	return EtcdMembers
+}
+
+func (em *etcdMembers) Check(c client.Client, phase operatorconfig.HealthCheckPhase, upgradeConfig *upgradev1alpha1.UpgradeConfig, logger logr.Logger) (*Result, error) {
+	masters := &corev1.NodeList{}
+	err := c.List(context.TODO(), masters, client.HasLabels{masterLabel})
+	if err != nil {
+		return nil, err
+	}
+	pods := &corev1.PodList{}
+	err = c.List(context.TODO(), pods, client.InNamespace(etcdNamespace), client.MatchingLabels{"k8s-app": "etcd"})
+	if err != nil {
+		return nil, err
+	}
+
+	promClient, err := em.prometheusBuilder.NewClient()
+	if err != nil {
+		return nil, err
+	}
+	samples, err := promClient.Query(etcdHasLeaderQuery, time.Time{})
+	if err != nil {
+		return nil, err
+	}
+	hasLeader := map[string]bool{}
+	for _, sample := range samples {
+		hasLeader[string(sample.Metric["pod"])] = sample.Value == 1
+	}
+
+	ready := map[string]string{}
+	for _, pod := range pods.Items {
+		if isPodReady(&pod) {
+			ready[pod.Spec.NodeName] = pod.Name
+		}
+	}
+	unhealthy := []string{}
+	// The query comes first so that the results can be reproduced
+	evidence := []string{"query: " + etcdHasLeaderQuery}
+	for _, master := range masters.Items {
+		member, found := ready[master.Name]
+		switch {
+		case !found:
+			evidence = append(evidence, fmt.Sprintf("%s: no ready etcd member", master.Name))
+		case !hasLeader[member]:
+			evidence = append(evidence, fmt.Sprintf("%s: etcd member %s has no leader", master.Name, member))
+		default:
+			continue
+		}
+		unhealthy = append(unhealthy, master.Name)
+	}
+	if len(unhealthy) > 0 {
+		return fail(evidence, "%d of %d etcd members are not healthy, on masters %s", len(unhealthy), len(masters.Items), strings.Join(unhealthy, ",")), nil
+	}
+	result := pass("all %d etcd members are ready and have a leader", len(masters.Items))
+	result.Evidence = evidence
+	return result, nil
+}
+
+func isPodReady(pod *corev1.Pod) bool {
+	for _, condition := range pod.Status.Conditions {
+		if condition.Type == corev1.PodReady {
+			return condition.Status == corev1.ConditionTrue
+		}
+	}
+	return false
+}
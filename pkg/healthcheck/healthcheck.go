@@ -0,0 +1,203 @@
+package healthcheck
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+	upgradev1alpha1 "github.com/openshift/managed-upgrade-operator/pkg/apis/upgrade/v1alpha1"
+	"github.com/openshift/managed-upgrade-operator/pkg/operatorconfig"
+	"github.com/openshift/managed-upgrade-operator/pkg/prometheus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Status is the outcome of a health check
+type Status string
+
+const (
+	StatusPass Status = "Pass"
+	StatusFail Status = "Fail"
+	// The health check found a problem which does not block the upgrade
+	StatusWarn Status = "Warn"
+)
+
+// Names of the built-in health checks, as used in the operator configuration
+const (
+	CriticalAlerts     = "CriticalAlerts"
+	ClusterOperators   = "ClusterOperators"
+	NodesReady         = "NodesReady"
+	PendingCSRs        = "PendingCSRs"
+	EtcdMembers        = "EtcdMembers"
+	MachineConfigPools = "MachineConfigPools"
+)
+
+// Result is the outcome of a health check and what it is based on
+type Result struct {
+	// Name of the health check
+	Name    string
+	Status  Status
+	Message string
+	// What the result is based on, like the firing alerts or the degraded operators
+	Evidence []string
+	// When the health check was performed
+	CheckedTime time.Time
+}
+
+//go:generate mockgen -destination=mocks/healthCheck.go -package=mocks github.com/openshift/managed-upgrade-operator/pkg/healthcheck HealthCheck
+
+// HealthCheck checks one aspect of the cluster health
+type HealthCheck interface {
+	// Name identifies the health check in the operator configuration and in the results
+	Name() string
+	// Check checks the cluster before or after the upgrade. An error means the check could not be performed.
+	Check(c client.Client, phase operatorconfig.HealthCheckPhase, upgradeConfig *upgradev1alpha1.UpgradeConfig, logger logr.Logger) (*Result, error)
+}
+
+// The registered health checks, by name
+var registry = map[string]HealthCheck{}
+
+func init() {
+	// The health checks querying Prometheus share its connections
+	prometheusBuilder := prometheus.NewBuilder()
+	Register(&criticalAlerts{prometheusBuilder: prometheusBuilder})
+	Register(&clusterOperators{})
+	Register(&nodesReady{})
+	Register(&pendingCSRs{})
+	Register(&etcdMembers{prometheusBuilder: prometheusBuilder})
+	Register(&machineConfigPools{})
+}
+
+// Register makes the health check available to the operator configuration, it must be called before the manager starts
+func Register(hc HealthCheck) {
+	if _, found := registry[hc.Name()]; found {
+		panic(fmt.Sprintf("health check %s is already registered", hc.Name()))
+	}
+	registry[hc.Name()] = hc
+}
+
+// Registered returns the names of the registered health checks, sorted
+func Registered() []string {
+	names := []string{}
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Validate returns an error if any of the names is not a registered health check
+func Validate(names []string) error {
+	unknown := []string{}
+	for _, name := range names {
+		if _, found := registry[name]; !found {
+			unknown = append(unknown, name)
+		}
+	}
+	if len(unknown) > 0 {
+		return fmt.Errorf("unknown health checks %s, the health checks are %s", strings.Join(unknown, ","), strings.Join(Registered(), ","))
+	}
+	return nil
+}
+
+// Run performs all the health checks configured for the phase, in order, and returns their results.
+// A health check which is unknown or could not be performed fails, the failures of the health checks
+// configured as warnOnly are downgraded to warnings.
+func Run(c client.Client, phase operatorconfig.HealthCheckPhase, upgradeConfig *upgradev1alpha1.UpgradeConfig, logger logr.Logger) []Result {
+	cfg := operatorconfig.Get()
+	warnOnly := map[string]bool{}
+	for _, name := range cfg.HealthCheck.WarnOnly {
+		warnOnly[name] = true
+	}
+	results := []Result{}
+	for _, name := range cfg.HealthChecks(phase) {
+		result := check(c, name, phase, upgradeConfig, logger)
+		if result.Status == StatusFail && warnOnly[name] {
+			result.Status = StatusWarn
+		}
+		result.CheckedTime = time.Now()
+		logger.Info(fmt.Sprintf("health check %s: %s %s", result.Name, result.Status, result.Message), "evidence", result.Evidence)
+		results = append(results, result)
+	}
+	return results
+}
+
+func check(c client.Client, name string, phase operatorconfig.HealthCheckPhase, upgradeConfig *upgradev1alpha1.UpgradeConfig, logger logr.Logger) Result {
+	hc, found := registry[name]
+	if !found {
+		return Result{Name: name, Status: StatusFail, Message: "unknown health check"}
+	}
+	result, err := hc.Check(c, phase, upgradeConfig, logger)
+	if err != nil {
+		return Result{Name: name, Status: StatusFail, Message: fmt.Sprintf("unable to perform the health check: %v", err)}
+	}
+	result.Name = name
+	return *result
+}
+
+// Failed returns the results of the health checks which failed
+func Failed(results []Result) []Result {
+	return withStatus(results, StatusFail)
+}
+
+// Warned returns the results of the health checks which warned
+func Warned(results []Result) []Result {
+	return withStatus(results, StatusWarn)
+}
+
+func withStatus(results []Result, status Status) []Result {
+	matching := []Result{}
+	for _, r := range results {
+		if r.Status == status {
+			matching = append(matching, r)
+		}
+	}
+	return matching
+}
+
+// Summary describes the failed and warned health checks in one line
+func Summary(results []Result) string {
+	failed := Failed(results)
+	warned := Warned(results)
+	summary := fmt.Sprintf("all %d health checks passed", len(results))
+	if len(failed) > 0 {
+		summary = fmt.Sprintf("%d of %d health checks failed: %s", len(failed), len(results), describe(failed))
+	}
+	if len(warned) > 0 {
+		summary = fmt.Sprintf("%s, %d warned: %s", summary, len(warned), describe(warned))
+	}
+	return summary
+}
+
+func describe(results []Result) string {
+	messages := []string{}
+	for _, r := range results {
+		messages = append(messages, fmt.Sprintf("%s: %s", r.Name, r.Message))
+	}
+	return strings.Join(messages, "; ")
+}
+
+// PreflightResults converts the results for the UpgradeConfig status
+func PreflightResults(results []Result) []upgradev1alpha1.PreflightResult {
+	preflight := []upgradev1alpha1.PreflightResult{}
+	for _, r := range results {
+		preflight = append(preflight, upgradev1alpha1.PreflightResult{
+			Name:        r.Name,
+			Result:      upgradev1alpha1.PreflightStatus(r.Status),
+			Message:     r.Message,
+			Evidence:    append([]string(nil), r.Evidence...),
+			CheckedTime: metav1.NewTime(r.CheckedTime),
+		})
+	}
+	return preflight
+}
+
+func pass(format string, a ...interface{}) *Result {
+	return &Result{Status: StatusPass, Message: fmt.Sprintf(format, a...)}
+}
+
+func fail(evidence []string, format string, a ...interface{}) *Result {
+	return &Result{Status: StatusFail, Message: fmt.Sprintf(format, a...), Evidence: evidence}
+}
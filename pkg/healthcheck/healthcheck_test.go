@@ -0,0 +1,152 @@
+package healthcheck
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/go-logr/logr"
+	upgradev1alpha1 "github.com/openshift/managed-upgrade-operator/pkg/apis/upgrade/v1alpha1"
+	"github.com/openshift/managed-upgrade-operator/pkg/operatorconfig"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// fakeCheck returns a canned result, it is registered directly to keep the tests independent
+type fakeCheck struct {
+	name   string
+	result *Result
+	err    error
+	calls  int
+	phase  operatorconfig.HealthCheckPhase
+}
+
+func (f *fakeCheck) Name() string {
+	return f.name
+}
+
+func (f *fakeCheck) Check(c client.Client, phase operatorconfig.HealthCheckPhase, upgradeConfig *upgradev1alpha1.UpgradeConfig, logger logr.Logger) (*Result, error) {
+	f.calls++
+	f.phase = phase
+	return f.result, f.err
+}
+
+var _ = Describe("HealthCheck", func() {
+	var (
+		passing *fakeCheck
+		failing *fakeCheck
+		broken  *fakeCheck
+		logger  logr.Logger
+	)
+
+	BeforeEach(func() {
+		passing = &fakeCheck{name: "FakePassing", result: pass("all good")}
+		failing = &fakeCheck{name: "FakeFailing", result: fail([]string{"thing: broken"}, "something is wrong")}
+		broken = &fakeCheck{name: "FakeBroken", err: fmt.Errorf("api unreachable")}
+		for _, f := range []*fakeCheck{passing, failing, broken} {
+			registry[f.name] = f
+		}
+		logger = logf.Log.WithName("healthcheck test logger")
+	})
+
+	// run performs the health checks as configured before the upgrade
+	run := func(names []string) []Result {
+		cfg := operatorconfig.Get()
+		cfg.HealthCheck.PreUpgrade = names
+		operatorconfig.Set(cfg, "", "")
+		return Run(nil, operatorconfig.PreUpgrade, nil, logger)
+	}
+
+	AfterEach(func() {
+		for _, f := range []*fakeCheck{passing, failing, broken} {
+			delete(registry, f.name)
+		}
+		operatorconfig.Reset()
+	})
+
+	Context("When running health checks", func() {
+		It("Runs all of them and reports every result in order", func() {
+			results := run([]string{"FakeFailing", "FakePassing", "FakeBroken"})
+			Expect(results).To(HaveLen(3))
+			for i := range results {
+				Expect(results[i].CheckedTime).NotTo(BeZero())
+				results[i].CheckedTime = time.Time{}
+			}
+			Expect(results).To(Equal([]Result{
+				{Name: "FakeFailing", Status: StatusFail, Message: "something is wrong", Evidence: []string{"thing: broken"}},
+				{Name: "FakePassing", Status: StatusPass, Message: "all good"},
+				{Name: "FakeBroken", Status: StatusFail, Message: "unable to perform the health check: api unreachable"},
+			}))
+			Expect(passing.calls).To(Equal(1))
+			Expect(passing.phase).To(Equal(operatorconfig.PreUpgrade))
+		})
+
+		It("Runs the health checks of the phase", func() {
+			cfg := operatorconfig.DefaultConfig()
+			cfg.HealthCheck.PostUpgrade = []string{"FakePassing"}
+			operatorconfig.Set(cfg, "", "")
+			results := Run(nil, operatorconfig.PostUpgrade, nil, logger)
+			Expect(results).To(HaveLen(1))
+			Expect(results[0].Name).To(Equal("FakePassing"))
+			Expect(passing.phase).To(Equal(operatorconfig.PostUpgrade))
+		})
+
+		It("Only warns on the failures of the warnOnly health checks", func() {
+			cfg := operatorconfig.DefaultConfig()
+			cfg.HealthCheck.WarnOnly = []string{"FakeFailing"}
+			operatorconfig.Set(cfg, "", "")
+			results := run([]string{"FakeFailing", "FakeBroken"})
+			Expect(results[0].Status).To(Equal(StatusWarn))
+			Expect(results[1].Status).To(Equal(StatusFail))
+			Expect(Summary(results)).To(Equal("1 of 2 health checks failed: FakeBroken: unable to perform the health check: api unreachable, 1 warned: FakeFailing: something is wrong"))
+		})
+
+		It("Converts the results for the status", func() {
+			results := run([]string{"FakeFailing"})
+			preflight := PreflightResults(results)
+			Expect(preflight).To(HaveLen(1))
+			Expect(preflight[0].Name).To(Equal("FakeFailing"))
+			Expect(preflight[0].Result).To(Equal(upgradev1alpha1.PreflightFail))
+			Expect(preflight[0].Message).To(Equal("something is wrong"))
+			Expect(preflight[0].Evidence).To(Equal([]string{"thing: broken"}))
+			Expect(preflight[0].CheckedTime.Time).To(Equal(results[0].CheckedTime))
+		})
+
+		It("Fails the unknown health checks", func() {
+			results := run([]string{"Unknown"})
+			Expect(results).To(HaveLen(1))
+			Expect(results[0].Status).To(Equal(StatusFail))
+		})
+
+		It("Summarizes the failures", func() {
+			results := run([]string{"FakeFailing", "FakePassing"})
+			Expect(Failed(results)).To(HaveLen(1))
+			Expect(Summary(results)).To(Equal("1 of 2 health checks failed: FakeFailing: something is wrong"))
+		})
+
+		It("Summarizes the success", func() {
+			results := run([]string{"FakePassing"})
+			Expect(Failed(results)).To(BeEmpty())
+			Expect(Summary(results)).To(Equal("all 1 health checks passed"))
+		})
+	})
+
+	Context("When validating health check names", func() {
+		It("Accepts the built-in health checks", func() {
+			Expect(Validate([]string{CriticalAlerts, ClusterOperators, NodesReady, PendingCSRs, EtcdMembers, MachineConfigPools})).To(Succeed())
+		})
+		It("Rejects the unknown health checks", func() {
+			err := Validate([]string{NodesReady, "NodeReady"})
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("NodeReady"))
+		})
+	})
+
+	Context("When registering a health check", func() {
+		It("Refuses a name already registered", func() {
+			Expect(func() { Register(&fakeCheck{name: NodesReady}) }).To(Panic())
+		})
+	})
+})
@@ -0,0 +1,45 @@
+package healthcheck
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/go-logr/logr"
+	machineconfigapi "github.com/openshift/machine-config-operator/pkg/apis/machineconfiguration.openshift.io/v1"
+	upgradev1alpha1 "github.com/openshift/managed-upgrade-operator/pkg/apis/upgrade/v1alpha1"
+	"github.com/openshift/managed-upgrade-operator/pkg/operatorconfig"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// machineConfigPools fails if any MachineConfigPool is degraded
+type machineConfigPools struct{}
+
+func (mcp *machineConfigPools) Name() string {
+	return MachineConfigPools
+}
+
+func (mcp *machineConfigPools) Check(c client.Client, phase operatorconfig.HealthCheckPhase, upgradeConfig *upgradev1alpha1.UpgradeConfig, logger logr.Logger) (*Result, error) {
+	poolList := &machineconfigapi.MachineConfigPoolList{}
+	err := c.List(context.TODO(), poolList)
+	if err != nil {
+		return nil, err
+	}
+
+	degraded := []string{}
+	evidence := []string{}
+	for _, pool := range poolList.Items {
+		for _, condition := range pool.Status.Conditions {
+			if condition.Type == machineconfigapi.MachineConfigPoolDegraded && condition.Status == corev1.ConditionTrue {
+				degraded = append(degraded, pool.Name)
+				evidence = append(evidence, fmt.Sprintf("%s: %s: %s", pool.Name, condition.Reason, condition.Message))
+				break
+			}
+		}
+	}
+	if len(degraded) > 0 {
+		return fail(evidence, "degraded machineconfigpools: %s", strings.Join(degraded, ",")), nil
+	}
+	return pass("none of the %d machineconfigpools is degraded", len(poolList.Items)), nil
+}
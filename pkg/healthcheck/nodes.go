@@ -0,0 +1,54 @@
+package healthcheck
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/go-logr/logr"
+	upgradev1alpha1 "github.com/openshift/managed-upgrade-operator/pkg/apis/upgrade/v1alpha1"
+	"github.com/openshift/managed-upgrade-operator/pkg/operatorconfig"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// nodesReady fails if any node is not Ready
+type nodesReady struct{}
+
+func (nr *nodesReady) Name() string {
+	return NodesReady
+}
+
+func (nr *nodesReady) Check(c client.Client, phase operatorconfig.HealthCheckPhase, upgradeConfig *upgradev1alpha1.UpgradeConfig, logger logr.Logger) (*Result, error) {
+	nodeList := &corev1.NodeList{}
+	err := c.List(context.TODO(), nodeList)
+	if err != nil {
+		return nil, err
+	}
+
+	notReady := []string{}
+	evidence := []string{}
+	for _, node := range nodeList.Items {
+		ready := readyCondition(&node)
+		if ready == nil {
+			notReady = append(notReady, node.Name)
+			evidence = append(evidence, fmt.Sprintf("%s: no Ready condition", node.Name))
+		} else if ready.Status != corev1.ConditionTrue {
+			notReady = append(notReady, node.Name)
+			evidence = append(evidence, fmt.Sprintf("%s: Ready=%s: %s", node.Name, ready.Status, ready.Message))
+		}
+	}
+	if len(notReady) > 0 {
+		return fail(evidence, "nodes not ready: %s", strings.Join(notReady, ",")), nil
+	}
+	return pass("all %d nodes are ready", len(nodeList.Items)), nil
+}
+
+func readyCondition(node *corev1.Node) *corev1.NodeCondition {
+	for i := range node.Status.Conditions {
+		if node.Status.Conditions[i].Type == corev1.NodeReady {
+			return &node.Status.Conditions[i]
+		}
+	}
+	return nil
+}
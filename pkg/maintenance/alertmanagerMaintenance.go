@@ -8,6 +8,7 @@ import (
 	"github.com/go-openapi/strfmt"
 	routev1 "github.com/openshift/api/route/v1"
 	"github.com/openshift/managed-upgrade-operator/config"
+	"github.com/openshift/managed-upgrade-operator/pkg/operatorconfig"
 	amv2Models "github.com/prometheus/alertmanager/api/v2/models"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/types"
@@ -17,26 +18,19 @@ import (
 )
 
 var (
-	alertManagerNamespace          = "openshift-monitoring"
-	alertManagerRouteName          = "alertmanager-main"
-	alertManagerServiceAccountName = "prometheus-k8s"
-	alertManagerBasePath           = "/api/v2/"
-
-	// Generally upgrades should not fire critical alerts but there are some critical alerts that will fire.
-	// e.g. 'etcdMembersDown' happens as the masters drain/reboot and a master is offline but this is expected and will resolve.
-	// This is a regex of critical alerts that can be ignored while upgrading of controlplane occurs
-	controlPlaneIgnoredCriticalAlerts = "(etcdMembersDown)"
+	alertManagerBasePath = "/api/v2/"
 )
 
 type alertManagerMaintenanceBuilder struct{}
 
 func (ammb *alertManagerMaintenanceBuilder) NewClient(client client.Client) (Maintenance, error) {
-	transport, err := getTransport(client)
+	cfg := operatorconfig.Get()
+	transport, err := getTransport(client, cfg.Monitoring)
 	if err != nil {
 		return nil, err
 	}
 
-	transport.DefaultAuthentication, err = getAuthentication(client)
+	transport.DefaultAuthentication, err = getAuthentication(client, cfg.Monitoring)
 	if err != nil {
 		return nil, err
 	}
@@ -45,18 +39,20 @@ func (ammb *alertManagerMaintenanceBuilder) NewClient(client client.Client) (Mai
 		client: alertManagerSilenceClient{
 			transport: transport,
 		},
+		config: cfg.Maintenance,
 	}, nil
 }
 
 type alertManagerMaintenance struct {
 	client alertManagerSilenceClient
+	config operatorconfig.MaintenanceConfig
 }
 
-func getTransport(c client.Client) (*httptransport.Runtime, error) {
+func getTransport(c client.Client, monitoring operatorconfig.MonitoringConfig) (*httptransport.Runtime, error) {
 	amRoute := &routev1.Route{}
 	err := c.Get(
 		context.TODO(),
-		types.NamespacedName{Namespace: alertManagerNamespace, Name: alertManagerRouteName},
+		types.NamespacedName{Namespace: monitoring.Namespace, Name: monitoring.AlertmanagerRoute},
 		amRoute,
 	)
 	if err != nil {
@@ -70,12 +66,12 @@ func getTransport(c client.Client) (*httptransport.Runtime, error) {
 	), nil
 }
 
-func getAuthentication(c client.Client) (runtime.ClientAuthInfoWriter, error) {
+func getAuthentication(c client.Client, monitoring operatorconfig.MonitoringConfig) (runtime.ClientAuthInfoWriter, error) {
 	sl := &corev1.SecretList{}
 	err := c.List(
 		context.TODO(),
 		sl,
-		&client.ListOptions{Namespace: alertManagerNamespace},
+		&client.ListOptions{Namespace: monitoring.Namespace},
 	)
 	if err != nil {
 		return nil, err
@@ -83,7 +79,7 @@ func getAuthentication(c client.Client) (runtime.ClientAuthInfoWriter, error) {
 
 	var token string
 	for _, s := range sl.Items {
-		if strings.Contains(s.Name, alertManagerServiceAccountName+"-token") {
+		if strings.Contains(s.Name, monitoring.ServiceAccount+"-token") {
 			token = string(s.Data["token"])
 		}
 	}
@@ -96,13 +92,13 @@ func getAuthentication(c client.Client) (runtime.ClientAuthInfoWriter, error) {
 func (amm *alertManagerMaintenance) StartControlPlane(endsAt time.Time) error {
 	now := strfmt.DateTime(time.Now().UTC())
 	end := strfmt.DateTime(endsAt.UTC())
-	err := amm.client.create(createDefaultMatchers(), now, end, config.OperatorName, "Silence for OSD upgrade")
+	err := amm.ensureSilence(createDefaultMatchers(amm.config), now, end)
 	if err != nil {
 		return err
 	}
 
-	matchers := []*amv2Models.Matcher{createMatcher("alertname", controlPlaneIgnoredCriticalAlerts, true)}
-	err = amm.client.create(matchers, now, end, config.OperatorName, "Silence for OSD upgrade")
+	matchers := []*amv2Models.Matcher{createMatcher("alertname", amm.config.ControlPlaneIgnoredCriticalAlerts, true)}
+	err = amm.ensureSilence(matchers, now, end)
 	if err != nil {
 		return err
 	}
@@ -115,7 +111,7 @@ func (amm *alertManagerMaintenance) StartControlPlane(endsAt time.Time) error {
 func (amm *alertManagerMaintenance) StartWorker(endsAt time.Time) error {
 	now := strfmt.DateTime(time.Now().UTC())
 	end := strfmt.DateTime(endsAt.UTC())
-	err := amm.client.create(createDefaultMatchers(), now, end, config.OperatorName, "Silence for OSD upgrade")
+	err := amm.ensureSilence(createDefaultMatchers(amm.config), now, end)
 	if err != nil {
 		return err
 	}
@@ -123,6 +119,21 @@ func (amm *alertManagerMaintenance) StartWorker(endsAt time.Time) error {
 	return nil
 }
 
+// Create the silence unless an active silence with the same matchers was already created by managed-upgrade-operator,
+// so that starting a maintenance again does not pile up silences
+func (amm *alertManagerMaintenance) ensureSilence(matchers amv2Models.Matchers, startsAt strfmt.DateTime, endsAt strfmt.DateTime) error {
+	silences, err := amm.client.List([]string{})
+	if err != nil {
+		return err
+	}
+	for _, s := range silences.Payload {
+		if *s.CreatedBy == config.OperatorName && *s.Status.State == amv2Models.SilenceStatusStateActive && sameMatchers(s.Matchers, matchers) {
+			return nil
+		}
+	}
+	return amm.client.create(matchers, startsAt, endsAt, config.OperatorName, "Silence for OSD upgrade")
+}
+
 // End all active maintenances created by managed-upgrade-operator in Alertmanager
 func (amm *alertManagerMaintenance) End() error {
 	silences, err := amm.client.List([]string{})
@@ -150,10 +161,22 @@ func createMatcher(alertMatchKey string, alertValue string, isRegex bool) *amv2M
 	}
 }
 
-func createDefaultMatchers() []*amv2Models.Matcher {
+func sameMatchers(a amv2Models.Matchers, b amv2Models.Matchers) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if *a[i].Name != *b[i].Name || *a[i].Value != *b[i].Value || *a[i].IsRegex != *b[i].IsRegex {
+			return false
+		}
+	}
+	return true
+}
+
+func createDefaultMatchers(cfg operatorconfig.MaintenanceConfig) []*amv2Models.Matcher {
 	// Upgrades can impact some availability which may trigger info/warning alerts. ignore those.
-	nonCriticalAlertMatcher := createMatcher("severity", "(warning|info|none)", true)
+	nonCriticalAlertMatcher := createMatcher("severity", cfg.SilencedSeverities, true)
 
-	inNamespaceAlertMatcher := createMatcher("namespace", "(^openshift.*|^kube.*|^redhat.*|^default$)", true)
+	inNamespaceAlertMatcher := createMatcher("namespace", cfg.SilencedNamespaces, true)
 	return amv2Models.Matchers{nonCriticalAlertMatcher, inNamespaceAlertMatcher}
 }
@@ -9,6 +9,7 @@ import (
 const (
 	metricsTag	= "upgradeoperator"
 	nameLabel	= "upgradeconfig_name"
+	sourceLabel	= "source"
 )
 
 type Metrics interface {
@@ -21,6 +22,12 @@ type Metrics interface {
 	UpdateMetricNodeUpgradeEndTime(time.Time, string)
 	UpdateMetricClusterVerificationFailed(string)
 	UpdateMetricClusterVerificationSucceeded(string)
+	UpdateMetricExternalUpgradeForbidden(string)
+	UpdateMetricExternalUpgradeCleared(string)
+	UpdateMetricWorkerUpgradePaused(string)
+	UpdateMetricWorkerUpgradeResumed(string)
+	UpdateMetricControlPlaneOperatorsProgress(float64, string)
+	UpdateMetricControlPlaneManifestsProgress(float64, string)
 }
 
 type Counter struct {}
@@ -56,6 +63,21 @@ var (
 		Name: "cluster_verification_failed",
 		Help: "Failed on the cluster upgrade verification step",
 	}, []string{nameLabel})
+	metricExternalUpgradeForbidden = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Subsystem: metricsTag,
+		Name: "external_upgrade_forbidden",
+		Help: "An upgrade forbidden by the policy was started outside the operator",
+	}, []string{nameLabel})
+	metricWorkerUpgradePaused = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Subsystem: metricsTag,
+		Name: "worker_upgrade_paused",
+		Help: "The worker upgrade was paused because a critical signal tripped",
+	}, []string{nameLabel})
+	metricControlPlaneUpgradeProgress = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Subsystem: metricsTag,
+		Name: "controlplane_upgrade_progress",
+		Help: "Fraction of the ClusterOperators at the target version or of the release manifests applied",
+	}, []string{nameLabel, sourceLabel})
 )
 
 func init() {
@@ -65,6 +87,9 @@ func init() {
 	metrics.Registry.MustRegister(metricControlPlaneUpgradeEndTime)
 	metrics.Registry.MustRegister(metricNodeUpgradeEndTime)
 	metrics.Registry.MustRegister(metricClusterVerificationFailed)
+	metrics.Registry.MustRegister(metricExternalUpgradeForbidden)
+	metrics.Registry.MustRegister(metricWorkerUpgradePaused)
+	metrics.Registry.MustRegister(metricControlPlaneUpgradeProgress)
 }
 
 func (c *Counter) UpdateMetricValidationFailed(upgradeconfig string) {
@@ -121,3 +146,39 @@ func (c *Counter) UpdateMetricClusterVerificationSucceeded(upgradeconfig string)
 			float64(0))
 }
 
+
+func (c *Counter) UpdateMetricExternalUpgradeForbidden(upgradeconfig string) {
+	metricExternalUpgradeForbidden.With(prometheus.Labels{
+		nameLabel: upgradeconfig}).Set(
+			float64(1))
+}
+
+func (c *Counter) UpdateMetricExternalUpgradeCleared(upgradeconfig string) {
+	metricExternalUpgradeForbidden.With(prometheus.Labels{
+		nameLabel: upgradeconfig}).Set(
+			float64(0))
+}
+
+func (c *Counter) UpdateMetricWorkerUpgradePaused(upgradeconfig string) {
+	metricWorkerUpgradePaused.With(prometheus.Labels{
+		nameLabel: upgradeconfig}).Set(
+			float64(1))
+}
+
+func (c *Counter) UpdateMetricWorkerUpgradeResumed(upgradeconfig string) {
+	metricWorkerUpgradePaused.With(prometheus.Labels{
+		nameLabel: upgradeconfig}).Set(
+			float64(0))
+}
+
+func (c *Counter) UpdateMetricControlPlaneOperatorsProgress(ratio float64, upgradeconfig string) {
+	metricControlPlaneUpgradeProgress.With(prometheus.Labels{
+		nameLabel: upgradeconfig, sourceLabel: "operators"}).Set(
+			ratio)
+}
+
+func (c *Counter) UpdateMetricControlPlaneManifestsProgress(ratio float64, upgradeconfig string) {
+	metricControlPlaneUpgradeProgress.With(prometheus.Labels{
+		nameLabel: upgradeconfig, sourceLabel: "manifests"}).Set(
+			ratio)
+}
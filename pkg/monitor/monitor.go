@@ -0,0 +1,241 @@
+package monitor
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+	machineconfigapi "github.com/openshift/machine-config-operator/pkg/apis/machineconfiguration.openshift.io/v1"
+	upgradev1alpha1 "github.com/openshift/managed-upgrade-operator/pkg/apis/upgrade/v1alpha1"
+	"github.com/openshift/managed-upgrade-operator/pkg/metrics"
+	"github.com/openshift/managed-upgrade-operator/pkg/operatorconfig"
+	"github.com/openshift/managed-upgrade-operator/pkg/prometheus"
+	"github.com/openshift/managed-upgrade-operator/pkg/upgradestatus"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+)
+
+var log = logf.Log.WithName("monitor")
+
+const (
+	// How often the monitor checks whether the signals are due to be evaluated
+	monitorTick = 10 * time.Second
+
+	// Name of the MachineConfigPool paused when a signal trips
+	workerPool = "worker"
+
+	// Reasons of the Degraded condition
+	ReasonSignalTripped = "SignalTripped"
+	ReasonResumed       = "Resumed"
+	ReasonSignalsClear  = "SignalsClear"
+)
+
+// blank assignment to verify that Monitor implements manager.Runnable
+var _ manager.Runnable = &Monitor{}
+
+// Monitor evaluates the critical signals at the configured interval while an upgrade is in progress.
+// Nothing is evaluated while no signal is configured.
+type Monitor struct {
+	client        client.Client
+	builder       prometheus.PrometheusClientBuilder
+	metricsClient metrics.Metrics
+}
+
+func NewMonitor(c client.Client, builder prometheus.PrometheusClientBuilder, metricsClient metrics.Metrics) *Monitor {
+	return &Monitor{client: c, builder: builder, metricsClient: metricsClient}
+}
+
+// Start evaluates the signals until stopped. The configuration is read on every tick so that it applies live.
+func (m *Monitor) Start(stop <-chan struct{}) error {
+	ticker := time.NewTicker(monitorTick)
+	defer ticker.Stop()
+
+	var last time.Time
+	for {
+		cfg := operatorconfig.Get()
+		if len(cfg.Monitor.Signals) > 0 && time.Since(last) >= cfg.MonitorInterval() {
+			m.monitor(cfg.Monitor.Signals)
+			last = time.Now()
+		}
+
+		select {
+		case <-stop:
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+func (m *Monitor) monitor(signals []operatorconfig.MonitorSignal) {
+	ucList := &upgradev1alpha1.UpgradeConfigList{}
+	err := m.client.List(context.TODO(), ucList)
+	if err != nil {
+		log.Error(err, "unable to list the UpgradeConfigs")
+		return
+	}
+	for i := range ucList.Items {
+		uc := &ucList.Items[i]
+		if !isUpgrading(uc) {
+			continue
+		}
+		logger := log.WithValues("Request.Namespace", uc.Namespace, "Request.Name", uc.Name)
+		promClient, err := m.builder.NewClient()
+		if err != nil {
+			logger.Error(err, "unable to build the Prometheus client")
+			return
+		}
+		err = Check(m.client, promClient, m.metricsClient, uc, signals, logger)
+		if err != nil {
+			logger.Error(err, "unable to monitor the upgrade")
+		}
+	}
+}
+
+// Check evaluates the signals while the upgrade of the desired version is in progress.
+// A signal tripping while the workers upgrade pauses the worker MachineConfigPool and sets the Degraded condition,
+// with the series which tripped it as evidence. The pool stays paused, even once the signals clear, until resumed.
+// Once resumed, the signals tripping at the time are tolerated until they all clear.
+func Check(c client.Client, promClient prometheus.PrometheusClient, metricsClient metrics.Metrics, uc *upgradev1alpha1.UpgradeConfig, signals []operatorconfig.MonitorSignal, logger logr.Logger) error {
+	if !isUpgrading(uc) {
+		return nil
+	}
+	history := uc.Status.History.GetHistory(uc.Spec.Desired.Version)
+	evidence, err := Evaluate(promClient, signals)
+	if err != nil {
+		return err
+	}
+
+	degraded := uc.Status.Conditions.GetCondition(upgradev1alpha1.Degraded)
+	resumed := degraded != nil && degraded.Reason == ReasonResumed && history.StartTime != nil &&
+		degraded.LastTransitionTime != nil && !degraded.LastTransitionTime.Before(history.StartTime)
+	if len(evidence) == 0 {
+		if !resumed {
+			return nil
+		}
+		condition := upgradev1alpha1.UpgradeCondition{
+			Type:    upgradev1alpha1.Degraded,
+			Status:  corev1.ConditionFalse,
+			Reason:  ReasonSignalsClear,
+			Message: "no critical signal is tripping",
+		}
+		return upgradestatus.Patch(c, uc, upgradestatus.SetCondition(condition))
+	}
+
+	switch {
+	case !isWorkerStage(history):
+		logger.Info(fmt.Sprintf("critical signals tripped outside the worker upgrade: %s", strings.Join(evidence, "; ")))
+		return nil
+	case degraded != nil && degraded.IsTrue():
+		logger.Info("the worker upgrade is paused until resumed")
+		return nil
+	case resumed:
+		logger.Info(fmt.Sprintf("the worker upgrade was resumed, tolerating the critical signals until they clear: %s", strings.Join(evidence, "; ")))
+		return nil
+	}
+
+	logger.Info(fmt.Sprintf("pausing the worker upgrade, critical signals tripped: %s", strings.Join(evidence, "; ")))
+	err = setPoolPaused(c, true)
+	if err != nil {
+		return err
+	}
+	condition := upgradev1alpha1.UpgradeCondition{
+		Type:    upgradev1alpha1.Degraded,
+		Status:  corev1.ConditionTrue,
+		Reason:  ReasonSignalTripped,
+		Message: fmt.Sprintf("the worker upgrade is paused until the %s annotation is set, critical signals tripped: %s", upgradev1alpha1.ResumeAnnotation, strings.Join(evidence, "; ")),
+	}
+	err = upgradestatus.Patch(c, uc, upgradestatus.SetCondition(condition))
+	if err != nil {
+		return err
+	}
+	metricsClient.UpdateMetricWorkerUpgradePaused(uc.Name)
+	return nil
+}
+
+// Resume unpauses the worker MachineConfigPool paused by the monitor and clears the Degraded condition,
+// then removes the resume annotation
+func Resume(c client.Client, metricsClient metrics.Metrics, uc *upgradev1alpha1.UpgradeConfig, logger logr.Logger) error {
+	if IsPaused(uc) {
+		logger.Info("resuming the worker upgrade")
+		err := setPoolPaused(c, false)
+		if err != nil {
+			return err
+		}
+		condition := upgradev1alpha1.UpgradeCondition{
+			Type:    upgradev1alpha1.Degraded,
+			Status:  corev1.ConditionFalse,
+			Reason:  ReasonResumed,
+			Message: "the worker upgrade was resumed",
+		}
+		err = upgradestatus.Patch(c, uc, upgradestatus.SetCondition(condition))
+		if err != nil {
+			return err
+		}
+		metricsClient.UpdateMetricWorkerUpgradeResumed(uc.Name)
+	}
+
+	patch := client.MergeFrom(uc.DeepCopy())
+	delete(uc.Annotations, upgradev1alpha1.ResumeAnnotation)
+	return c.Patch(context.TODO(), uc, patch)
+}
+
+// IsPaused returns true if the monitor paused the worker upgrade
+func IsPaused(uc *upgradev1alpha1.UpgradeConfig) bool {
+	degraded := uc.Status.Conditions.GetCondition(upgradev1alpha1.Degraded)
+	return degraded != nil && degraded.IsTrue() && degraded.Reason == ReasonSignalTripped
+}
+
+// Evaluate returns the evidence of the tripped signals: the series returned by their queries, sorted.
+// An error means Prometheus could not be queried.
+func Evaluate(promClient prometheus.PrometheusClient, signals []operatorconfig.MonitorSignal) ([]string, error) {
+	evidence := []string{}
+	for _, signal := range signals {
+		series, err := promClient.Query(signal.Query, time.Time{})
+		if err != nil {
+			return nil, err
+		}
+		found := []string{}
+		for _, s := range series {
+			found = append(found, fmt.Sprintf("%s: %s = %s", signal.Name, s.Metric, s.Value))
+		}
+		sort.Strings(found)
+		evidence = append(evidence, found...)
+	}
+	return evidence, nil
+}
+
+// isUpgrading returns true if the upgrade of the desired version is in progress
+func isUpgrading(uc *upgradev1alpha1.UpgradeConfig) bool {
+	if uc.IsDryRun() {
+		return false
+	}
+	history := uc.Status.History.GetHistory(uc.Spec.Desired.Version)
+	return history != nil && history.Phase == upgradev1alpha1.UpgradePhaseUpgrading
+}
+
+// isWorkerStage returns true once the masters are upgraded, until the workers are
+func isWorkerStage(history *upgradev1alpha1.UpgradeHistory) bool {
+	return history.Conditions.IsTrueFor(upgradev1alpha1.AllMasterNodesUpgraded) &&
+		!history.Conditions.IsTrueFor(upgradev1alpha1.AllWorkerNodesUpgraded)
+}
+
+// setPoolPaused pauses or unpauses the worker MachineConfigPool
+func setPoolPaused(c client.Client, paused bool) error {
+	pool := &machineconfigapi.MachineConfigPool{}
+	err := c.Get(context.TODO(), types.NamespacedName{Name: workerPool}, pool)
+	if err != nil {
+		return err
+	}
+	if pool.Spec.Paused == paused {
+		return nil
+	}
+	patch := client.MergeFrom(pool.DeepCopy())
+	pool.Spec.Paused = paused
+	return c.Patch(context.TODO(), pool, patch)
+}
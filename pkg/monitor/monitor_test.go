@@ -0,0 +1,186 @@
+package monitor
+
+import (
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/golang/mock/gomock"
+	machineconfigapi "github.com/openshift/machine-config-operator/pkg/apis/machineconfiguration.openshift.io/v1"
+	upgradev1alpha1 "github.com/openshift/managed-upgrade-operator/pkg/apis/upgrade/v1alpha1"
+	"github.com/openshift/managed-upgrade-operator/pkg/metrics"
+	"github.com/openshift/managed-upgrade-operator/pkg/operatorconfig"
+	prometheusMocks "github.com/openshift/managed-upgrade-operator/pkg/prometheus/mocks"
+	"github.com/openshift/managed-upgrade-operator/util/mocks"
+	testStructs "github.com/openshift/managed-upgrade-operator/util/mocks/structs"
+	"github.com/prometheus/common/model"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Monitor", func() {
+	var (
+		mockCtrl             *gomock.Controller
+		mockKubeClient       *mocks.MockClient
+		mockUpdater          *mocks.MockStatusWriter
+		mockPrometheusClient *prometheusMocks.MockPrometheusClient
+		upgradeConfig        *upgradev1alpha1.UpgradeConfig
+		signals              []operatorconfig.MonitorSignal
+		started              metav1.Time
+		logger               logr.Logger
+	)
+
+	poolName := types.NamespacedName{Name: "worker"}
+	leaderless := model.Vector{
+		&model.Sample{Metric: model.Metric{"instance": "master-1"}, Value: 0},
+		&model.Sample{Metric: model.Metric{"instance": "master-0"}, Value: 0},
+	}
+	expectPool := func(paused bool) *machineconfigapi.MachineConfigPool {
+		patched := &machineconfigapi.MachineConfigPool{}
+		mockKubeClient.EXPECT().Get(gomock.Any(), poolName, gomock.Any()).SetArg(2, machineconfigapi.MachineConfigPool{
+			Spec: machineconfigapi.MachineConfigPoolSpec{Paused: !paused},
+		})
+		mockKubeClient.EXPECT().Patch(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(
+			func(ctx interface{}, obj *machineconfigapi.MachineConfigPool, patch client.Patch) error {
+				obj.DeepCopyInto(patched)
+				return nil
+			})
+		return patched
+	}
+	setStep := func(step upgradev1alpha1.UpgradeConditionType, status corev1.ConditionStatus) {
+		upgradeConfig.Status.History[0].Conditions.SetCondition(upgradev1alpha1.UpgradeCondition{Type: step, Status: status})
+	}
+	setDegraded := func(status corev1.ConditionStatus, reason string, at time.Time) {
+		upgradeConfig.Status.Conditions = upgradev1alpha1.Conditions{{
+			Type:               upgradev1alpha1.Degraded,
+			Status:             status,
+			Reason:             reason,
+			LastTransitionTime: &metav1.Time{Time: at},
+		}}
+	}
+
+	BeforeEach(func() {
+		mockCtrl = gomock.NewController(GinkgoT())
+		mockKubeClient = mocks.NewMockClient(mockCtrl)
+		mockUpdater = mocks.NewMockStatusWriter(mockCtrl)
+		mockPrometheusClient = prometheusMocks.NewMockPrometheusClient(mockCtrl)
+		mockKubeClient.EXPECT().Status().Return(mockUpdater).AnyTimes()
+		started = metav1.NewTime(time.Now().Add(-time.Hour))
+		upgradeConfig = testStructs.NewUpgradeConfigBuilder().GetUpgradeConfig()
+		upgradeConfig.Status.History = upgradev1alpha1.UpgradeHistories{{
+			Version:    upgradeConfig.Spec.Desired.Version,
+			Phase:      upgradev1alpha1.UpgradePhaseUpgrading,
+			StartTime:  &started,
+			Conditions: upgradev1alpha1.NewConditions(),
+		}}
+		signals = []operatorconfig.MonitorSignal{{Name: "etcd-no-leader", Query: "etcd_server_has_leader == 0"}}
+		logger = logf.Log.WithName("monitor test logger")
+	})
+
+	AfterEach(func() {
+		mockCtrl.Finish()
+	})
+
+	Context("When evaluating the signals", func() {
+		It("returns the tripping series as evidence, sorted", func() {
+			mockPrometheusClient.EXPECT().Query("etcd_server_has_leader == 0", time.Time{}).Return(leaderless, nil)
+			evidence, err := Evaluate(mockPrometheusClient, signals)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(evidence).To(Equal([]string{
+				`etcd-no-leader: {instance="master-0"} = 0`,
+				`etcd-no-leader: {instance="master-1"} = 0`,
+			}))
+		})
+	})
+
+	Context("When a signal trips while the workers upgrade", func() {
+		BeforeEach(func() {
+			setStep(upgradev1alpha1.AllMasterNodesUpgraded, corev1.ConditionTrue)
+			setStep(upgradev1alpha1.AllWorkerNodesUpgraded, corev1.ConditionFalse)
+			mockPrometheusClient.EXPECT().Query(gomock.Any(), gomock.Any()).Return(leaderless, nil)
+		})
+
+		It("pauses the worker pool and sets the Degraded condition with the evidence", func() {
+			pool := expectPool(true)
+			mockUpdater.EXPECT().Patch(gomock.Any(), gomock.Any(), gomock.Any())
+			err := Check(mockKubeClient, mockPrometheusClient, &metrics.Counter{}, upgradeConfig, signals, logger)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(pool.Spec.Paused).To(BeTrue())
+			Expect(IsPaused(upgradeConfig)).To(BeTrue())
+			degraded := upgradeConfig.Status.Conditions.GetCondition(upgradev1alpha1.Degraded)
+			Expect(degraded.Message).To(ContainSubstring(`etcd-no-leader: {instance="master-0"} = 0; etcd-no-leader: {instance="master-1"} = 0`))
+		})
+		It("leaves the pool paused while the upgrade is not resumed", func() {
+			setDegraded(corev1.ConditionTrue, ReasonSignalTripped, time.Now())
+			err := Check(mockKubeClient, mockPrometheusClient, &metrics.Counter{}, upgradeConfig, signals, logger)
+			Expect(err).NotTo(HaveOccurred())
+		})
+		It("tolerates the signals once resumed", func() {
+			setDegraded(corev1.ConditionFalse, ReasonResumed, time.Now())
+			err := Check(mockKubeClient, mockPrometheusClient, &metrics.Counter{}, upgradeConfig, signals, logger)
+			Expect(err).NotTo(HaveOccurred())
+		})
+		It("pauses again when resumed during a previous upgrade", func() {
+			setDegraded(corev1.ConditionFalse, ReasonSignalsClear, started.Add(-time.Hour))
+			expectPool(true)
+			mockUpdater.EXPECT().Patch(gomock.Any(), gomock.Any(), gomock.Any())
+			err := Check(mockKubeClient, mockPrometheusClient, &metrics.Counter{}, upgradeConfig, signals, logger)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(IsPaused(upgradeConfig)).To(BeTrue())
+		})
+	})
+
+	Context("When a signal trips outside the worker upgrade", func() {
+		It("does not pause anything", func() {
+			mockPrometheusClient.EXPECT().Query(gomock.Any(), gomock.Any()).Return(leaderless, nil)
+			err := Check(mockKubeClient, mockPrometheusClient, &metrics.Counter{}, upgradeConfig, signals, logger)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(IsPaused(upgradeConfig)).To(BeFalse())
+		})
+		It("does not evaluate the signals unless upgrading", func() {
+			upgradeConfig.Status.History[0].Phase = upgradev1alpha1.UpgradePhaseUpgraded
+			err := Check(mockKubeClient, mockPrometheusClient, &metrics.Counter{}, upgradeConfig, signals, logger)
+			Expect(err).NotTo(HaveOccurred())
+		})
+	})
+
+	Context("When the signals clear after a resume", func() {
+		It("records that they cleared so that they pause again", func() {
+			setDegraded(corev1.ConditionFalse, ReasonResumed, time.Now())
+			mockPrometheusClient.EXPECT().Query(gomock.Any(), gomock.Any()).Return(model.Vector{}, nil)
+			mockUpdater.EXPECT().Patch(gomock.Any(), gomock.Any(), gomock.Any())
+			err := Check(mockKubeClient, mockPrometheusClient, &metrics.Counter{}, upgradeConfig, signals, logger)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(upgradeConfig.Status.Conditions.GetCondition(upgradev1alpha1.Degraded).Reason).To(Equal(ReasonSignalsClear))
+		})
+	})
+
+	Context("When resuming the upgrade", func() {
+		BeforeEach(func() {
+			upgradeConfig.Annotations = map[string]string{upgradev1alpha1.ResumeAnnotation: "true"}
+		})
+
+		It("unpauses the worker pool, clears the Degraded condition and removes the annotation", func() {
+			setDegraded(corev1.ConditionTrue, ReasonSignalTripped, time.Now())
+			pool := expectPool(false)
+			mockUpdater.EXPECT().Patch(gomock.Any(), gomock.Any(), gomock.Any())
+			mockKubeClient.EXPECT().Patch(gomock.Any(), upgradeConfig, gomock.Any())
+			err := Resume(mockKubeClient, &metrics.Counter{}, upgradeConfig, logger)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(pool.Spec.Paused).To(BeFalse())
+			Expect(upgradeConfig.Status.Conditions.GetCondition(upgradev1alpha1.Degraded).Reason).To(Equal(ReasonResumed))
+			Expect(upgradeConfig.Annotations).NotTo(HaveKey(upgradev1alpha1.ResumeAnnotation))
+		})
+		It("only removes the annotation when the upgrade is not paused", func() {
+			mockKubeClient.EXPECT().Patch(gomock.Any(), upgradeConfig, gomock.Any())
+			err := Resume(mockKubeClient, &metrics.Counter{}, upgradeConfig, logger)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(upgradeConfig.Annotations).NotTo(HaveKey(upgradev1alpha1.ResumeAnnotation))
+		})
+	})
+})
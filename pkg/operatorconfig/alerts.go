@@ -0,0 +1,190 @@
+package operatorconfig
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/prometheus/common/model"
+)
+
+// HealthCheckPhase tells whether the health checks are performed before or after the upgrade
+type HealthCheckPhase string
+
+const (
+	PreUpgrade  HealthCheckPhase = "preUpgrade"
+	PostUpgrade HealthCheckPhase = "postUpgrade"
+)
+
+// The alert severities, from the highest
+var severities = []string{"critical", "warning", "info"}
+
+// A PromQL label matcher, like team="sre" or pod!~"test-.*"
+var labelMatcherRE = regexp.MustCompile(`^\s*([a-zA-Z_][a-zA-Z0-9_]*)\s*(=~|!~|!=|=)\s*("(?:[^"\\]|\\.)*")\s*$`)
+
+// AlertFilterConfig describes the firing alerts which fail the CriticalAlerts health check
+type AlertFilterConfig struct {
+	// Lowest severity failing the health check: critical, warning or info
+	MinimumSeverity string `json:"minimumSeverity"`
+	// Regexes of the namespaces whose alerts are considered, the alerts of all namespaces when empty
+	IncludeNamespaces []string `json:"includeNamespaces"`
+	// Regexes of the namespaces whose alerts are ignored
+	ExcludeNamespaces []string `json:"excludeNamespaces"`
+	// Names of the alerts ignored in each phase
+	IgnoredAlerts PhaseNamesConfig `json:"ignoredAlerts"`
+	// PromQL label matchers the alerts must also match, like team="sre"
+	LabelMatchers []string `json:"labelMatchers"`
+	// How long the alerts must have been active continuously to fail the health check in each phase, in minutes
+	MinimumAgeMinutes MinimumAgeConfig `json:"minimumAgeMinutes"`
+}
+
+// MinimumAgeConfig holds the minimum age of the alerts before and after the upgrade, in minutes
+type MinimumAgeConfig struct {
+	PreUpgrade  int `json:"preUpgrade"`
+	PostUpgrade int `json:"postUpgrade"`
+}
+
+// labelMatcher is a parsed PromQL label matcher
+type labelMatcher struct {
+	name  string
+	op    string
+	value string
+}
+
+func (m labelMatcher) String() string {
+	return m.name + m.op + strconv.Quote(m.value)
+}
+
+// parseLabelMatcher parses and validates a PromQL label matcher
+func parseLabelMatcher(s string) (labelMatcher, error) {
+	parts := labelMatcherRE.FindStringSubmatch(s)
+	if parts == nil {
+		return labelMatcher{}, fmt.Errorf("%q is not a label matcher like name=\"value\"", s)
+	}
+	value, err := strconv.Unquote(parts[3])
+	if err != nil {
+		return labelMatcher{}, fmt.Errorf("%q has an invalid value: %v", s, err)
+	}
+	m := labelMatcher{name: parts[1], op: parts[2], value: value}
+	if m.op == "=~" || m.op == "!~" {
+		if err := validateRegex(value); err != nil {
+			return labelMatcher{}, fmt.Errorf("%q has an invalid regex: %v", s, err)
+		}
+	}
+	return m, nil
+}
+
+// validateRegex compiles the regex the way Prometheus does, anchored
+func validateRegex(value string) error {
+	_, err := regexp.Compile("^(?:" + value + ")$")
+	return err
+}
+
+// validate returns all the problems of the alert filter
+func (f *AlertFilterConfig) validate() []error {
+	errs := []error{}
+	found := false
+	for _, s := range severities {
+		found = found || s == f.MinimumSeverity
+	}
+	if !found {
+		errs = append(errs, fmt.Errorf("healthCheck.alerts.minimumSeverity must be one of %s", strings.Join(severities, ",")))
+	}
+	namespaces := map[string][]string{
+		"healthCheck.alerts.includeNamespaces": f.IncludeNamespaces,
+		"healthCheck.alerts.excludeNamespaces": f.ExcludeNamespaces,
+	}
+	for field, values := range namespaces {
+		for _, ns := range values {
+			if len(ns) == 0 {
+				errs = append(errs, fmt.Errorf("%s contains an empty regex", field))
+			} else if err := validateRegex(ns); err != nil {
+				errs = append(errs, fmt.Errorf("%s contains an invalid regex %q: %v", field, ns, err))
+			}
+		}
+	}
+	for _, alert := range append(append([]string{}, f.IgnoredAlerts.PreUpgrade...), f.IgnoredAlerts.PostUpgrade...) {
+		if !model.IsValidMetricName(model.LabelValue(alert)) {
+			errs = append(errs, fmt.Errorf("healthCheck.alerts.ignoredAlerts contains an invalid alert name %q", alert))
+		}
+	}
+	for _, matcher := range f.LabelMatchers {
+		if _, err := parseLabelMatcher(matcher); err != nil {
+			errs = append(errs, fmt.Errorf("healthCheck.alerts.labelMatchers: %v", err))
+		}
+	}
+	if f.MinimumAgeMinutes.PreUpgrade < 0 || f.MinimumAgeMinutes.PostUpgrade < 0 {
+		errs = append(errs, fmt.Errorf("healthCheck.alerts.minimumAgeMinutes must not be negative"))
+	}
+	return errs
+}
+
+// Query returns the PromQL query of the firing alerts failing the health check in the phase.
+// With a minimum age, only the alerts which have been active continuously for that long are returned.
+// The filter must be valid.
+func (f *AlertFilterConfig) Query(phase HealthCheckPhase) string {
+	matchers := []labelMatcher{{name: "alertstate", op: "=", value: "firing"}}
+
+	for i, s := range severities {
+		if s != f.MinimumSeverity {
+			continue
+		}
+		if i == 0 {
+			matchers = append(matchers, labelMatcher{name: "severity", op: "=", value: s})
+		} else {
+			matchers = append(matchers, labelMatcher{name: "severity", op: "=~", value: strings.Join(severities[:i+1], "|")})
+		}
+	}
+	if len(f.IncludeNamespaces) > 0 {
+		matchers = append(matchers, labelMatcher{name: "namespace", op: "=~", value: strings.Join(f.IncludeNamespaces, "|")})
+	}
+	if len(f.ExcludeNamespaces) > 0 {
+		matchers = append(matchers, labelMatcher{name: "namespace", op: "!~", value: strings.Join(f.ExcludeNamespaces, "|")})
+	}
+	ignored := f.IgnoredAlerts.Of(phase)
+	minimumAge := f.MinimumAgeMinutes.PreUpgrade
+	if phase == PostUpgrade {
+		minimumAge = f.MinimumAgeMinutes.PostUpgrade
+	}
+	if len(ignored) > 0 {
+		// Alert names are metric names, they have nothing to escape
+		matchers = append(matchers, labelMatcher{name: "alertname", op: "!~", value: strings.Join(ignored, "|")})
+	}
+	for _, s := range f.LabelMatchers {
+		m, err := parseLabelMatcher(s)
+		if err == nil {
+			matchers = append(matchers, m)
+		}
+	}
+
+	alerts := "ALERTS" + selector(matchers)
+	if minimumAge > 0 {
+		// ALERTS_FOR_STATE holds when the alert became active, it is reset when the alert resolves.
+		// It carries the alert labels but alertstate.
+		forState := "ALERTS_FOR_STATE" + selector(matchers[1:])
+		return fmt.Sprintf("%s and ignoring(alertstate) (time() - %s > %d)", alerts, forState, minimumAge*60)
+	}
+	return alerts
+}
+
+// selector returns the PromQL selector of the label matchers, which can be empty
+func selector(matchers []labelMatcher) string {
+	if len(matchers) == 0 {
+		return ""
+	}
+	selectors := []string{}
+	for _, m := range matchers {
+		selectors = append(selectors, m.String())
+	}
+	return fmt.Sprintf("{%s}", strings.Join(selectors, ","))
+}
+
+// deepCopy returns a copy of the alert filter sharing nothing with it
+func (f AlertFilterConfig) deepCopy() AlertFilterConfig {
+	f.IncludeNamespaces = append([]string(nil), f.IncludeNamespaces...)
+	f.ExcludeNamespaces = append([]string(nil), f.ExcludeNamespaces...)
+	f.IgnoredAlerts = f.IgnoredAlerts.deepCopy()
+	f.LabelMatchers = append([]string(nil), f.LabelMatchers...)
+	return f
+}
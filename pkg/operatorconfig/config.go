@@ -0,0 +1,447 @@
+package operatorconfig
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/go-multierror"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	// Name of the ConfigMap holding the operator configuration, in the operator namespace
+	ConfigMapName = "managed-upgrade-operator-config"
+	// Key of the ConfigMap holding the configuration document
+	ConfigKey = "config.yaml"
+)
+
+// Config is the operator wide configuration. Every field has a default, so the ConfigMap only needs the overrides.
+type Config struct {
+	Monitoring   MonitoringConfig   `json:"monitoring"`
+	Maintenance  MaintenanceConfig  `json:"maintenance"`
+	HealthCheck  HealthCheckConfig  `json:"healthCheck"`
+	Verification VerificationConfig `json:"verification"`
+	Regression   RegressionConfig   `json:"regression"`
+	Monitor      MonitorConfig      `json:"monitor"`
+	Scale        ScaleConfig        `json:"scale"`
+	Estimate     EstimateConfig     `json:"estimate"`
+	Policy       PolicyConfig       `json:"policy"`
+	Reporter     ReporterConfig     `json:"reporter"`
+}
+
+// MonitoringConfig locates the cluster monitoring stack
+type MonitoringConfig struct {
+	// Namespace of Prometheus and Alertmanager
+	Namespace string `json:"namespace"`
+	// URL of the Prometheus service, its certificate must be signed by the service CA
+	PrometheusURL string `json:"prometheusURL"`
+	// Time given to Prometheus to evaluate a query
+	QueryTimeoutSeconds int `json:"queryTimeoutSeconds"`
+	// Name of the Alertmanager route
+	AlertmanagerRoute string `json:"alertmanagerRoute"`
+	// Service account whose token is used to query Alertmanager
+	ServiceAccount string `json:"serviceAccount"`
+}
+
+// MaintenanceConfig describes the alerts silenced during the maintenance windows
+type MaintenanceConfig struct {
+	// Regex of the severities silenced while upgrading
+	SilencedSeverities string `json:"silencedSeverities"`
+	// Regex of the namespaces whose alerts are silenced while upgrading
+	SilencedNamespaces string `json:"silencedNamespaces"`
+	// Regex of the critical alerts which are expected while the control plane upgrades and can be silenced
+	ControlPlaneIgnoredCriticalAlerts string `json:"controlPlaneIgnoredCriticalAlerts"`
+}
+
+// HealthCheckConfig describes the cluster health checks and the alerts which fail them
+type HealthCheckConfig struct {
+	// Health checks performed before the upgrade, by name
+	PreUpgrade []string `json:"preUpgrade"`
+	// Health checks performed after the upgrade, by name
+	PostUpgrade []string `json:"postUpgrade"`
+	// Health checks whose failures only warn and do not block the upgrade, by name
+	WarnOnly []string `json:"warnOnly"`
+	// Firing alerts which fail the CriticalAlerts health check
+	Alerts AlertFilterConfig `json:"alerts"`
+	// ClusterOperator conditions which fail the ClusterOperators health check
+	Operators OperatorCheckConfig `json:"operators"`
+	// How long the health checks performed after the upgrade must pass continuously before the upgrade is done
+	SettleMinutes int `json:"settleMinutes"`
+}
+
+// OperatorCheckConfig describes when the ClusterOperator conditions fail the ClusterOperators health check.
+// Degraded=True and Available=False fail it, so does Upgradeable=False before a minor version upgrade.
+type OperatorCheckConfig struct {
+	// How long a condition must have been stable, in minutes. Before the upgrade, the failing conditions
+	// which changed more recently only warn. After the upgrade, the healthy ones which did fail the check.
+	StableMinutes int `json:"stableMinutes"`
+	// Operators whose failing conditions are reported without failing the health check, in each phase
+	IgnoredOperators PhaseNamesConfig `json:"ignoredOperators"`
+}
+
+// PhaseNamesConfig lists names, like alerts or operators, before and after the upgrade
+type PhaseNamesConfig struct {
+	PreUpgrade  []string `json:"preUpgrade"`
+	PostUpgrade []string `json:"postUpgrade"`
+}
+
+// Of returns the names of the phase
+func (n PhaseNamesConfig) Of(phase HealthCheckPhase) []string {
+	if phase == PostUpgrade {
+		return n.PostUpgrade
+	}
+	return n.PreUpgrade
+}
+
+func (n PhaseNamesConfig) deepCopy() PhaseNamesConfig {
+	return PhaseNamesConfig{
+		PreUpgrade:  append([]string(nil), n.PreUpgrade...),
+		PostUpgrade: append([]string(nil), n.PostUpgrade...),
+	}
+}
+
+// VerificationConfig describes the workloads checked after the upgrade
+type VerificationConfig struct {
+	// Prefixes of the namespaces whose replicasets and daemonsets must be ready
+	NamespacePrefixes []string `json:"namespacePrefixes"`
+}
+
+// RegressionConfig describes the SLO metrics compared over a window before the upgrade commenced and after it was verified
+type RegressionConfig struct {
+	// Length of the windows compared, in minutes
+	WindowMinutes int `json:"windowMinutes"`
+	// Metrics compared, nothing is compared when empty
+	Metrics []RegressionMetric `json:"metrics"`
+}
+
+// RegressionMetric is a PromQL expression which increases when the cluster regresses, like an error rate or a latency.
+// It regresses when its average over the window increases by more than any of its thresholds.
+type RegressionMetric struct {
+	// Name of the metric in the comparison recorded in the history
+	Name string `json:"name"`
+	// PromQL expression returning a single series
+	Query string `json:"query"`
+	// Largest increase of the average, in the unit of the expression
+	MaxIncrease *float64 `json:"maxIncrease,omitempty"`
+	// Largest increase of the average relative to before the upgrade, in percent
+	MaxIncreasePercent *float64 `json:"maxIncreasePercent,omitempty"`
+}
+
+// MonitorConfig describes the critical signals evaluated while the cluster upgrades.
+// A signal tripping while the workers upgrade pauses the worker MachineConfigPool until the upgrade is resumed.
+type MonitorConfig struct {
+	// Time between two evaluations of the signals
+	IntervalSeconds int `json:"intervalSeconds"`
+	// Signals evaluated, nothing is monitored when empty
+	Signals []MonitorSignal `json:"signals"`
+}
+
+// MonitorSignal is a PromQL expression which trips while it returns any series, like the expression of an alerting rule
+type MonitorSignal struct {
+	// Name of the signal in the evidence recorded when it trips
+	Name string `json:"name"`
+	// PromQL expression returning the series which trip the signal
+	Query string `json:"query"`
+}
+
+// ScaleConfig describes the extra workers added for the upgrade
+type ScaleConfig struct {
+	// Time given to the extra workers to become ready
+	TimeoutMinutes int `json:"timeoutMinutes"`
+}
+
+// EstimateConfig holds the durations used when there is no past upgrade to learn from
+type EstimateConfig struct {
+	ControlPlaneMinutes int `json:"controlPlaneMinutes"`
+	NodeMinutes         int `json:"nodeMinutes"`
+}
+
+// PolicyConfig describes where the upgrade policies of the cluster are pulled from
+type PolicyConfig struct {
+	// Base URL of the OCM-style API serving the upgrade policies, policies are not pulled when empty
+	URL string `json:"url"`
+	// Time between two pulls of the policies
+	IntervalMinutes int `json:"intervalMinutes"`
+	// Name of the UpgradeConfig created from the policy
+	UpgradeConfigName string `json:"upgradeConfigName"`
+	// Only apply a policy if its version is an available upgrade of the cluster according to the API
+	CheckAllowedVersions bool `json:"checkAllowedVersions"`
+}
+
+// ReporterConfig describes where the upgrade state transitions are reported
+type ReporterConfig struct {
+	// URL the reports are POSTed to, nothing is reported when empty
+	URL string `json:"url"`
+	// Number of reports kept while the endpoint is unreachable, the oldest are dropped first
+	BufferSize int `json:"bufferSize"`
+}
+
+// DefaultConfig returns the configuration used when the ConfigMap does not exist
+func DefaultConfig() *Config {
+	return &Config{
+		Monitoring: MonitoringConfig{
+			Namespace:           "openshift-monitoring",
+			PrometheusURL:       "https://prometheus-k8s.openshift-monitoring.svc:9091",
+			QueryTimeoutSeconds: 30,
+			AlertmanagerRoute:   "alertmanager-main",
+			ServiceAccount:      "prometheus-k8s",
+		},
+		Maintenance: MaintenanceConfig{
+			SilencedSeverities: "(warning|info|none)",
+			SilencedNamespaces: "(^openshift.*|^kube.*|^redhat.*|^default$)",
+			// Generally upgrades should not fire critical alerts but there are some critical alerts that will fire.
+			// e.g. 'etcdMembersDown' happens as the masters drain/reboot and a master is offline but this is expected and will resolve.
+			ControlPlaneIgnoredCriticalAlerts: "(etcdMembersDown)",
+		},
+		HealthCheck: HealthCheckConfig{
+			PreUpgrade:  []string{"CriticalAlerts", "ClusterOperators"},
+			PostUpgrade: []string{"CriticalAlerts", "ClusterOperators"},
+			Alerts: AlertFilterConfig{
+				MinimumSeverity:   "critical",
+				IncludeNamespaces: []string{"openshift.*", "kube.*", "default"},
+				ExcludeNamespaces: []string{"openshift-customer-monitoring"},
+				IgnoredAlerts: PhaseNamesConfig{
+					PreUpgrade:  []string{"ClusterUpgradingSRE", "DNSErrors05MinSRE", "MetricsClientSendFailingSRE"},
+					PostUpgrade: []string{"ClusterUpgradingSRE", "DNSErrors05MinSRE", "MetricsClientSendFailingSRE"},
+				},
+			},
+		},
+		Verification: VerificationConfig{
+			NamespacePrefixes: []string{"default", "kube", "openshift"},
+		},
+		Regression: RegressionConfig{
+			WindowMinutes: 30,
+		},
+		Monitor: MonitorConfig{
+			IntervalSeconds: 60,
+		},
+		Scale: ScaleConfig{
+			TimeoutMinutes: 30,
+		},
+		Estimate: EstimateConfig{
+			ControlPlaneMinutes: 90,
+			NodeMinutes:         8,
+		},
+		Policy: PolicyConfig{
+			IntervalMinutes:   10,
+			UpgradeConfigName: "osd-upgrade-config",
+		},
+		Reporter: ReporterConfig{
+			BufferSize: 200,
+		},
+	}
+}
+
+// Parse reads the configuration from the ConfigMap, on top of the defaults, and validates it
+func Parse(cm *corev1.ConfigMap) (*Config, error) {
+	cfg := DefaultConfig()
+	data, ok := cm.Data[ConfigKey]
+	if !ok {
+		return nil, fmt.Errorf("configmap %s/%s has no %s", cm.Namespace, cm.Name, ConfigKey)
+	}
+	err := yaml.UnmarshalStrict([]byte(data), cfg)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse %s of configmap %s/%s: %v", ConfigKey, cm.Namespace, cm.Name, err)
+	}
+	err = cfg.Validate()
+	if err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// Validate returns all the problems of the configuration
+func (cfg *Config) Validate() error {
+	var result *multierror.Error
+	required := map[string]string{
+		"monitoring.namespace":         cfg.Monitoring.Namespace,
+		"monitoring.prometheusURL":     cfg.Monitoring.PrometheusURL,
+		"monitoring.alertmanagerRoute": cfg.Monitoring.AlertmanagerRoute,
+		"monitoring.serviceAccount":    cfg.Monitoring.ServiceAccount,
+	}
+	for field, value := range required {
+		if len(value) == 0 {
+			result = multierror.Append(result, fmt.Errorf("%s must be set", field))
+		}
+	}
+	regexes := map[string]string{
+		"maintenance.silencedSeverities":                cfg.Maintenance.SilencedSeverities,
+		"maintenance.silencedNamespaces":                cfg.Maintenance.SilencedNamespaces,
+		"maintenance.controlPlaneIgnoredCriticalAlerts": cfg.Maintenance.ControlPlaneIgnoredCriticalAlerts,
+	}
+	for field, value := range regexes {
+		if len(value) == 0 {
+			result = multierror.Append(result, fmt.Errorf("%s must be set", field))
+			continue
+		}
+		if _, err := regexp.Compile(value); err != nil {
+			result = multierror.Append(result, fmt.Errorf("%s is not a valid regex: %v", field, err))
+		}
+	}
+	result = multierror.Append(result, cfg.HealthCheck.Alerts.validate()...)
+	for _, name := range cfg.HealthCheckNames() {
+		if len(name) == 0 {
+			result = multierror.Append(result, fmt.Errorf("healthCheck names an empty health check"))
+		}
+	}
+	if cfg.HealthCheck.Operators.StableMinutes < 0 {
+		result = multierror.Append(result, fmt.Errorf("healthCheck.operators.stableMinutes must not be negative"))
+	}
+	ignoredOperators := cfg.HealthCheck.Operators.IgnoredOperators
+	for _, name := range append(append([]string{}, ignoredOperators.PreUpgrade...), ignoredOperators.PostUpgrade...) {
+		if len(name) == 0 {
+			result = multierror.Append(result, fmt.Errorf("healthCheck.operators.ignoredOperators names an empty operator"))
+		}
+	}
+	if cfg.HealthCheck.SettleMinutes < 0 {
+		result = multierror.Append(result, fmt.Errorf("healthCheck.settleMinutes must not be negative"))
+	}
+	if cfg.Regression.WindowMinutes <= 0 {
+		result = multierror.Append(result, fmt.Errorf("regression.windowMinutes must be positive"))
+	}
+	names := map[string]bool{}
+	for i, metric := range cfg.Regression.Metrics {
+		field := fmt.Sprintf("regression.metrics[%d]", i)
+		if len(metric.Name) == 0 {
+			result = multierror.Append(result, fmt.Errorf("%s.name must be set", field))
+		} else if names[metric.Name] {
+			result = multierror.Append(result, fmt.Errorf("%s.name %s is not unique", field, metric.Name))
+		}
+		names[metric.Name] = true
+		if len(metric.Query) == 0 {
+			result = multierror.Append(result, fmt.Errorf("%s.query must be set", field))
+		}
+		if metric.MaxIncrease == nil && metric.MaxIncreasePercent == nil {
+			result = multierror.Append(result, fmt.Errorf("%s must set maxIncrease or maxIncreasePercent", field))
+		}
+		if (metric.MaxIncrease != nil && *metric.MaxIncrease < 0) || (metric.MaxIncreasePercent != nil && *metric.MaxIncreasePercent < 0) {
+			result = multierror.Append(result, fmt.Errorf("%s thresholds must not be negative", field))
+		}
+	}
+	if cfg.Monitor.IntervalSeconds <= 0 {
+		result = multierror.Append(result, fmt.Errorf("monitor.intervalSeconds must be positive"))
+	}
+	signals := map[string]bool{}
+	for i, signal := range cfg.Monitor.Signals {
+		field := fmt.Sprintf("monitor.signals[%d]", i)
+		if len(signal.Name) == 0 {
+			result = multierror.Append(result, fmt.Errorf("%s.name must be set", field))
+		} else if signals[signal.Name] {
+			result = multierror.Append(result, fmt.Errorf("%s.name %s is not unique", field, signal.Name))
+		}
+		signals[signal.Name] = true
+		if len(signal.Query) == 0 {
+			result = multierror.Append(result, fmt.Errorf("%s.query must be set", field))
+		}
+	}
+	if cfg.Scale.TimeoutMinutes <= 0 {
+		result = multierror.Append(result, fmt.Errorf("scale.timeoutMinutes must be positive"))
+	}
+	if cfg.Estimate.ControlPlaneMinutes <= 0 {
+		result = multierror.Append(result, fmt.Errorf("estimate.controlPlaneMinutes must be positive"))
+	}
+	if cfg.Estimate.NodeMinutes <= 0 {
+		result = multierror.Append(result, fmt.Errorf("estimate.nodeMinutes must be positive"))
+	}
+	if cfg.Monitoring.QueryTimeoutSeconds <= 0 {
+		result = multierror.Append(result, fmt.Errorf("monitoring.queryTimeoutSeconds must be positive"))
+	}
+	urls := map[string]string{
+		"monitoring.prometheusURL": cfg.Monitoring.PrometheusURL,
+		"policy.url":               cfg.Policy.URL,
+		"reporter.url":             cfg.Reporter.URL,
+	}
+	for field, value := range urls {
+		if len(value) == 0 {
+			continue
+		}
+		u, err := url.Parse(value)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || len(u.Host) == 0 {
+			result = multierror.Append(result, fmt.Errorf("%s must be an http or https URL", field))
+		}
+	}
+	if cfg.Policy.IntervalMinutes <= 0 {
+		result = multierror.Append(result, fmt.Errorf("policy.intervalMinutes must be positive"))
+	}
+	if len(cfg.Policy.UpgradeConfigName) == 0 {
+		result = multierror.Append(result, fmt.Errorf("policy.upgradeConfigName must be set"))
+	}
+	if cfg.Reporter.BufferSize <= 0 {
+		result = multierror.Append(result, fmt.Errorf("reporter.bufferSize must be positive"))
+	}
+	return result.ErrorOrNil()
+}
+
+// RegressionWindow returns the length of the windows the SLO metrics are compared over
+func (cfg *Config) RegressionWindow() time.Duration {
+	return time.Duration(cfg.Regression.WindowMinutes) * time.Minute
+}
+
+// MonitorInterval returns the time between two evaluations of the critical signals
+func (cfg *Config) MonitorInterval() time.Duration {
+	return time.Duration(cfg.Monitor.IntervalSeconds) * time.Second
+}
+
+// ScaleTimeout returns the time given to the extra workers to become ready
+func (cfg *Config) ScaleTimeout() time.Duration {
+	return time.Duration(cfg.Scale.TimeoutMinutes) * time.Minute
+}
+
+// QueryTimeout returns the time given to Prometheus to evaluate a query
+func (cfg *Config) QueryTimeout() time.Duration {
+	return time.Duration(cfg.Monitoring.QueryTimeoutSeconds) * time.Second
+}
+
+// SettlePeriod returns how long the health checks performed after the upgrade must pass continuously
+func (cfg *Config) SettlePeriod() time.Duration {
+	return time.Duration(cfg.HealthCheck.SettleMinutes) * time.Minute
+}
+
+// OperatorStablePeriod returns how long a ClusterOperator condition must have been stable for the health check
+func (cfg *Config) OperatorStablePeriod() time.Duration {
+	return time.Duration(cfg.HealthCheck.Operators.StableMinutes) * time.Minute
+}
+
+// PolicyInterval returns the time between two pulls of the upgrade policies
+func (cfg *Config) PolicyInterval() time.Duration {
+	return time.Duration(cfg.Policy.IntervalMinutes) * time.Minute
+}
+
+// ControlPlaneDuration returns the control plane upgrade duration used when there is no past upgrade
+func (cfg *Config) ControlPlaneDuration() time.Duration {
+	return time.Duration(cfg.Estimate.ControlPlaneMinutes) * time.Minute
+}
+
+// NodeDuration returns the node upgrade duration used when there is no past upgrade
+func (cfg *Config) NodeDuration() time.Duration {
+	return time.Duration(cfg.Estimate.NodeMinutes) * time.Minute
+}
+
+// HealthChecks returns the health checks performed in the phase, by name
+func (cfg *Config) HealthChecks(phase HealthCheckPhase) []string {
+	if phase == PostUpgrade {
+		return cfg.HealthCheck.PostUpgrade
+	}
+	return cfg.HealthCheck.PreUpgrade
+}
+
+// HealthCheckNames returns all the health checks the configuration names
+func (cfg *Config) HealthCheckNames() []string {
+	names := append([]string{}, cfg.HealthCheck.PreUpgrade...)
+	names = append(names, cfg.HealthCheck.PostUpgrade...)
+	return append(names, cfg.HealthCheck.WarnOnly...)
+}
+
+// IsVerifiedNamespace returns true if the workloads of the namespace are checked after the upgrade
+func (cfg *Config) IsVerifiedNamespace(namespace string) bool {
+	for _, prefix := range cfg.Verification.NamespacePrefixes {
+		if strings.HasPrefix(namespace, prefix) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,222 @@
+package operatorconfig
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http/httptest"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/yaml"
+)
+
+var _ = Describe("OperatorConfig", func() {
+	var cm *corev1.ConfigMap
+
+	BeforeEach(func() {
+		cm = &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "test-namespace", Name: ConfigMapName, ResourceVersion: "7"},
+			Data:       map[string]string{},
+		}
+	})
+
+	AfterEach(func() {
+		Reset()
+	})
+
+	Context("When parsing the ConfigMap", func() {
+		It("applies the overrides on top of the defaults", func() {
+			cm.Data[ConfigKey] = "scale:\n  timeoutMinutes: 45\nhealthCheck:\n  alerts:\n    ignoredAlerts:\n      preUpgrade: [NoisyAlert]\n"
+			cfg, err := Parse(cm)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(cfg.Scale.TimeoutMinutes).To(Equal(45))
+			Expect(cfg.HealthCheck.Alerts.IgnoredAlerts.PreUpgrade).To(Equal([]string{"NoisyAlert"}))
+			Expect(cfg.HealthCheck.Alerts.IgnoredAlerts.PostUpgrade).To(Equal(DefaultConfig().HealthCheck.Alerts.IgnoredAlerts.PostUpgrade))
+			Expect(cfg.Monitoring).To(Equal(DefaultConfig().Monitoring))
+			Expect(cfg.Estimate).To(Equal(DefaultConfig().Estimate))
+		})
+		It("reads the defaults from the example ConfigMap", func() {
+			data, err := ioutil.ReadFile("../../deploy/operator_config.yaml")
+			Expect(err).NotTo(HaveOccurred())
+			example := &corev1.ConfigMap{}
+			Expect(yaml.Unmarshal(data, example)).To(Succeed())
+			cfg, err := Parse(example)
+			Expect(err).NotTo(HaveOccurred())
+			// Empty lists read as empty rather than unset
+			cfg.HealthCheck.WarnOnly = nil
+			cfg.HealthCheck.Alerts.LabelMatchers = nil
+			cfg.HealthCheck.Operators.IgnoredOperators = PhaseNamesConfig{}
+			cfg.Regression.Metrics = nil
+			cfg.Monitor.Signals = nil
+			Expect(cfg).To(Equal(DefaultConfig()))
+		})
+		It("fails without the configuration key", func() {
+			_, err := Parse(cm)
+			Expect(err).To(HaveOccurred())
+		})
+		It("fails on unknown fields", func() {
+			cm.Data[ConfigKey] = "scale:\n  timeoutMinute: 45\n"
+			_, err := Parse(cm)
+			Expect(err).To(HaveOccurred())
+		})
+		It("reports every invalid field", func() {
+			cm.Data[ConfigKey] = "maintenance:\n  silencedNamespaces: \"(^openshift\"\nestimate:\n  nodeMinutes: 0\nmonitoring:\n  namespace: \"\"\n"
+			_, err := Parse(cm)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("maintenance.silencedNamespaces is not a valid regex"))
+			Expect(err.Error()).To(ContainSubstring("estimate.nodeMinutes must be positive"))
+			Expect(err.Error()).To(ContainSubstring("monitoring.namespace must be set"))
+		})
+	})
+
+	Context("When configuring the SLO metrics", func() {
+		It("reads the metrics and their thresholds", func() {
+			cm.Data[ConfigKey] = "regression:\n  metrics:\n  - name: etcd-fsync\n    query: etcd_fsync\n    maxIncreasePercent: 50\n"
+			cfg, err := Parse(cm)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(cfg.Regression.Metrics).To(HaveLen(1))
+			Expect(*cfg.Regression.Metrics[0].MaxIncreasePercent).To(Equal(50.0))
+			Expect(cfg.Regression.Metrics[0].MaxIncrease).To(BeNil())
+		})
+		It("rejects the metrics without a query, a unique name or a threshold", func() {
+			cm.Data[ConfigKey] = "regression:\n  metrics:\n  - name: errors\n    maxIncrease: -1\n  - name: errors\n    query: errors\n"
+			_, err := Parse(cm)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("regression.metrics[0].query must be set"))
+			Expect(err.Error()).To(ContainSubstring("regression.metrics[0] thresholds must not be negative"))
+			Expect(err.Error()).To(ContainSubstring("regression.metrics[1].name errors is not unique"))
+			Expect(err.Error()).To(ContainSubstring("regression.metrics[1] must set maxIncrease or maxIncreasePercent"))
+		})
+	})
+
+	Context("When configuring the monitored signals", func() {
+		It("reads the signals", func() {
+			cm.Data[ConfigKey] = "monitor:\n  intervalSeconds: 30\n  signals:\n  - name: etcd-no-leader\n    query: etcd_server_has_leader == 0\n"
+			cfg, err := Parse(cm)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(cfg.MonitorInterval()).To(Equal(30 * time.Second))
+			Expect(cfg.Monitor.Signals).To(Equal([]MonitorSignal{{Name: "etcd-no-leader", Query: "etcd_server_has_leader == 0"}}))
+		})
+		It("rejects the signals without a query or a unique name", func() {
+			cm.Data[ConfigKey] = "monitor:\n  intervalSeconds: 0\n  signals:\n  - name: errors\n  - name: errors\n    query: errors > 0\n"
+			_, err := Parse(cm)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("monitor.intervalSeconds must be positive"))
+			Expect(err.Error()).To(ContainSubstring("monitor.signals[0].query must be set"))
+			Expect(err.Error()).To(ContainSubstring("monitor.signals[1].name errors is not unique"))
+		})
+	})
+
+	Context("When generating the alerts query", func() {
+		var filter AlertFilterConfig
+
+		BeforeEach(func() {
+			filter = DefaultConfig().HealthCheck.Alerts
+		})
+
+		It("selects the critical alerts of the platform namespaces by default", func() {
+			Expect(filter.Query(PreUpgrade)).To(Equal(`ALERTS{alertstate="firing",severity="critical",namespace=~"openshift.*|kube.*|default",namespace!~"openshift-customer-monitoring",alertname!~"ClusterUpgradingSRE|DNSErrors05MinSRE|MetricsClientSendFailingSRE"}`))
+		})
+		It("ignores the alerts of the phase", func() {
+			filter.IgnoredAlerts.PreUpgrade = []string{"PreAlert"}
+			filter.IgnoredAlerts.PostUpgrade = nil
+			Expect(filter.Query(PreUpgrade)).To(ContainSubstring(`alertname!~"PreAlert"`))
+			Expect(filter.Query(PostUpgrade)).NotTo(ContainSubstring("alertname"))
+		})
+		It("includes the severities down to the threshold", func() {
+			filter.MinimumSeverity = "warning"
+			Expect(filter.Query(PreUpgrade)).To(ContainSubstring(`severity=~"critical|warning"`))
+		})
+		It("considers all namespaces when none is included", func() {
+			filter.IncludeNamespaces = nil
+			filter.ExcludeNamespaces = nil
+			Expect(filter.Query(PreUpgrade)).NotTo(ContainSubstring("namespace"))
+		})
+		It("adds the label matchers, escaped", func() {
+			filter.LabelMatchers = []string{`team = "sre"`, `pod!~"test-.*\\d"`}
+			Expect(filter.Query(PreUpgrade)).To(HaveSuffix(`,team="sre",pod!~"test-.*\\d"}`))
+		})
+		It("only selects the alerts active for the minimum age", func() {
+			filter.IncludeNamespaces = nil
+			filter.ExcludeNamespaces = nil
+			filter.IgnoredAlerts.PostUpgrade = nil
+			filter.MinimumAgeMinutes.PostUpgrade = 10
+			Expect(filter.Query(PostUpgrade)).To(Equal(`ALERTS{alertstate="firing",severity="critical"} and ignoring(alertstate) (time() - ALERTS_FOR_STATE{severity="critical"} > 600)`))
+			Expect(filter.Query(PreUpgrade)).NotTo(ContainSubstring("ALERTS_FOR_STATE"))
+		})
+		It("measures the minimum age from when the alert became active again, not on two points in time", func() {
+			// An alert firing now and the minimum age ago may have resolved in between,
+			// the time it became active is reset when it resolves
+			filter.MinimumAgeMinutes.PreUpgrade = 5
+			query := filter.Query(PreUpgrade)
+			Expect(query).NotTo(ContainSubstring("offset"))
+			Expect(query).To(ContainSubstring(`(time() - ALERTS_FOR_STATE{severity="critical",namespace=~"openshift.*|kube.*|default",`))
+			Expect(query).To(HaveSuffix(`alertname!~"ClusterUpgradingSRE|DNSErrors05MinSRE|MetricsClientSendFailingSRE"} > 300)`))
+		})
+		It("rejects an invalid filter", func() {
+			cm.Data[ConfigKey] = strings.Join([]string{
+				"healthCheck:",
+				"  alerts:",
+				"    minimumSeverity: major",
+				"    includeNamespaces: [\"openshift(\"]",
+				"    ignoredAlerts:",
+				"      postUpgrade: [\"Bad Alert\"]",
+				"    labelMatchers: [\"team\", \"team=~\\\"(\\\"\"]",
+				"    minimumAgeMinutes:",
+				"      postUpgrade: -5",
+				"  settleMinutes: -1",
+				"  operators:",
+				"    stableMinutes: -1",
+			}, "\n")
+			_, err := Parse(cm)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("healthCheck.alerts.minimumSeverity must be one of critical,warning,info"))
+			Expect(err.Error()).To(ContainSubstring("healthCheck.alerts.includeNamespaces contains an invalid regex"))
+			Expect(err.Error()).To(ContainSubstring(`invalid alert name "Bad Alert"`))
+			Expect(err.Error()).To(ContainSubstring(`"team" is not a label matcher`))
+			Expect(err.Error()).To(ContainSubstring(`has an invalid regex`))
+			Expect(err.Error()).To(ContainSubstring("healthCheck.alerts.minimumAgeMinutes must not be negative"))
+			Expect(err.Error()).To(ContainSubstring("healthCheck.settleMinutes must not be negative"))
+			Expect(err.Error()).To(ContainSubstring("healthCheck.operators.stableMinutes must not be negative"))
+		})
+	})
+
+	Context("When the configuration changes", func() {
+		It("makes the new configuration effective", func() {
+			cfg := DefaultConfig()
+			cfg.Scale.TimeoutMinutes = 10
+			Set(cfg, fmt.Sprintf("%s/%s", cm.Namespace, cm.Name), cm.ResourceVersion)
+			Expect(Get().Scale.TimeoutMinutes).To(Equal(10))
+			Expect(GetStatus().Source).To(Equal("test-namespace/" + ConfigMapName))
+		})
+		It("does not share the effective configuration", func() {
+			Get().Verification.NamespacePrefixes[0] = "changed"
+			Expect(Get().Verification.NamespacePrefixes[0]).To(Equal("default"))
+		})
+		It("keeps the effective configuration when a load fails", func() {
+			cfg := DefaultConfig()
+			cfg.Scale.TimeoutMinutes = 10
+			Set(cfg, "test-namespace/"+ConfigMapName, "7")
+			SetError(fmt.Errorf("invalid"))
+			Expect(Get().Scale.TimeoutMinutes).To(Equal(10))
+			Expect(GetStatus().Error).To(Equal("invalid"))
+		})
+	})
+
+	Context("When serving the effective configuration", func() {
+		It("returns it as JSON", func() {
+			Set(DefaultConfig(), "test-namespace/"+ConfigMapName, "7")
+			recorder := httptest.NewRecorder()
+			Handler().ServeHTTP(recorder, httptest.NewRequest("GET", "/config", nil))
+			status := Status{}
+			Expect(json.Unmarshal(recorder.Body.Bytes(), &status)).To(Succeed())
+			Expect(status.ResourceVersion).To(Equal("7"))
+			Expect(status.Config).To(Equal(DefaultConfig()))
+		})
+	})
+})
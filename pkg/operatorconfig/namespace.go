@@ -0,0 +1,26 @@
+package operatorconfig
+
+import (
+	"errors"
+	"os"
+
+	"github.com/operator-framework/operator-sdk/pkg/k8sutil"
+)
+
+// Used when running outside of the cluster, where the operator namespace cannot be found
+const defaultOperatorNamespace = "openshift-managed-upgrade-operator"
+
+// OperatorNamespace returns the namespace the operator is deployed in, which holds its ConfigMaps
+func OperatorNamespace() (string, error) {
+	namespace, err := k8sutil.GetOperatorNamespace()
+	if err == nil {
+		return namespace, nil
+	}
+	if errors.Is(err, k8sutil.ErrRunLocal) || errors.Is(err, k8sutil.ErrNoNamespace) {
+		if ns, ok := os.LookupEnv("OPERATOR_NAMESPACE"); ok {
+			return ns, nil
+		}
+		return defaultOperatorNamespace, nil
+	}
+	return "", err
+}
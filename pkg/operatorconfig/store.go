@@ -0,0 +1,104 @@
+package operatorconfig
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Status tells where the effective configuration comes from
+type Status struct {
+	// ConfigMap the configuration was loaded from, empty when running with the defaults
+	Source string `json:"source,omitempty"`
+	// ResourceVersion of the ConfigMap the configuration was loaded from
+	ResourceVersion string `json:"resourceVersion,omitempty"`
+	// Time the configuration was applied
+	LoadedAt time.Time `json:"loadedAt"`
+	// Error of the last load, the previous configuration stays effective when the ConfigMap is invalid
+	Error string `json:"error,omitempty"`
+	// Effective configuration
+	Config *Config `json:"config"`
+}
+
+var (
+	mutex   sync.RWMutex
+	current = Status{Config: DefaultConfig(), LoadedAt: time.Now()}
+)
+
+// Get returns the effective configuration.
+// It is read again for each use so that changes to the ConfigMap are applied live.
+func Get() *Config {
+	mutex.RLock()
+	defer mutex.RUnlock()
+	return current.Config.DeepCopy()
+}
+
+// GetStatus returns the effective configuration along with where it comes from
+func GetStatus() Status {
+	mutex.RLock()
+	defer mutex.RUnlock()
+	status := current
+	status.Config = current.Config.DeepCopy()
+	return status
+}
+
+// Set makes the configuration loaded from the source effective
+func Set(cfg *Config, source string, resourceVersion string) {
+	mutex.Lock()
+	defer mutex.Unlock()
+	current = Status{
+		Source:          source,
+		ResourceVersion: resourceVersion,
+		LoadedAt:        time.Now(),
+		Config:          cfg.DeepCopy(),
+	}
+}
+
+// SetError records the error of a load, the effective configuration is left unchanged
+func SetError(err error) {
+	mutex.Lock()
+	defer mutex.Unlock()
+	current.Error = err.Error()
+}
+
+// Reset goes back to the default configuration
+func Reset() {
+	Set(DefaultConfig(), "", "")
+}
+
+// Handler serves the effective configuration as JSON
+func Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		err := json.NewEncoder(w).Encode(GetStatus())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+	})
+}
+
+// DeepCopy returns a copy of the configuration sharing nothing with it
+func (cfg *Config) DeepCopy() *Config {
+	out := *cfg
+	out.HealthCheck.Alerts = cfg.HealthCheck.Alerts.deepCopy()
+	out.HealthCheck.Operators.IgnoredOperators = cfg.HealthCheck.Operators.IgnoredOperators.deepCopy()
+	out.HealthCheck.PreUpgrade = append([]string(nil), cfg.HealthCheck.PreUpgrade...)
+	out.HealthCheck.PostUpgrade = append([]string(nil), cfg.HealthCheck.PostUpgrade...)
+	out.HealthCheck.WarnOnly = append([]string(nil), cfg.HealthCheck.WarnOnly...)
+	out.Verification.NamespacePrefixes = append([]string(nil), cfg.Verification.NamespacePrefixes...)
+	out.Regression.Metrics = nil
+	for _, metric := range cfg.Regression.Metrics {
+		if metric.MaxIncrease != nil {
+			v := *metric.MaxIncrease
+			metric.MaxIncrease = &v
+		}
+		if metric.MaxIncreasePercent != nil {
+			v := *metric.MaxIncreasePercent
+			metric.MaxIncreasePercent = &v
+		}
+		out.Regression.Metrics = append(out.Regression.Metrics, metric)
+	}
+	out.Monitor.Signals = append([]MonitorSignal(nil), cfg.Monitor.Signals...)
+	return &out
+}
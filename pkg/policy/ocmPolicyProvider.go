@@ -0,0 +1,173 @@
+package policy
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	configv1 "github.com/openshift/api/config/v1"
+	"github.com/openshift/managed-upgrade-operator/pkg/operatorconfig"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	clustersPath = "/api/clusters_mgmt/v1/clusters"
+	// Registry of the pull secret whose token authenticates the cluster to the API
+	pullSecretRegistry = "cloud.openshift.com"
+
+	defaultTimeout = 30 * time.Second
+)
+
+var (
+	pullSecretName = types.NamespacedName{Namespace: "openshift-config", Name: "pull-secret"}
+)
+
+type ocmPolicyProviderBuilder struct{}
+
+// NewClient returns a provider for the cluster, authenticated with the pull secret of the cluster
+func (b *ocmPolicyProviderBuilder) NewClient(c client.Client) (PolicyProvider, error) {
+	cfg := operatorconfig.Get()
+	if len(cfg.Policy.URL) == 0 {
+		return nil, fmt.Errorf("no upgrade policy URL is configured")
+	}
+
+	clusterVersion := &configv1.ClusterVersion{}
+	err := c.Get(context.TODO(), types.NamespacedName{Name: "version"}, clusterVersion)
+	if err != nil {
+		return nil, err
+	}
+	token, err := getPullSecretToken(c)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ocmPolicyProvider{
+		baseURL:    strings.TrimSuffix(cfg.Policy.URL, "/"),
+		externalID: string(clusterVersion.Spec.ClusterID),
+		token:      token,
+		client:     &http.Client{Timeout: defaultTimeout},
+	}, nil
+}
+
+// ocmPolicyProvider pulls the upgrade policies from an OCM-style clusters management API
+type ocmPolicyProvider struct {
+	baseURL string
+	// ID of the cluster in the ClusterVersion, known as the external ID by the API
+	externalID string
+	token      string
+	client     *http.Client
+}
+
+type clusterList struct {
+	Items []cluster `json:"items"`
+}
+
+type cluster struct {
+	ID         string         `json:"id"`
+	ExternalID string         `json:"external_id"`
+	Version    clusterVersion `json:"version"`
+}
+
+type clusterVersion struct {
+	ID                string   `json:"id"`
+	AvailableUpgrades []string `json:"available_upgrades"`
+}
+
+type upgradePolicyList struct {
+	Items []UpgradePolicy `json:"items"`
+}
+
+// Policies returns the upgrade policies of the cluster
+func (p *ocmPolicyProvider) Policies() ([]UpgradePolicy, error) {
+	c, err := p.getCluster()
+	if err != nil {
+		return nil, err
+	}
+	policies := &upgradePolicyList{}
+	err = p.get(fmt.Sprintf("%s/%s/upgrade_policies", clustersPath, url.PathEscape(c.ID)), nil, policies)
+	if err != nil {
+		return nil, err
+	}
+	return policies.Items, nil
+}
+
+// AvailableUpgrades returns the versions the cluster is allowed to upgrade to
+func (p *ocmPolicyProvider) AvailableUpgrades() ([]string, error) {
+	c, err := p.getCluster()
+	if err != nil {
+		return nil, err
+	}
+	return c.Version.AvailableUpgrades, nil
+}
+
+// getCluster finds the cluster by its external ID, the API identifies it by its own ID
+func (p *ocmPolicyProvider) getCluster() (*cluster, error) {
+	clusters := &clusterList{}
+	query := url.Values{"search": []string{fmt.Sprintf("external_id='%s'", p.externalID)}}
+	err := p.get(clustersPath, query, clusters)
+	if err != nil {
+		return nil, err
+	}
+	if len(clusters.Items) != 1 {
+		return nil, fmt.Errorf("found %d clusters with external ID %s", len(clusters.Items), p.externalID)
+	}
+	return &clusters.Items[0], nil
+}
+
+func (p *ocmPolicyProvider) get(path string, query url.Values, v interface{}) error {
+	req, err := http.NewRequest(http.MethodGet, p.baseURL+path, nil)
+	if err != nil {
+		return err
+	}
+	req.URL.RawQuery = query.Encode()
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Authorization", fmt.Sprintf("AccessToken %s:%s", p.externalID, p.token))
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("unable to reach the upgrade policy API: %v", err)
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("upgrade policy API replied %d to %s: %s", resp.StatusCode, path, string(body))
+	}
+	err = json.Unmarshal(body, v)
+	if err != nil {
+		return fmt.Errorf("invalid reply of the upgrade policy API to %s: %v", path, err)
+	}
+	return nil
+}
+
+// getPullSecretToken returns the token of the cluster pull secret authenticating the cluster to the API
+func getPullSecretToken(c client.Client) (string, error) {
+	secret := &corev1.Secret{}
+	err := c.Get(context.TODO(), pullSecretName, secret)
+	if err != nil {
+		return "", fmt.Errorf("unable to fetch the pull secret: %v", err)
+	}
+	dockerConfig := struct {
+		Auths map[string]struct {
+			Auth string `json:"auth"`
+		} `json:"auths"`
+	}{}
+	err = json.Unmarshal(secret.Data[corev1.DockerConfigJsonKey], &dockerConfig)
+	if err != nil {
+		return "", fmt.Errorf("unable to parse the pull secret: %v", err)
+	}
+	auth, ok := dockerConfig.Auths[pullSecretRegistry]
+	if !ok || len(auth.Auth) == 0 {
+		return "", fmt.Errorf("the pull secret has no token for %s", pullSecretRegistry)
+	}
+	return auth.Auth, nil
+}
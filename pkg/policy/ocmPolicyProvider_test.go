@@ -0,0 +1,123 @@
+package policy
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	configv1 "github.com/openshift/api/config/v1"
+	"github.com/openshift/managed-upgrade-operator/pkg/operatorconfig"
+	"github.com/openshift/managed-upgrade-operator/util/mocks"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("OcmPolicyProvider", func() {
+	var (
+		mockCtrl       *gomock.Controller
+		mockKubeClient *mocks.MockClient
+		server         *httptest.Server
+		handler        http.HandlerFunc
+		pullSecret     corev1.Secret
+	)
+
+	BeforeEach(func() {
+		mockCtrl = gomock.NewController(GinkgoT())
+		mockKubeClient = mocks.NewMockClient(mockCtrl)
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			handler(w, r)
+		}))
+		cfg := operatorconfig.DefaultConfig()
+		cfg.Policy.URL = server.URL + "/"
+		operatorconfig.Set(cfg, "", "")
+		pullSecret = corev1.Secret{
+			Data: map[string][]byte{
+				corev1.DockerConfigJsonKey: []byte(`{"auths":{"cloud.openshift.com":{"auth":"dG9rZW4=","email":"sre@example.com"}}}`),
+			},
+		}
+		mockKubeClient.EXPECT().Get(gomock.Any(), types.NamespacedName{Name: "version"}, gomock.Any()).SetArg(2, configv1.ClusterVersion{
+			Spec: configv1.ClusterVersionSpec{ClusterID: "external-id"},
+		}).AnyTimes()
+	})
+
+	AfterEach(func() {
+		server.Close()
+		operatorconfig.Reset()
+		mockCtrl.Finish()
+	})
+
+	Context("When the API serves the policies of the cluster", func() {
+		BeforeEach(func() {
+			mockKubeClient.EXPECT().Get(gomock.Any(), pullSecretName, gomock.Any()).SetArg(2, pullSecret)
+			handler = func(w http.ResponseWriter, r *http.Request) {
+				defer GinkgoRecover()
+				Expect(r.Header.Get("Authorization")).To(Equal("AccessToken external-id:dG9rZW4="))
+				switch r.URL.Path {
+				case clustersPath:
+					Expect(r.URL.Query().Get("search")).To(Equal("external_id='external-id'"))
+					fmt.Fprint(w, `{"items":[{"id":"internal-id","external_id":"external-id","version":{"id":"openshift-v4.4.6","available_upgrades":["4.4.7","4.4.8"]}}]}`)
+				case clustersPath + "/internal-id/upgrade_policies":
+					fmt.Fprint(w, `{"items":[{"id":"policy-id","schedule_type":"manual","upgrade_type":"OSD","version":"4.4.7","next_run":"2020-06-20T10:00:00Z"}]}`)
+				default:
+					w.WriteHeader(http.StatusNotFound)
+				}
+			}
+		})
+
+		It("returns the policies", func() {
+			provider, err := NewBuilder().NewClient(mockKubeClient)
+			Expect(err).NotTo(HaveOccurred())
+			policies, err := provider.Policies()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(policies).To(Equal([]UpgradePolicy{{
+				ID:           "policy-id",
+				ScheduleType: ScheduleTypeManual,
+				UpgradeType:  UpgradeTypeOSD,
+				Version:      "4.4.7",
+				NextRun:      time.Date(2020, 6, 20, 10, 0, 0, 0, time.UTC),
+			}}))
+		})
+
+		It("returns the available upgrades", func() {
+			provider, err := NewBuilder().NewClient(mockKubeClient)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(provider.AvailableUpgrades()).To(Equal([]string{"4.4.7", "4.4.8"}))
+		})
+	})
+
+	Context("When the API rejects the cluster", func() {
+		It("returns the error", func() {
+			mockKubeClient.EXPECT().Get(gomock.Any(), pullSecretName, gomock.Any()).SetArg(2, pullSecret)
+			handler = func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusUnauthorized)
+				fmt.Fprint(w, `{"reason":"invalid token"}`)
+			}
+			provider, err := NewBuilder().NewClient(mockKubeClient)
+			Expect(err).NotTo(HaveOccurred())
+			_, err = provider.Policies()
+			Expect(err).To(MatchError(ContainSubstring("401")))
+		})
+	})
+
+	Context("When the pull secret has no token for the API", func() {
+		It("cannot build the provider", func() {
+			pullSecret.Data[corev1.DockerConfigJsonKey] = []byte(`{"auths":{"quay.io":{"auth":"dG9rZW4="}}}`)
+			mockKubeClient.EXPECT().Get(gomock.Any(), pullSecretName, gomock.Any()).SetArg(2, pullSecret)
+			_, err := NewBuilder().NewClient(mockKubeClient)
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Context("When no policy URL is configured", func() {
+		It("cannot build the provider", func() {
+			operatorconfig.Reset()
+			_, err := NewBuilder().NewClient(mockKubeClient)
+			Expect(err).To(HaveOccurred())
+		})
+	})
+})
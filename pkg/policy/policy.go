@@ -0,0 +1,46 @@
+package policy
+
+import (
+	"time"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// ScheduleType tells whether an upgrade policy was scheduled once by a person or recurs automatically
+type ScheduleType string
+
+const (
+	ScheduleTypeManual    ScheduleType = "manual"
+	ScheduleTypeAutomatic ScheduleType = "automatic"
+
+	// Type of the policies upgrading the cluster, other types are not handled by the operator
+	UpgradeTypeOSD = "OSD"
+)
+
+// UpgradePolicy describes when the cluster is upgraded and to which version
+type UpgradePolicy struct {
+	ID           string       `json:"id"`
+	ScheduleType ScheduleType `json:"schedule_type"`
+	// Cron expression of the automatic policies
+	Schedule    string    `json:"schedule,omitempty"`
+	UpgradeType string    `json:"upgrade_type"`
+	Version     string    `json:"version"`
+	NextRun     time.Time `json:"next_run"`
+}
+
+//go:generate mockgen -destination=mocks/policyProvider.go -package=mocks github.com/openshift/managed-upgrade-operator/pkg/policy PolicyProvider
+type PolicyProvider interface {
+	// Policies returns the upgrade policies of the cluster
+	Policies() ([]UpgradePolicy, error)
+	// AvailableUpgrades returns the versions the cluster is allowed to upgrade to
+	AvailableUpgrades() ([]string, error)
+}
+
+//go:generate mockgen -destination=mocks/policyProviderBuilder.go -package=mocks github.com/openshift/managed-upgrade-operator/pkg/policy PolicyProviderBuilder
+type PolicyProviderBuilder interface {
+	NewClient(client client.Client) (PolicyProvider, error)
+}
+
+func NewBuilder() PolicyProviderBuilder {
+	return &ocmPolicyProviderBuilder{}
+}
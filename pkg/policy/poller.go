@@ -0,0 +1,66 @@
+package policy
+
+import (
+	"time"
+
+	"github.com/openshift/managed-upgrade-operator/pkg/operatorconfig"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+)
+
+var log = logf.Log.WithName("policy")
+
+// How often the poller checks whether the policies are due to be pulled
+const pollerTick = time.Minute
+
+// blank assignment to verify that Poller implements manager.Runnable
+var _ manager.Runnable = &Poller{}
+
+// Poller pulls the upgrade policies at the configured interval while the manager runs.
+// Nothing is pulled while no policy URL is configured.
+type Poller struct {
+	client  client.Client
+	builder PolicyProviderBuilder
+}
+
+func NewPoller(c client.Client, builder PolicyProviderBuilder) *Poller {
+	return &Poller{client: c, builder: builder}
+}
+
+// Start pulls the policies until stopped. The configuration is read on every tick so that it applies live,
+// a changed URL is pulled right away.
+func (p *Poller) Start(stop <-chan struct{}) error {
+	ticker := time.NewTicker(pollerTick)
+	defer ticker.Stop()
+
+	var last time.Time
+	lastURL := ""
+	for {
+		cfg := operatorconfig.Get()
+		if len(cfg.Policy.URL) > 0 && (cfg.Policy.URL != lastURL || time.Since(last) >= cfg.PolicyInterval()) {
+			p.poll(cfg.Policy)
+			last = time.Now()
+		}
+		lastURL = cfg.Policy.URL
+
+		select {
+		case <-stop:
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+func (p *Poller) poll(cfg operatorconfig.PolicyConfig) {
+	logger := log.WithValues("URL", cfg.URL)
+	provider, err := p.builder.NewClient(p.client)
+	if err != nil {
+		logger.Error(err, "unable to build the upgrade policy client")
+		return
+	}
+	err = Sync(p.client, provider, cfg, logger)
+	if err != nil {
+		logger.Error(err, "unable to apply the upgrade policy")
+	}
+}
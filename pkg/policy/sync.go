@@ -0,0 +1,118 @@
+package policy
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-logr/logr"
+	configv1 "github.com/openshift/api/config/v1"
+	upgradev1alpha1 "github.com/openshift/managed-upgrade-operator/pkg/apis/upgrade/v1alpha1"
+	"github.com/openshift/managed-upgrade-operator/pkg/operatorconfig"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Sync creates or updates the UpgradeConfig from the next upgrade policy of the cluster.
+// The UpgradeConfig is left as is when the cluster has no upgrade policy.
+func Sync(c client.Client, provider PolicyProvider, cfg operatorconfig.PolicyConfig, logger logr.Logger) error {
+	policies, err := provider.Policies()
+	if err != nil {
+		return err
+	}
+	next := nextPolicy(policies)
+	if next == nil {
+		logger.Info("the cluster has no upgrade policy")
+		return nil
+	}
+
+	if cfg.CheckAllowedVersions {
+		available, err := provider.AvailableUpgrades()
+		if err != nil {
+			return err
+		}
+		if !contains(available, next.Version) {
+			return fmt.Errorf("version %s of upgrade policy %s is not an available upgrade of the cluster", next.Version, next.ID)
+		}
+	}
+
+	uc := &upgradev1alpha1.UpgradeConfig{}
+	err = c.Get(context.TODO(), types.NamespacedName{Name: cfg.UpgradeConfigName}, uc)
+	if err != nil {
+		if !errors.IsNotFound(err) {
+			return err
+		}
+		clusterVersion := &configv1.ClusterVersion{}
+		err = c.Get(context.TODO(), types.NamespacedName{Name: "version"}, clusterVersion)
+		if err != nil {
+			return err
+		}
+		uc = &upgradev1alpha1.UpgradeConfig{
+			ObjectMeta: metav1.ObjectMeta{Name: cfg.UpgradeConfigName},
+			Spec: upgradev1alpha1.UpgradeConfigSpec{
+				Desired: upgradev1alpha1.Update{Channel: clusterVersion.Spec.Channel},
+			},
+		}
+		applyPolicy(uc, next)
+		logger.Info(fmt.Sprintf("creating upgradeconfig %s from upgrade policy %s to %s at %s", uc.Name, next.ID, next.Version, uc.Spec.UpgradeAt))
+		return c.Create(context.TODO(), uc)
+	}
+
+	base := uc.DeepCopy()
+	if !applyPolicy(uc, next) {
+		return nil
+	}
+	logger.Info(fmt.Sprintf("updating upgradeconfig %s from upgrade policy %s to %s at %s", uc.Name, next.ID, next.Version, uc.Spec.UpgradeAt))
+	return c.Patch(context.TODO(), uc, client.MergeFrom(base))
+}
+
+// nextPolicy returns the upgrade policy running next, ignoring the policies the operator does not handle
+func nextPolicy(policies []UpgradePolicy) *UpgradePolicy {
+	var next *UpgradePolicy
+	for i := range policies {
+		p := &policies[i]
+		if len(p.UpgradeType) > 0 && p.UpgradeType != UpgradeTypeOSD {
+			continue
+		}
+		if len(p.Version) == 0 {
+			continue
+		}
+		if next == nil || p.NextRun.Before(next.NextRun) {
+			next = p
+		}
+	}
+	return next
+}
+
+// applyPolicy sets the version and the schedule of the policy in the UpgradeConfig, it returns true if anything changed
+func applyPolicy(uc *upgradev1alpha1.UpgradeConfig, p *UpgradePolicy) bool {
+	upgradeAt := ""
+	if !p.NextRun.IsZero() {
+		upgradeAt = p.NextRun.UTC().Format(time.RFC3339)
+	}
+	if uc.Spec.Desired.Version == p.Version &&
+		uc.Spec.UpgradeAt == upgradeAt &&
+		uc.Annotations[upgradev1alpha1.PolicyIDAnnotation] == p.ID &&
+		uc.Annotations[upgradev1alpha1.PolicyScheduleTypeAnnotation] == string(p.ScheduleType) {
+		return false
+	}
+	uc.Spec.Desired.Version = p.Version
+	uc.Spec.UpgradeAt = upgradeAt
+	if uc.Annotations == nil {
+		uc.Annotations = map[string]string{}
+	}
+	uc.Annotations[upgradev1alpha1.PolicyIDAnnotation] = p.ID
+	uc.Annotations[upgradev1alpha1.PolicyScheduleTypeAnnotation] = string(p.ScheduleType)
+	return true
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,133 @@
+package policy
+
+import (
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/golang/mock/gomock"
+	configv1 "github.com/openshift/api/config/v1"
+	upgradev1alpha1 "github.com/openshift/managed-upgrade-operator/pkg/apis/upgrade/v1alpha1"
+	"github.com/openshift/managed-upgrade-operator/pkg/operatorconfig"
+	"github.com/openshift/managed-upgrade-operator/util/mocks"
+	testStructs "github.com/openshift/managed-upgrade-operator/util/mocks/structs"
+	k8serrs "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// fakeProvider serves fixed policies
+type fakeProvider struct {
+	policies  []UpgradePolicy
+	available []string
+}
+
+func (p *fakeProvider) Policies() ([]UpgradePolicy, error) {
+	return p.policies, nil
+}
+
+func (p *fakeProvider) AvailableUpgrades() ([]string, error) {
+	return p.available, nil
+}
+
+var _ = Describe("Sync", func() {
+	var (
+		mockCtrl       *gomock.Controller
+		mockKubeClient *mocks.MockClient
+		provider       *fakeProvider
+		cfg            operatorconfig.PolicyConfig
+		ucName         types.NamespacedName
+		nextRun        time.Time
+		logger         logr.Logger
+	)
+
+	BeforeEach(func() {
+		mockCtrl = gomock.NewController(GinkgoT())
+		mockKubeClient = mocks.NewMockClient(mockCtrl)
+		cfg = operatorconfig.DefaultConfig().Policy
+		ucName = types.NamespacedName{Name: cfg.UpgradeConfigName}
+		nextRun = time.Date(2020, 6, 20, 10, 0, 0, 0, time.UTC)
+		provider = &fakeProvider{
+			policies: []UpgradePolicy{
+				{ID: "later", ScheduleType: ScheduleTypeAutomatic, UpgradeType: UpgradeTypeOSD, Version: "4.4.8", NextRun: nextRun.Add(24 * time.Hour)},
+				{ID: "next", ScheduleType: ScheduleTypeManual, UpgradeType: UpgradeTypeOSD, Version: "4.4.7", NextRun: nextRun},
+				{ID: "addon", UpgradeType: "ADDON", Version: "1.0.0", NextRun: nextRun.Add(-time.Hour)},
+			},
+			available: []string{"4.4.7", "4.4.8"},
+		}
+		logger = logf.Log.WithName("policy test logger")
+	})
+
+	AfterEach(func() {
+		mockCtrl.Finish()
+	})
+
+	Context("When the UpgradeConfig does not exist", func() {
+		It("creates it from the next policy", func() {
+			matcher := testStructs.NewUpgradeConfigMatcher()
+			gomock.InOrder(
+				mockKubeClient.EXPECT().Get(gomock.Any(), ucName, gomock.Any()).Return(k8serrs.NewNotFound(schema.GroupResource{}, ucName.Name)),
+				mockKubeClient.EXPECT().Get(gomock.Any(), types.NamespacedName{Name: "version"}, gomock.Any()).SetArg(2, configv1.ClusterVersion{
+					Spec: configv1.ClusterVersionSpec{Channel: "stable-4.4"},
+				}),
+				mockKubeClient.EXPECT().Create(gomock.Any(), matcher),
+			)
+			Expect(Sync(mockKubeClient, provider, cfg, logger)).To(Succeed())
+			uc := matcher.ActualUpgradeConfig
+			Expect(uc.Name).To(Equal(cfg.UpgradeConfigName))
+			Expect(uc.Spec.Desired.Version).To(Equal("4.4.7"))
+			Expect(uc.Spec.Desired.Channel).To(Equal("stable-4.4"))
+			Expect(uc.Spec.UpgradeAt).To(Equal("2020-06-20T10:00:00Z"))
+			Expect(uc.Annotations).To(HaveKeyWithValue(upgradev1alpha1.PolicyIDAnnotation, "next"))
+			Expect(uc.Annotations).To(HaveKeyWithValue(upgradev1alpha1.PolicyScheduleTypeAnnotation, "manual"))
+		})
+	})
+
+	Context("When the UpgradeConfig exists", func() {
+		var upgradeConfig *upgradev1alpha1.UpgradeConfig
+		BeforeEach(func() {
+			upgradeConfig = testStructs.NewUpgradeConfigBuilder().WithNamespacedName(ucName).GetUpgradeConfig()
+		})
+
+		It("updates it from the next policy", func() {
+			matcher := testStructs.NewUpgradeConfigMatcher()
+			mockKubeClient.EXPECT().Get(gomock.Any(), ucName, gomock.Any()).SetArg(2, *upgradeConfig)
+			mockKubeClient.EXPECT().Patch(gomock.Any(), matcher, gomock.Any())
+			Expect(Sync(mockKubeClient, provider, cfg, logger)).To(Succeed())
+			Expect(matcher.ActualUpgradeConfig.Spec.Desired.Version).To(Equal("4.4.7"))
+			Expect(matcher.ActualUpgradeConfig.Spec.Desired.Channel).To(Equal(upgradeConfig.Spec.Desired.Channel))
+			Expect(matcher.ActualUpgradeConfig.Spec.UpgradeAt).To(Equal("2020-06-20T10:00:00Z"))
+		})
+
+		It("leaves it alone when it already follows the policy", func() {
+			applyPolicy(upgradeConfig, &provider.policies[1])
+			mockKubeClient.EXPECT().Get(gomock.Any(), ucName, gomock.Any()).SetArg(2, *upgradeConfig)
+			mockKubeClient.EXPECT().Patch(gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+			Expect(Sync(mockKubeClient, provider, cfg, logger)).To(Succeed())
+		})
+	})
+
+	Context("When the allowed versions are checked", func() {
+		BeforeEach(func() {
+			cfg.CheckAllowedVersions = true
+		})
+
+		It("does not apply a policy to a version which is not available", func() {
+			provider.available = []string{"4.4.8"}
+			mockKubeClient.EXPECT().Get(gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+			err := Sync(mockKubeClient, provider, cfg, logger)
+			Expect(err).To(MatchError("version 4.4.7 of upgrade policy next is not an available upgrade of the cluster"))
+		})
+	})
+
+	Context("When the cluster has no upgrade policy", func() {
+		It("does nothing", func() {
+			provider.policies = nil
+			mockKubeClient.EXPECT().Get(gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+			Expect(Sync(mockKubeClient, provider, cfg, logger)).To(Succeed())
+		})
+	})
+})
@@ -0,0 +1,116 @@
+package progress
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	configv1 "github.com/openshift/api/config/v1"
+	upgradev1alpha1 "github.com/openshift/managed-upgrade-operator/pkg/apis/upgrade/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Name of the ClusterOperator version reporting the version of the operator itself
+const operatorVersionName = "operator"
+
+// Matches the progress of the cluster version operator in its Progressing message,
+// like "Working towards 4.4.7: 45 of 560 done (8% complete)"
+var manifestsDone = regexp.MustCompile(`(\d+) of (\d+) done`)
+
+// ControlPlane returns the progress of the control plane upgrade to the version: the ClusterOperators reporting
+// the version and the release manifests the cluster version operator applied.
+// The last progress time is carried over from the previous progress unless the counts changed.
+func ControlPlane(clusterVersion *configv1.ClusterVersion, operators []configv1.ClusterOperator, version string, previous *upgradev1alpha1.ControlPlaneProgress, now time.Time) upgradev1alpha1.ControlPlaneProgress {
+	progress := upgradev1alpha1.ControlPlaneProgress{TotalOperators: int32(len(operators))}
+	for _, co := range operators {
+		if operatorVersion(co) == version {
+			progress.UpdatedOperators++
+			continue
+		}
+		progress.PendingOperators = append(progress.PendingOperators, co.Name)
+		if isProgressing(co.Status.Conditions) {
+			progress.ProgressingOperators = append(progress.ProgressingOperators, co.Name)
+		}
+	}
+	sort.Strings(progress.PendingOperators)
+	sort.Strings(progress.ProgressingOperators)
+
+	for _, c := range clusterVersion.Status.Conditions {
+		if c.Type != configv1.OperatorProgressing {
+			continue
+		}
+		progress.Message = c.Message
+		if m := manifestsDone.FindStringSubmatch(c.Message); m != nil {
+			done, _ := strconv.Atoi(m[1])
+			total, _ := strconv.Atoi(m[2])
+			progress.DoneManifests = int32(done)
+			progress.TotalManifests = int32(total)
+		}
+	}
+
+	progress.LastProgressTime = &metav1.Time{Time: now}
+	if previous != nil && previous.LastProgressTime != nil &&
+		previous.UpdatedOperators == progress.UpdatedOperators && previous.DoneManifests == progress.DoneManifests {
+		progress.LastProgressTime = previous.LastProgressTime.DeepCopy()
+	}
+	return progress
+}
+
+// Changed returns true if the progress differs from the previous one
+func Changed(previous *upgradev1alpha1.ControlPlaneProgress, progress upgradev1alpha1.ControlPlaneProgress) bool {
+	return previous == nil || !reflect.DeepEqual(*previous, progress)
+}
+
+// Summary describes the progress, like "12 of 30 operators at the target version, 45 of 560 manifests done, progressing: dns,ingress"
+func Summary(progress *upgradev1alpha1.ControlPlaneProgress) string {
+	parts := []string{fmt.Sprintf("%d of %d operators at the target version", progress.UpdatedOperators, progress.TotalOperators)}
+	if progress.TotalManifests > 0 {
+		parts = append(parts, fmt.Sprintf("%d of %d manifests done", progress.DoneManifests, progress.TotalManifests))
+	}
+	if len(progress.ProgressingOperators) > 0 {
+		parts = append(parts, "progressing: "+strings.Join(progress.ProgressingOperators, ","))
+	}
+	if progress.LastProgressTime != nil {
+		parts = append(parts, "last progress at "+progress.LastProgressTime.UTC().Format(time.RFC3339))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// OperatorsRatio returns the fraction of the ClusterOperators reporting the target version
+func OperatorsRatio(progress *upgradev1alpha1.ControlPlaneProgress) float64 {
+	if progress.TotalOperators == 0 {
+		return 0
+	}
+	return float64(progress.UpdatedOperators) / float64(progress.TotalOperators)
+}
+
+// ManifestsRatio returns the fraction of the release manifests the cluster version operator applied
+func ManifestsRatio(progress *upgradev1alpha1.ControlPlaneProgress) float64 {
+	if progress.TotalManifests == 0 {
+		return 0
+	}
+	return float64(progress.DoneManifests) / float64(progress.TotalManifests)
+}
+
+// operatorVersion returns the version the ClusterOperator reports for itself
+func operatorVersion(co configv1.ClusterOperator) string {
+	for _, v := range co.Status.Versions {
+		if v.Name == operatorVersionName {
+			return v.Version
+		}
+	}
+	return ""
+}
+
+func isProgressing(conditions []configv1.ClusterOperatorStatusCondition) bool {
+	for _, c := range conditions {
+		if c.Type == configv1.OperatorProgressing {
+			return c.Status == configv1.ConditionTrue
+		}
+	}
+	return false
+}
@@ -0,0 +1,89 @@
+package progress
+
+import (
+	"time"
+
+	configv1 "github.com/openshift/api/config/v1"
+	upgradev1alpha1 "github.com/openshift/managed-upgrade-operator/pkg/apis/upgrade/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Progress", func() {
+	var (
+		clusterVersion *configv1.ClusterVersion
+		operators      []configv1.ClusterOperator
+		now            time.Time
+	)
+
+	operator := func(name string, version string, progressing configv1.ConditionStatus) configv1.ClusterOperator {
+		co := configv1.ClusterOperator{ObjectMeta: metav1.ObjectMeta{Name: name}}
+		co.Status.Versions = []configv1.OperandVersion{{Name: "operator", Version: version}}
+		co.Status.Conditions = []configv1.ClusterOperatorStatusCondition{{Type: configv1.OperatorProgressing, Status: progressing}}
+		return co
+	}
+
+	BeforeEach(func() {
+		now = time.Date(2020, 6, 20, 10, 0, 0, 0, time.UTC)
+		clusterVersion = &configv1.ClusterVersion{}
+		clusterVersion.Status.Conditions = []configv1.ClusterOperatorStatusCondition{
+			{Type: configv1.OperatorProgressing, Status: configv1.ConditionTrue, Message: "Working towards 4.4.7: 45 of 560 done (8% complete)"},
+		}
+		operators = []configv1.ClusterOperator{
+			operator("kube-apiserver", "4.4.7", configv1.ConditionFalse),
+			operator("ingress", "4.4.6", configv1.ConditionFalse),
+			operator("dns", "4.4.6", configv1.ConditionTrue),
+			operator("etcd", "4.4.7", configv1.ConditionFalse),
+		}
+	})
+
+	Context("When the control plane is upgrading", func() {
+		It("counts the operators at the target version and the manifests done", func() {
+			progress := ControlPlane(clusterVersion, operators, "4.4.7", nil, now)
+			Expect(progress.UpdatedOperators).To(Equal(int32(2)))
+			Expect(progress.TotalOperators).To(Equal(int32(4)))
+			Expect(progress.PendingOperators).To(Equal([]string{"dns", "ingress"}))
+			Expect(progress.ProgressingOperators).To(Equal([]string{"dns"}))
+			Expect(progress.DoneManifests).To(Equal(int32(45)))
+			Expect(progress.TotalManifests).To(Equal(int32(560)))
+			Expect(progress.LastProgressTime.Time).To(Equal(now))
+			Expect(OperatorsRatio(&progress)).To(Equal(0.5))
+			Expect(Summary(&progress)).To(Equal("2 of 4 operators at the target version, 45 of 560 manifests done, progressing: dns, last progress at 2020-06-20T10:00:00Z"))
+		})
+		It("leaves the manifests unset when the cluster version operator does not report them", func() {
+			clusterVersion.Status.Conditions[0].Message = "Unable to apply 4.4.7: an unknown error has occurred"
+			progress := ControlPlane(clusterVersion, operators, "4.4.7", nil, now)
+			Expect(progress.TotalManifests).To(BeZero())
+			Expect(ManifestsRatio(&progress)).To(BeZero())
+			Expect(Summary(&progress)).NotTo(ContainSubstring("manifests"))
+		})
+	})
+
+	Context("When comparing with the previous progress", func() {
+		var previous upgradev1alpha1.ControlPlaneProgress
+
+		BeforeEach(func() {
+			previous = ControlPlane(clusterVersion, operators, "4.4.7", nil, now.Add(-time.Hour))
+		})
+
+		It("keeps the last progress time while stuck", func() {
+			progress := ControlPlane(clusterVersion, operators, "4.4.7", &previous, now)
+			Expect(progress.LastProgressTime.Time).To(Equal(now.Add(-time.Hour)))
+			Expect(Changed(&previous, progress)).To(BeFalse())
+		})
+		It("moves the last progress time once more manifests are done", func() {
+			clusterVersion.Status.Conditions[0].Message = "Working towards 4.4.7: 46 of 560 done (8% complete)"
+			progress := ControlPlane(clusterVersion, operators, "4.4.7", &previous, now)
+			Expect(progress.LastProgressTime.Time).To(Equal(now))
+			Expect(Changed(&previous, progress)).To(BeTrue())
+		})
+		It("moves the last progress time once more operators are at the target version", func() {
+			operators[1] = operator("ingress", "4.4.7", configv1.ConditionFalse)
+			progress := ControlPlane(clusterVersion, operators, "4.4.7", &previous, now)
+			Expect(progress.LastProgressTime.Time).To(Equal(now))
+			Expect(progress.PendingOperators).To(Equal([]string{"dns"}))
+		})
+	})
+})
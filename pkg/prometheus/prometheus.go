@@ -0,0 +1,31 @@
+package prometheus
+
+import (
+	"time"
+
+	"github.com/prometheus/common/model"
+)
+
+//go:generate mockgen -destination=mocks/prometheusClient.go -package=mocks github.com/openshift/managed-upgrade-operator/pkg/prometheus PrometheusClient
+
+// PrometheusClient queries the cluster Prometheus
+type PrometheusClient interface {
+	// Query evaluates the PromQL query at the given time, now if zero. The query must return an instant vector.
+	Query(query string, at time.Time) (model.Vector, error)
+	// QueryRange evaluates the PromQL query over the range, every step. The query must return an instant vector.
+	QueryRange(query string, start time.Time, end time.Time, step time.Duration) (model.Matrix, error)
+}
+
+//go:generate mockgen -destination=mocks/prometheusClientBuilder.go -package=mocks github.com/openshift/managed-upgrade-operator/pkg/prometheus PrometheusClientBuilder
+
+// PrometheusClientBuilder builds clients of the cluster Prometheus as configured in the operator configuration
+type PrometheusClientBuilder interface {
+	NewClient() (PrometheusClient, error)
+}
+
+func NewBuilder() PrometheusClientBuilder {
+	return &prometheusClientBuilder{
+		tokenFile: tokenFile,
+		caFile:    serviceCAFile,
+	}
+}
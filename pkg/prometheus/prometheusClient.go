@@ -0,0 +1,191 @@
+package prometheus
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/openshift/managed-upgrade-operator/pkg/operatorconfig"
+	"github.com/prometheus/common/model"
+)
+
+const (
+	// Token of the operator service account, mounted in its pods
+	tokenFile = "/var/run/secrets/kubernetes.io/serviceaccount/token"
+	// CA bundle of the service serving certificates, mounted in every pod by OpenShift
+	serviceCAFile = "/var/run/secrets/kubernetes.io/serviceaccount/service-ca.crt"
+
+	queryPath      = "/api/v1/query"
+	queryRangePath = "/api/v1/query_range"
+	// How long an idle connection to Prometheus is kept for the next query
+	idleConnTimeout = 90 * time.Second
+)
+
+type prometheusClientBuilder struct {
+	tokenFile string
+	caFile    string
+
+	// The transport is shared by the clients built from the same service CA bundle and Prometheus URL,
+	// so that their connections are reused
+	mutex     sync.Mutex
+	transport *http.Transport
+	ca        []byte
+	baseURL   string
+}
+
+// NewClient returns a client of the configured Prometheus service, authenticated with the operator service account
+// and trusting the service CA only
+func (b *prometheusClientBuilder) NewClient() (PrometheusClient, error) {
+	cfg := operatorconfig.Get()
+	token, err := ioutil.ReadFile(b.tokenFile)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read the service account token: %v", err)
+	}
+	baseURL := strings.TrimSuffix(cfg.Monitoring.PrometheusURL, "/")
+	transport, err := b.transportFor(baseURL)
+	if err != nil {
+		return nil, err
+	}
+
+	timeout := cfg.QueryTimeout()
+	return &prometheusClient{
+		baseURL: baseURL,
+		token:   strings.TrimSpace(string(token)),
+		timeout: timeout,
+		client:  &http.Client{Timeout: timeout, Transport: transport},
+	}, nil
+}
+
+// transportFor returns the shared transport, it is rebuilt when the service CA bundle or the Prometheus URL changed
+func (b *prometheusClientBuilder) transportFor(baseURL string) (*http.Transport, error) {
+	ca, err := ioutil.ReadFile(b.caFile)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read the service CA bundle: %v", err)
+	}
+
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	if b.transport != nil && bytes.Equal(b.ca, ca) && b.baseURL == baseURL {
+		return b.transport, nil
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(ca) {
+		return nil, fmt.Errorf("no certificate found in the service CA bundle %s", b.caFile)
+	}
+	if b.transport != nil {
+		b.transport.CloseIdleConnections()
+	}
+	b.transport = &http.Transport{
+		Proxy:           http.ProxyFromEnvironment,
+		TLSClientConfig: &tls.Config{RootCAs: pool},
+		IdleConnTimeout: idleConnTimeout,
+	}
+	b.ca = ca
+	b.baseURL = baseURL
+	return b.transport, nil
+}
+
+// prometheusClient queries the Prometheus HTTP API
+type prometheusClient struct {
+	baseURL string
+	token   string
+	// Time given to Prometheus to evaluate a query
+	timeout time.Duration
+	client  *http.Client
+}
+
+// apiResponse is the envelope of every reply of the Prometheus HTTP API
+type apiResponse struct {
+	Status    string   `json:"status"`
+	Data      apiData  `json:"data"`
+	ErrorType string   `json:"errorType"`
+	Error     string   `json:"error"`
+	Warnings  []string `json:"warnings"`
+}
+
+type apiData struct {
+	ResultType model.ValueType `json:"resultType"`
+	Result     json.RawMessage `json:"result"`
+}
+
+func (p *prometheusClient) Query(query string, at time.Time) (model.Vector, error) {
+	params := url.Values{"query": []string{query}}
+	if !at.IsZero() {
+		params.Set("time", formatTime(at))
+	}
+	vector := model.Vector{}
+	err := p.query(queryPath, params, model.ValVector, &vector)
+	if err != nil {
+		return nil, err
+	}
+	return vector, nil
+}
+
+func (p *prometheusClient) QueryRange(query string, start time.Time, end time.Time, step time.Duration) (model.Matrix, error) {
+	params := url.Values{
+		"query": []string{query},
+		"start": []string{formatTime(start)},
+		"end":   []string{formatTime(end)},
+		"step":  []string{strconv.FormatFloat(step.Seconds(), 'f', -1, 64)},
+	}
+	matrix := model.Matrix{}
+	err := p.query(queryRangePath, params, model.ValMatrix, &matrix)
+	if err != nil {
+		return nil, err
+	}
+	return matrix, nil
+}
+
+// query POSTs the query to the API and decodes its result, which must be of the expected type
+func (p *prometheusClient) query(path string, params url.Values, expected model.ValueType, result interface{}) error {
+	params.Set("timeout", p.timeout.String())
+	req, err := http.NewRequest(http.MethodPost, p.baseURL+path, strings.NewReader(params.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Authorization", "Bearer "+p.token)
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("unable to reach Prometheus: %v", err)
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	// Prometheus describes the errors in the envelope, the proxy in front of it does not
+	reply := &apiResponse{}
+	err = json.Unmarshal(body, reply)
+	if err != nil {
+		return fmt.Errorf("Prometheus replied %d to query %s: %s", resp.StatusCode, params.Get("query"), string(body))
+	}
+	if reply.Status != "success" {
+		return fmt.Errorf("Prometheus rejected query %s: %s: %s", params.Get("query"), reply.ErrorType, reply.Error)
+	}
+	if reply.Data.ResultType != expected {
+		return fmt.Errorf("query %s returned a %s instead of a %s", params.Get("query"), reply.Data.ResultType, expected)
+	}
+	err = json.Unmarshal(reply.Data.Result, result)
+	if err != nil {
+		return fmt.Errorf("invalid result of query %s: %v", params.Get("query"), err)
+	}
+	return nil
+}
+
+// formatTime formats the time as the Prometheus API expects it, in seconds since the epoch
+func formatTime(t time.Time) string {
+	return strconv.FormatFloat(float64(t.UnixNano())/float64(time.Second), 'f', -1, 64)
+}
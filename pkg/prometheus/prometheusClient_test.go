@@ -0,0 +1,239 @@
+package prometheus
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/openshift/managed-upgrade-operator/pkg/operatorconfig"
+	"github.com/prometheus/common/model"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("PrometheusClient", func() {
+	var (
+		server  *httptest.Server
+		handler http.HandlerFunc
+		dir     string
+		builder *prometheusClientBuilder
+	)
+
+	BeforeEach(func() {
+		server = httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			handler(w, r)
+		}))
+		var err error
+		dir, err = ioutil.TempDir("", "prometheus")
+		Expect(err).NotTo(HaveOccurred())
+		builder = &prometheusClientBuilder{
+			tokenFile: filepath.Join(dir, "token"),
+			caFile:    filepath.Join(dir, "service-ca.crt"),
+		}
+		Expect(ioutil.WriteFile(builder.tokenFile, []byte("operator-token\n"), 0600)).To(Succeed())
+		ca := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+		Expect(ioutil.WriteFile(builder.caFile, ca, 0600)).To(Succeed())
+		cfg := operatorconfig.DefaultConfig()
+		cfg.Monitoring.PrometheusURL = server.URL + "/"
+		operatorconfig.Set(cfg, "", "")
+	})
+
+	AfterEach(func() {
+		server.Close()
+		os.RemoveAll(dir)
+		operatorconfig.Reset()
+	})
+
+	Context("When querying an instant vector", func() {
+		It("sends the query authenticated with the service account token", func() {
+			at := time.Unix(1592647200, 500000000)
+			handler = func(w http.ResponseWriter, r *http.Request) {
+				defer GinkgoRecover()
+				Expect(r.Method).To(Equal(http.MethodPost))
+				Expect(r.URL.Path).To(Equal(queryPath))
+				Expect(r.Header.Get("Authorization")).To(Equal("Bearer operator-token"))
+				Expect(r.ParseForm()).To(Succeed())
+				Expect(r.PostForm.Get("query")).To(Equal(`ALERTS{alertstate="firing"}`))
+				Expect(r.PostForm.Get("time")).To(Equal("1592647200.5"))
+				Expect(r.PostForm.Get("timeout")).To(Equal("30s"))
+				fmt.Fprint(w, `{"status":"success","data":{"resultType":"vector","result":[{"metric":{"alertname":"KubePodNotReady"},"value":[1592647200.5,"1"]}]}}`)
+			}
+			promClient, err := builder.NewClient()
+			Expect(err).NotTo(HaveOccurred())
+			vector, err := promClient.Query(`ALERTS{alertstate="firing"}`, at)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(vector).To(HaveLen(1))
+			Expect(vector[0].Metric).To(Equal(model.Metric{"alertname": "KubePodNotReady"}))
+			Expect(vector[0].Value).To(Equal(model.SampleValue(1)))
+		})
+
+		It("evaluates the query now when no time is given", func() {
+			handler = func(w http.ResponseWriter, r *http.Request) {
+				defer GinkgoRecover()
+				Expect(r.ParseForm()).To(Succeed())
+				Expect(r.PostForm).NotTo(HaveKey("time"))
+				fmt.Fprint(w, `{"status":"success","data":{"resultType":"vector","result":[]}}`)
+			}
+			promClient, err := builder.NewClient()
+			Expect(err).NotTo(HaveOccurred())
+			vector, err := promClient.Query("up", time.Time{})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(vector).To(BeEmpty())
+		})
+
+		It("reports the errors of Prometheus", func() {
+			handler = func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusBadRequest)
+				fmt.Fprint(w, `{"status":"error","errorType":"bad_data","error":"parse error at char 4"}`)
+			}
+			promClient, err := builder.NewClient()
+			Expect(err).NotTo(HaveOccurred())
+			_, err = promClient.Query("up{", time.Time{})
+			Expect(err).To(MatchError("Prometheus rejected query up{: bad_data: parse error at char 4"))
+		})
+
+		It("reports the replies which are not from Prometheus", func() {
+			handler = func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusForbidden)
+				fmt.Fprint(w, "Forbidden")
+			}
+			promClient, err := builder.NewClient()
+			Expect(err).NotTo(HaveOccurred())
+			_, err = promClient.Query("up", time.Time{})
+			Expect(err).To(MatchError("Prometheus replied 403 to query up: Forbidden"))
+		})
+
+		It("rejects the results which are not vectors", func() {
+			handler = func(w http.ResponseWriter, r *http.Request) {
+				fmt.Fprint(w, `{"status":"success","data":{"resultType":"scalar","result":[1592647200,"1"]}}`)
+			}
+			promClient, err := builder.NewClient()
+			Expect(err).NotTo(HaveOccurred())
+			_, err = promClient.Query("1", time.Time{})
+			Expect(err).To(MatchError("query 1 returned a scalar instead of a vector"))
+		})
+
+		It("gives up once the query times out", func() {
+			done := make(chan struct{})
+			defer close(done)
+			handler = func(w http.ResponseWriter, r *http.Request) {
+				<-done
+			}
+			promClient, err := builder.NewClient()
+			Expect(err).NotTo(HaveOccurred())
+			promClient.(*prometheusClient).client.Timeout = 100 * time.Millisecond
+			_, err = promClient.Query("up", time.Time{})
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("unable to reach Prometheus"))
+		})
+	})
+
+	Context("When querying a range", func() {
+		It("sends the range and returns the matrix", func() {
+			start := time.Unix(1592647200, 0)
+			handler = func(w http.ResponseWriter, r *http.Request) {
+				defer GinkgoRecover()
+				Expect(r.URL.Path).To(Equal(queryRangePath))
+				Expect(r.ParseForm()).To(Succeed())
+				Expect(r.PostForm.Get("start")).To(Equal("1592647200"))
+				Expect(r.PostForm.Get("end")).To(Equal("1592647800"))
+				Expect(r.PostForm.Get("step")).To(Equal("300"))
+				fmt.Fprint(w, `{"status":"success","data":{"resultType":"matrix","result":[{"metric":{"job":"apiserver"},"values":[[1592647200,"1"],[1592647500,"0.5"],[1592647800,"1"]]}]}}`)
+			}
+			promClient, err := builder.NewClient()
+			Expect(err).NotTo(HaveOccurred())
+			matrix, err := promClient.QueryRange("up", start, start.Add(10*time.Minute), 5*time.Minute)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(matrix).To(HaveLen(1))
+			Expect(matrix[0].Metric).To(Equal(model.Metric{"job": "apiserver"}))
+			Expect(matrix[0].Values).To(HaveLen(3))
+			Expect(matrix[0].Values[1].Value).To(Equal(model.SampleValue(0.5)))
+		})
+	})
+
+	Context("When building the client", func() {
+		It("only trusts the service CA", func() {
+			// Every httptest server has the same certificate, another CA is needed
+			key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+			Expect(err).NotTo(HaveOccurred())
+			template := &x509.Certificate{
+				SerialNumber:          big.NewInt(1),
+				Subject:               pkix.Name{CommonName: "another-ca"},
+				NotBefore:             time.Now().Add(-time.Hour),
+				NotAfter:              time.Now().Add(time.Hour),
+				IsCA:                  true,
+				BasicConstraintsValid: true,
+				KeyUsage:              x509.KeyUsageCertSign,
+			}
+			der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ioutil.WriteFile(builder.caFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600)).To(Succeed())
+			handler = func(w http.ResponseWriter, r *http.Request) {
+				fmt.Fprint(w, `{"status":"success","data":{"resultType":"vector","result":[]}}`)
+			}
+			promClient, err := builder.NewClient()
+			Expect(err).NotTo(HaveOccurred())
+			_, err = promClient.Query("up", time.Time{})
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("certificate signed by unknown authority"))
+		})
+
+		It("shares the transport between the clients", func() {
+			first, err := builder.NewClient()
+			Expect(err).NotTo(HaveOccurred())
+			second, err := builder.NewClient()
+			Expect(err).NotTo(HaveOccurred())
+			transport := first.(*prometheusClient).client.Transport.(*http.Transport)
+			Expect(second.(*prometheusClient).client.Transport).To(BeIdenticalTo(transport))
+			Expect(transport.IdleConnTimeout).To(Equal(idleConnTimeout))
+		})
+
+		It("rebuilds the transport once the service CA bundle changed", func() {
+			first, err := builder.NewClient()
+			Expect(err).NotTo(HaveOccurred())
+			ca := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+			Expect(ioutil.WriteFile(builder.caFile, append(ca, ca...), 0600)).To(Succeed())
+			second, err := builder.NewClient()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(second.(*prometheusClient).client.Transport).NotTo(BeIdenticalTo(first.(*prometheusClient).client.Transport))
+		})
+
+		It("rebuilds the transport once the Prometheus URL changed", func() {
+			first, err := builder.NewClient()
+			Expect(err).NotTo(HaveOccurred())
+			cfg := operatorconfig.DefaultConfig()
+			cfg.Monitoring.PrometheusURL = "https://prometheus.example.com"
+			operatorconfig.Set(cfg, "", "")
+			second, err := builder.NewClient()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(second.(*prometheusClient).baseURL).To(Equal("https://prometheus.example.com"))
+			Expect(second.(*prometheusClient).client.Transport).NotTo(BeIdenticalTo(first.(*prometheusClient).client.Transport))
+		})
+
+		It("fails without the service account token", func() {
+			Expect(os.Remove(builder.tokenFile)).To(Succeed())
+			_, err := builder.NewClient()
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("unable to read the service account token"))
+		})
+
+		It("fails without a certificate in the service CA bundle", func() {
+			Expect(ioutil.WriteFile(builder.caFile, []byte("not a certificate"), 0600)).To(Succeed())
+			_, err := builder.NewClient()
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("no certificate found"))
+		})
+	})
+})
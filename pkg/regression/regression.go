@@ -0,0 +1,122 @@
+package regression
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	upgradev1alpha1 "github.com/openshift/managed-upgrade-operator/pkg/apis/upgrade/v1alpha1"
+	"github.com/openshift/managed-upgrade-operator/pkg/operatorconfig"
+	"github.com/openshift/managed-upgrade-operator/pkg/prometheus"
+)
+
+// Resolution of the metrics averaged over the windows
+const step = time.Minute
+
+// Compare averages every configured metric over the window before the upgrade commenced and the window after it
+// was verified, and compares the averages with the thresholds of the metric.
+// An error means Prometheus could not be queried, a metric without data in either window does not regress.
+func Compare(promClient prometheus.PrometheusClient, cfg operatorconfig.RegressionConfig, commenced time.Time, verified time.Time) (*upgradev1alpha1.RegressionComparison, error) {
+	window := time.Duration(cfg.WindowMinutes) * time.Minute
+	comparison := &upgradev1alpha1.RegressionComparison{
+		WindowMinutes: int32(cfg.WindowMinutes),
+	}
+	comparison.BeforeWindowEnd.Time = commenced
+	comparison.AfterWindowStart.Time = verified
+
+	for _, metric := range cfg.Metrics {
+		before, err := average(promClient, metric.Query, commenced.Add(-window), commenced)
+		if err != nil {
+			return nil, err
+		}
+		after, err := average(promClient, metric.Query, verified, verified.Add(window))
+		if err != nil {
+			return nil, err
+		}
+		comparison.Metrics = append(comparison.Metrics, compare(metric, before, after))
+	}
+	return comparison, nil
+}
+
+// Regressed returns the metrics of the comparison which regressed
+func Regressed(comparison *upgradev1alpha1.RegressionComparison) []upgradev1alpha1.MetricComparison {
+	regressed := []upgradev1alpha1.MetricComparison{}
+	for _, m := range comparison.Metrics {
+		if m.Regressed {
+			regressed = append(regressed, m)
+		}
+	}
+	return regressed
+}
+
+// sample is the average of a metric over a window, unset if the metric can not be averaged
+type sample struct {
+	value float64
+	// Why the metric can not be averaged
+	missing string
+}
+
+// average returns the average of the single series the query returns over the window
+func average(promClient prometheus.PrometheusClient, query string, start time.Time, end time.Time) (sample, error) {
+	matrix, err := promClient.QueryRange(query, start, end, step)
+	if err != nil {
+		return sample{}, err
+	}
+	if len(matrix) == 0 || len(matrix[0].Values) == 0 {
+		return sample{missing: fmt.Sprintf("no data from %s to %s", format(start), format(end))}, nil
+	}
+	if len(matrix) > 1 {
+		return sample{missing: fmt.Sprintf("the query returned %d series instead of one", len(matrix))}, nil
+	}
+	sum := 0.0
+	for _, v := range matrix[0].Values {
+		sum += float64(v.Value)
+	}
+	return sample{value: sum / float64(len(matrix[0].Values))}, nil
+}
+
+// compare compares the averages of the metric with its thresholds
+func compare(metric operatorconfig.RegressionMetric, before sample, after sample) upgradev1alpha1.MetricComparison {
+	comparison := upgradev1alpha1.MetricComparison{Name: metric.Name}
+	if len(before.missing) > 0 {
+		comparison.Message = "not compared, before the upgrade: " + before.missing
+		return comparison
+	}
+	comparison.Before = formatValue(before.value)
+	if len(after.missing) > 0 {
+		comparison.Message = "not compared, after the upgrade: " + after.missing
+		return comparison
+	}
+	comparison.After = formatValue(after.value)
+
+	delta := after.value - before.value
+	comparison.Delta = formatValue(delta)
+	if metric.MaxIncrease != nil && delta > *metric.MaxIncrease {
+		comparison.Regressed = true
+		comparison.Message = fmt.Sprintf("increased by %s, more than %s", formatValue(delta), formatValue(*metric.MaxIncrease))
+	}
+	if before.value != 0 {
+		percent := delta / before.value * 100
+		comparison.DeltaPercent = formatValue(percent)
+		if metric.MaxIncreasePercent != nil && percent > *metric.MaxIncreasePercent && !comparison.Regressed {
+			comparison.Regressed = true
+			comparison.Message = fmt.Sprintf("increased by %s%%, more than %s%%", formatValue(percent), formatValue(*metric.MaxIncreasePercent))
+		}
+	} else if metric.MaxIncreasePercent != nil && metric.MaxIncrease == nil && delta > 0 {
+		// Any increase from zero is infinite in percent
+		comparison.Regressed = true
+		comparison.Message = fmt.Sprintf("increased by %s from 0", formatValue(delta))
+	}
+	if !comparison.Regressed {
+		comparison.Message = "within the thresholds"
+	}
+	return comparison
+}
+
+func formatValue(v float64) string {
+	return strconv.FormatFloat(v, 'g', 6, 64)
+}
+
+func format(t time.Time) string {
+	return t.UTC().Format(time.RFC3339)
+}
@@ -0,0 +1,13 @@
+package regression
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestRegression(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Regression Suite")
+}
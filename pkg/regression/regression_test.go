@@ -0,0 +1,112 @@
+package regression
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	upgradev1alpha1 "github.com/openshift/managed-upgrade-operator/pkg/apis/upgrade/v1alpha1"
+	"github.com/openshift/managed-upgrade-operator/pkg/operatorconfig"
+	"github.com/openshift/managed-upgrade-operator/pkg/prometheus/mocks"
+	"github.com/prometheus/common/model"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Regression", func() {
+	var (
+		mockCtrl             *gomock.Controller
+		mockPrometheusClient *mocks.MockPrometheusClient
+		cfg                  operatorconfig.RegressionConfig
+		commenced            time.Time
+		verified             time.Time
+	)
+
+	series := func(values ...float64) model.Matrix {
+		stream := &model.SampleStream{Metric: model.Metric{}}
+		for i, v := range values {
+			stream.Values = append(stream.Values, model.SamplePair{Timestamp: model.Time(i * 60000), Value: model.SampleValue(v)})
+		}
+		return model.Matrix{stream}
+	}
+	threshold := func(v float64) *float64 {
+		return &v
+	}
+	expectWindows := func(query string, before model.Matrix, after model.Matrix) {
+		mockPrometheusClient.EXPECT().QueryRange(query, commenced.Add(-30*time.Minute), commenced, time.Minute).Return(before, nil)
+		mockPrometheusClient.EXPECT().QueryRange(query, verified, verified.Add(30*time.Minute), time.Minute).Return(after, nil)
+	}
+
+	BeforeEach(func() {
+		mockCtrl = gomock.NewController(GinkgoT())
+		mockPrometheusClient = mocks.NewMockPrometheusClient(mockCtrl)
+		commenced = time.Date(2020, 6, 20, 10, 0, 0, 0, time.UTC)
+		verified = commenced.Add(2 * time.Hour)
+		cfg = operatorconfig.RegressionConfig{
+			WindowMinutes: 30,
+			Metrics: []operatorconfig.RegressionMetric{
+				{Name: "apiserver-errors", Query: "apiserver_errors", MaxIncrease: threshold(0.01)},
+				{Name: "etcd-fsync", Query: "etcd_fsync", MaxIncreasePercent: threshold(50)},
+			},
+		}
+	})
+
+	AfterEach(func() {
+		mockCtrl.Finish()
+	})
+
+	Context("When comparing the windows", func() {
+		It("records the averages and their deltas", func() {
+			expectWindows("apiserver_errors", series(0.01, 0.03), series(0.02, 0.02))
+			expectWindows("etcd_fsync", series(0.004), series(0.005))
+			comparison, err := Compare(mockPrometheusClient, cfg, commenced, verified)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(comparison.BeforeWindowEnd.Time).To(Equal(commenced))
+			Expect(comparison.AfterWindowStart.Time).To(Equal(verified))
+			Expect(comparison.WindowMinutes).To(Equal(int32(30)))
+			Expect(comparison.Metrics).To(Equal([]upgradev1alpha1.MetricComparison{
+				{Name: "apiserver-errors", Before: "0.02", After: "0.02", Delta: "0", DeltaPercent: "0", Message: "within the thresholds"},
+				{Name: "etcd-fsync", Before: "0.004", After: "0.005", Delta: "0.001", DeltaPercent: "25", Message: "within the thresholds"},
+			}))
+			Expect(Regressed(comparison)).To(BeEmpty())
+		})
+
+		It("flags the metrics increasing more than their thresholds", func() {
+			expectWindows("apiserver_errors", series(0.01), series(0.05))
+			expectWindows("etcd_fsync", series(0.004), series(0.008))
+			comparison, err := Compare(mockPrometheusClient, cfg, commenced, verified)
+			Expect(err).NotTo(HaveOccurred())
+			regressed := Regressed(comparison)
+			Expect(regressed).To(HaveLen(2))
+			Expect(regressed[0].Message).To(Equal("increased by 0.04, more than 0.01"))
+			Expect(regressed[1].Message).To(Equal("increased by 100%, more than 50%"))
+		})
+
+		It("flags any increase from zero of the metrics with a relative threshold only", func() {
+			cfg.Metrics = cfg.Metrics[1:]
+			expectWindows("etcd_fsync", series(0), series(0.001))
+			comparison, err := Compare(mockPrometheusClient, cfg, commenced, verified)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(Regressed(comparison)).To(HaveLen(1))
+			Expect(comparison.Metrics[0].DeltaPercent).To(BeEmpty())
+		})
+
+		It("does not compare the metrics without data or with several series", func() {
+			expectWindows("apiserver_errors", model.Matrix{}, series(1))
+			expectWindows("etcd_fsync", series(1), append(series(1), series(2)...))
+			comparison, err := Compare(mockPrometheusClient, cfg, commenced, verified)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(Regressed(comparison)).To(BeEmpty())
+			Expect(comparison.Metrics[0].Message).To(Equal("not compared, before the upgrade: no data from 2020-06-20T09:30:00Z to 2020-06-20T10:00:00Z"))
+			Expect(comparison.Metrics[1].Before).To(Equal("1"))
+			Expect(comparison.Metrics[1].Message).To(Equal("not compared, after the upgrade: the query returned 2 series instead of one"))
+		})
+
+		It("fails when Prometheus can not be queried", func() {
+			mockPrometheusClient.EXPECT().QueryRange(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, fmt.Errorf("unable to reach Prometheus"))
+			_, err := Compare(mockPrometheusClient, cfg, commenced, verified)
+			Expect(err).To(MatchError("unable to reach Prometheus"))
+		})
+	})
+})
@@ -0,0 +1,151 @@
+package reporter
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	upgradev1alpha1 "github.com/openshift/managed-upgrade-operator/pkg/apis/upgrade/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// Event is the kind of state transition reported, see docs/reporter.md
+type Event string
+
+const (
+	// The upgrade entered a new phase, other than Failed or Upgraded
+	EventPhaseChanged Event = "PhaseChanged"
+	// A step of the upgrade started or completed
+	EventStepChanged Event = "StepChanged"
+	// The upgrade failed
+	EventFailed Event = "Failed"
+	// The upgrade completed
+	EventCompleted Event = "Completed"
+)
+
+// StepStatus tells whether the current step is still running
+type StepStatus string
+
+const (
+	StepStatusInProgress StepStatus = "InProgress"
+	StepStatusCompleted  StepStatus = "Completed"
+)
+
+// Report is the body POSTed to the reporter endpoint, see docs/reporter.md
+type Report struct {
+	// Unique ID of the report, the same report may be sent more than once
+	ID            string                               `json:"id"`
+	ClusterID     string                               `json:"clusterId"`
+	UpgradeConfig string                               `json:"upgradeConfig"`
+	Version       string                               `json:"version"`
+	Source        upgradev1alpha1.UpgradeSource        `json:"source,omitempty"`
+	Event         Event                                `json:"event"`
+	Phase         upgradev1alpha1.UpgradePhase         `json:"phase"`
+	Step          upgradev1alpha1.UpgradeConditionType `json:"step,omitempty"`
+	StepStatus    StepStatus                           `json:"stepStatus,omitempty"`
+	// Reason code of a failure
+	Reason  string    `json:"reason,omitempty"`
+	Message string    `json:"message,omitempty"`
+	Time    time.Time `json:"time"`
+}
+
+// state is what is compared to detect the transitions of an upgrade
+type state struct {
+	Phase      upgradev1alpha1.UpgradePhase         `json:"phase"`
+	Step       upgradev1alpha1.UpgradeConditionType `json:"step,omitempty"`
+	StepStatus StepStatus                           `json:"stepStatus,omitempty"`
+}
+
+// stateOf returns the state of the upgrade, the current step is the one started last.
+// The approval gate decisions are recorded in conditions too, they are not steps.
+func stateOf(history *upgradev1alpha1.UpgradeHistory) state {
+	s := state{Phase: history.Phase}
+	// Conditions are added in front
+	for _, current := range history.Conditions {
+		if strings.HasPrefix(string(current.Type), upgradev1alpha1.GateConditionPrefix) {
+			continue
+		}
+		s.Step = current.Type
+		s.StepStatus = StepStatusInProgress
+		if current.Status == corev1.ConditionTrue {
+			s.StepStatus = StepStatusCompleted
+		}
+		break
+	}
+	return s
+}
+
+// isTerminal returns true if the upgrade will not change anymore
+func isTerminal(phase upgradev1alpha1.UpgradePhase) bool {
+	return phase == upgradev1alpha1.UpgradePhaseUpgraded ||
+		phase == upgradev1alpha1.UpgradePhaseFailed ||
+		phase == upgradev1alpha1.UpgradePhaseSuperseded
+}
+
+// transitions returns the reports of the changes from the previous state to the state of the history.
+// The reports are missing the IDs and the cluster.
+func transitions(previous *state, history *upgradev1alpha1.UpgradeHistory, now time.Time) []Report {
+	current := stateOf(history)
+	base := Report{
+		Version:    history.Version,
+		Source:     history.Source,
+		Phase:      current.Phase,
+		Step:       current.Step,
+		StepStatus: current.StepStatus,
+		Time:       now,
+	}
+
+	reports := []Report{}
+	if previous == nil || previous.Step != current.Step || previous.StepStatus != current.StepStatus {
+		if len(current.Step) > 0 {
+			r := base
+			r.Event = EventStepChanged
+			if c := history.Conditions.GetCondition(current.Step); c != nil {
+				r.Message = c.Message
+			}
+			reports = append(reports, r)
+		}
+	}
+	if previous == nil || previous.Phase != current.Phase {
+		r := base
+		switch current.Phase {
+		case upgradev1alpha1.UpgradePhaseFailed:
+			r.Event = EventFailed
+			r.Reason, r.Message = failureOf(history)
+		case upgradev1alpha1.UpgradePhaseUpgraded:
+			r.Event = EventCompleted
+		default:
+			r.Event = EventPhaseChanged
+		}
+		reports = append(reports, r)
+	}
+	return reports
+}
+
+// failureOf returns the reason code and the message of the failed upgrade.
+// The reason code is the reason of the failed step when it is a code, like RetargetRejected, or <step>Failed.
+func failureOf(history *upgradev1alpha1.UpgradeHistory) (string, string) {
+	for _, c := range history.Conditions {
+		if c.Status == corev1.ConditionTrue {
+			continue
+		}
+		if isCode(c.Reason) {
+			return c.Reason, c.Message
+		}
+		return fmt.Sprintf("%sFailed", c.Type), c.Message
+	}
+	return "UpgradeFailed", ""
+}
+
+// isCode returns true if the reason is a CamelCase code rather than a sentence
+func isCode(reason string) bool {
+	if len(reason) == 0 || reason[0] < 'A' || reason[0] > 'Z' {
+		return false
+	}
+	for _, r := range reason {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9') {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,335 @@
+package reporter
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	configv1 "github.com/openshift/api/config/v1"
+	upgradev1alpha1 "github.com/openshift/managed-upgrade-operator/pkg/apis/upgrade/v1alpha1"
+	"github.com/openshift/managed-upgrade-operator/pkg/operatorconfig"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/retry"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+)
+
+const (
+	// ConfigMap keeping the reports not delivered yet and the last reported states, in the operator namespace,
+	// so that nothing is lost or reported twice across restarts
+	BufferConfigMapName = "managed-upgrade-operator-reports"
+	reportsKey          = "reports.json"
+	statesKey           = "states.json"
+
+	defaultTimeout = 30 * time.Second
+	// How often the buffer is flushed when nothing new was reported
+	flushInterval = time.Minute
+	minBackoff    = 5 * time.Second
+	maxBackoff    = 5 * time.Minute
+)
+
+var log = logf.Log.WithName("reporter")
+
+// blank assignment to verify that StatusReporter implements manager.Runnable
+var _ manager.Runnable = &StatusReporter{}
+
+// StatusReporter pushes the state transitions of the upgrades to the configured endpoint.
+// Transitions are observed on every status written and buffered, the buffer is flushed in the background
+// and retried with a backoff while the endpoint is unreachable.
+type StatusReporter struct {
+	client     client.Client
+	namespace  string
+	httpClient *http.Client
+	minBackoff time.Duration
+	maxBackoff time.Duration
+	wake       chan struct{}
+
+	mutex     sync.Mutex
+	loaded    bool
+	buffer    *corev1.ConfigMap
+	clusterID string
+	reports   []Report
+	// Last state of each upgrade, keyed by UpgradeConfig name and version
+	states map[string]state
+}
+
+func NewStatusReporter(c client.Client, namespace string) *StatusReporter {
+	return &StatusReporter{
+		client:     c,
+		namespace:  namespace,
+		httpClient: &http.Client{Timeout: defaultTimeout},
+		minBackoff: minBackoff,
+		maxBackoff: maxBackoff,
+		wake:       make(chan struct{}, 1),
+	}
+}
+
+// permanentError is returned when the endpoint rejected the report, sending it again does not help
+type permanentError struct {
+	msg string
+}
+
+func (e *permanentError) Error() string {
+	return e.msg
+}
+
+// Observe buffers the reports of the transitions of the upgrades of the UpgradeConfig.
+// The upgrades seen for the first time once they are over happened before reporting was enabled, they are not reported.
+func (r *StatusReporter) Observe(uc *upgradev1alpha1.UpgradeConfig) {
+	cfg := operatorconfig.Get().Reporter
+	if len(cfg.URL) == 0 {
+		return
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	err := r.load()
+	if err != nil {
+		log.Error(err, "unable to load the report buffer, the transitions are not reported")
+		return
+	}
+
+	now := time.Now()
+	changed := false
+	for i := range uc.Status.History {
+		history := &uc.Status.History[i]
+		if history.DryRun {
+			continue
+		}
+		key := uc.Name + "/" + history.Version
+		current := stateOf(history)
+		previous, found := r.states[key]
+		if found && previous == current {
+			continue
+		}
+		r.states[key] = current
+		changed = true
+		if !found && isTerminal(history.Phase) {
+			continue
+		}
+
+		var from *state
+		if found {
+			from = &previous
+		}
+		for _, report := range transitions(from, history, now) {
+			report.ID = uuid.New().String()
+			report.ClusterID = r.getClusterID()
+			report.UpgradeConfig = uc.Name
+			r.reports = append(r.reports, report)
+		}
+	}
+	if !changed {
+		return
+	}
+
+	if dropped := len(r.reports) - cfg.BufferSize; dropped > 0 {
+		log.Info(fmt.Sprintf("report buffer is full, dropping the %d oldest reports", dropped))
+		r.reports = r.reports[dropped:]
+	}
+	err = r.persist()
+	if err != nil {
+		log.Error(err, "unable to persist the report buffer")
+	}
+	select {
+	case r.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Start flushes the buffered reports until stopped
+func (r *StatusReporter) Start(stop <-chan struct{}) error {
+	backoff := time.Duration(0)
+	for {
+		wait := flushInterval
+		wake := r.wake
+		err := r.flush()
+		if err != nil {
+			backoff = nextBackoff(backoff, r.minBackoff, r.maxBackoff)
+			log.Error(err, fmt.Sprintf("unable to deliver the reports, retrying in %s", backoff))
+			wait = backoff
+			// New reports do not cut the backoff short
+			wake = nil
+		} else {
+			backoff = 0
+		}
+
+		select {
+		case <-stop:
+			return nil
+		case <-wake:
+		case <-time.After(wait):
+		}
+	}
+}
+
+// flush sends the buffered reports in order. It stops at the first report which can not be delivered for now,
+// a report the endpoint rejects is dropped.
+func (r *StatusReporter) flush() error {
+	for {
+		url := operatorconfig.Get().Reporter.URL
+		if len(url) == 0 {
+			return nil
+		}
+		r.mutex.Lock()
+		err := r.load()
+		if err != nil || len(r.reports) == 0 {
+			r.mutex.Unlock()
+			return err
+		}
+		report := r.reports[0]
+		r.mutex.Unlock()
+
+		err = r.send(url, report)
+		if err != nil {
+			if _, ok := err.(*permanentError); !ok {
+				return err
+			}
+			log.Error(err, fmt.Sprintf("dropping report %s rejected by the endpoint", report.ID))
+		}
+
+		r.mutex.Lock()
+		if len(r.reports) > 0 && r.reports[0].ID == report.ID {
+			r.reports = r.reports[1:]
+		}
+		err = r.persist()
+		r.mutex.Unlock()
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// send POSTs the report to the endpoint
+func (r *StatusReporter) send(url string, report Report) error {
+	body, err := json.Marshal(report)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := r.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return nil
+	}
+	reply, _ := ioutil.ReadAll(resp.Body)
+	msg := fmt.Sprintf("endpoint replied %d to report %s: %s", resp.StatusCode, report.ID, string(reply))
+	if resp.StatusCode >= 400 && resp.StatusCode < 500 && resp.StatusCode != http.StatusRequestTimeout && resp.StatusCode != http.StatusTooManyRequests {
+		return &permanentError{msg: msg}
+	}
+	return fmt.Errorf(msg)
+}
+
+// load reads the buffer persisted by a previous run, once
+func (r *StatusReporter) load() error {
+	if r.loaded {
+		return nil
+	}
+	r.reports = []Report{}
+	r.states = map[string]state{}
+	cm := &corev1.ConfigMap{}
+	err := r.client.Get(context.TODO(), types.NamespacedName{Namespace: r.namespace, Name: BufferConfigMapName}, cm)
+	if err != nil {
+		if !errors.IsNotFound(err) {
+			return err
+		}
+		r.loaded = true
+		return nil
+	}
+	if data, ok := cm.Data[reportsKey]; ok {
+		err = json.Unmarshal([]byte(data), &r.reports)
+		if err != nil {
+			return fmt.Errorf("invalid buffered reports: %v", err)
+		}
+	}
+	if data, ok := cm.Data[statesKey]; ok {
+		err = json.Unmarshal([]byte(data), &r.states)
+		if err != nil {
+			return fmt.Errorf("invalid reported states: %v", err)
+		}
+	}
+	r.buffer = cm
+	r.loaded = true
+	return nil
+}
+
+// persist writes the buffer to its ConfigMap
+func (r *StatusReporter) persist() error {
+	reports, err := json.Marshal(r.reports)
+	if err != nil {
+		return err
+	}
+	states, err := json.Marshal(r.states)
+	if err != nil {
+		return err
+	}
+	data := map[string]string{reportsKey: string(reports), statesKey: string(states)}
+
+	if r.buffer == nil {
+		cm := &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Namespace: r.namespace, Name: BufferConfigMapName},
+			Data:       data,
+		}
+		err = r.client.Create(context.TODO(), cm)
+		if err != nil {
+			return err
+		}
+		r.buffer = cm
+		return nil
+	}
+
+	first := true
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		if !first {
+			err := r.client.Get(context.TODO(), types.NamespacedName{Namespace: r.namespace, Name: BufferConfigMapName}, r.buffer)
+			if err != nil {
+				return err
+			}
+		}
+		first = false
+		r.buffer.Data = data
+		return r.client.Update(context.TODO(), r.buffer)
+	})
+}
+
+// getClusterID returns the ID of the cluster the reports are about, empty if it is unknown for now
+func (r *StatusReporter) getClusterID() string {
+	if len(r.clusterID) > 0 {
+		return r.clusterID
+	}
+	clusterVersion := &configv1.ClusterVersion{}
+	err := r.client.Get(context.TODO(), types.NamespacedName{Name: "version"}, clusterVersion)
+	if err != nil {
+		log.Error(err, "unable to get the cluster ID")
+		return ""
+	}
+	r.clusterID = string(clusterVersion.Spec.ClusterID)
+	return r.clusterID
+}
+
+func nextBackoff(current time.Duration, min time.Duration, max time.Duration) time.Duration {
+	if current < min {
+		return min
+	}
+	if current*2 > max {
+		return max
+	}
+	return current * 2
+}
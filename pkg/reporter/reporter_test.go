@@ -0,0 +1,264 @@
+package reporter
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+
+	"github.com/golang/mock/gomock"
+	configv1 "github.com/openshift/api/config/v1"
+	upgradev1alpha1 "github.com/openshift/managed-upgrade-operator/pkg/apis/upgrade/v1alpha1"
+	"github.com/openshift/managed-upgrade-operator/pkg/operatorconfig"
+	"github.com/openshift/managed-upgrade-operator/util/mocks"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("StatusReporter", func() {
+	const namespace = "test-namespace"
+	var (
+		mockCtrl       *gomock.Controller
+		mockKubeClient *mocks.MockClient
+		server         *httptest.Server
+		status         int
+		mutex          sync.Mutex
+		received       []Report
+		stored         *corev1.ConfigMap
+		reporter       *StatusReporter
+		upgradeConfig  *upgradev1alpha1.UpgradeConfig
+	)
+
+	// The buffer ConfigMap is kept in memory, it survives the reporters like it survives the operator restarts
+	store := func(ctx context.Context, obj runtime.Object, opts ...interface{}) error {
+		stored = obj.(*corev1.ConfigMap).DeepCopy()
+		return nil
+	}
+
+	BeforeEach(func() {
+		mockCtrl = gomock.NewController(GinkgoT())
+		mockKubeClient = mocks.NewMockClient(mockCtrl)
+		status = http.StatusOK
+		received = nil
+		stored = nil
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			defer GinkgoRecover()
+			Expect(r.Method).To(Equal(http.MethodPost))
+			Expect(r.Header.Get("Content-Type")).To(Equal("application/json"))
+			mutex.Lock()
+			defer mutex.Unlock()
+			if status == http.StatusOK {
+				report := Report{}
+				Expect(json.NewDecoder(r.Body).Decode(&report)).To(Succeed())
+				received = append(received, report)
+			}
+			w.WriteHeader(status)
+		}))
+		cfg := operatorconfig.DefaultConfig()
+		cfg.Reporter.URL = server.URL
+		operatorconfig.Set(cfg, "", "")
+
+		mockKubeClient.EXPECT().Get(gomock.Any(), types.NamespacedName{Name: "version"}, gomock.Any()).SetArg(2, configv1.ClusterVersion{
+			Spec: configv1.ClusterVersionSpec{ClusterID: "cluster-id"},
+		}).AnyTimes()
+		mockKubeClient.EXPECT().Get(gomock.Any(), types.NamespacedName{Namespace: namespace, Name: BufferConfigMapName}, gomock.Any()).DoAndReturn(
+			func(ctx context.Context, key client.ObjectKey, obj runtime.Object) error {
+				if stored == nil {
+					return errors.NewNotFound(schema.GroupResource{Resource: "configmaps"}, key.Name)
+				}
+				stored.DeepCopyInto(obj.(*corev1.ConfigMap))
+				return nil
+			}).AnyTimes()
+		mockKubeClient.EXPECT().Create(gomock.Any(), gomock.Any()).DoAndReturn(
+			func(ctx context.Context, obj runtime.Object, opts ...client.CreateOption) error {
+				return store(ctx, obj)
+			}).AnyTimes()
+		mockKubeClient.EXPECT().Update(gomock.Any(), gomock.Any()).DoAndReturn(
+			func(ctx context.Context, obj runtime.Object, opts ...client.UpdateOption) error {
+				return store(ctx, obj)
+			}).AnyTimes()
+
+		reporter = NewStatusReporter(mockKubeClient, namespace)
+		upgradeConfig = &upgradev1alpha1.UpgradeConfig{}
+		upgradeConfig.Name = "test-upgradeconfig"
+		upgradeConfig.Status.History = []upgradev1alpha1.UpgradeHistory{
+			{
+				Version: "4.4.5",
+				Phase:   upgradev1alpha1.UpgradePhaseUpgrading,
+				Conditions: upgradev1alpha1.Conditions{
+					{Type: upgradev1alpha1.UpgradeValidated, Status: corev1.ConditionFalse, Reason: "Validation not done"},
+				},
+			},
+		}
+	})
+
+	AfterEach(func() {
+		server.Close()
+		operatorconfig.Reset()
+		mockCtrl.Finish()
+	})
+
+	Context("When the endpoint is up", func() {
+		It("Reports the step and the phase of a new upgrade", func() {
+			reporter.Observe(upgradeConfig)
+			Expect(reporter.flush()).To(Succeed())
+			Expect(received).To(HaveLen(2))
+			Expect(received[0].Event).To(Equal(EventStepChanged))
+			Expect(received[0].Step).To(Equal(upgradev1alpha1.UpgradeValidated))
+			Expect(received[0].StepStatus).To(Equal(StepStatusInProgress))
+			Expect(received[1].Event).To(Equal(EventPhaseChanged))
+			Expect(received[1].Phase).To(Equal(upgradev1alpha1.UpgradePhaseUpgrading))
+			for _, report := range received {
+				Expect(report.ID).NotTo(BeEmpty())
+				Expect(report.ClusterID).To(Equal("cluster-id"))
+				Expect(report.UpgradeConfig).To(Equal("test-upgradeconfig"))
+				Expect(report.Version).To(Equal("4.4.5"))
+			}
+			Expect(reporter.reports).To(BeEmpty())
+		})
+
+		It("Reports nothing when the state did not change", func() {
+			reporter.Observe(upgradeConfig)
+			Expect(reporter.flush()).To(Succeed())
+			reporter.Observe(upgradeConfig)
+			Expect(reporter.flush()).To(Succeed())
+			Expect(received).To(HaveLen(2))
+		})
+
+		It("Reports the failure with its reason code", func() {
+			reporter.Observe(upgradeConfig)
+			upgradeConfig.Status.History[0].Phase = upgradev1alpha1.UpgradePhaseFailed
+			upgradeConfig.Status.History[0].Conditions[0].Message = "cluster is not ready"
+			reporter.Observe(upgradeConfig)
+			Expect(reporter.flush()).To(Succeed())
+			Expect(received).To(HaveLen(3))
+			Expect(received[2].Event).To(Equal(EventFailed))
+			Expect(received[2].Reason).To(Equal("ValidationFailed"))
+			Expect(received[2].Message).To(Equal("cluster is not ready"))
+		})
+
+		It("Reports the completion", func() {
+			reporter.Observe(upgradeConfig)
+			history := &upgradeConfig.Status.History[0]
+			history.Phase = upgradev1alpha1.UpgradePhaseUpgraded
+			history.Conditions = append(upgradev1alpha1.Conditions{
+				{Type: upgradev1alpha1.PostClusterHealthCheck, Status: corev1.ConditionTrue},
+			}, history.Conditions...)
+			reporter.Observe(upgradeConfig)
+			Expect(reporter.flush()).To(Succeed())
+			Expect(received).To(HaveLen(4))
+			Expect(received[2].Event).To(Equal(EventStepChanged))
+			Expect(received[2].Step).To(Equal(upgradev1alpha1.PostClusterHealthCheck))
+			Expect(received[2].StepStatus).To(Equal(StepStatusCompleted))
+			Expect(received[3].Event).To(Equal(EventCompleted))
+		})
+
+		It("Does not report the approval gate decisions as steps", func() {
+			reporter.Observe(upgradeConfig)
+			history := &upgradeConfig.Status.History[0]
+			history.Conditions = append(upgradev1alpha1.Conditions{
+				{Type: upgradev1alpha1.GateConditionType("change-freeze"), Status: corev1.ConditionTrue},
+			}, history.Conditions...)
+			reporter.Observe(upgradeConfig)
+			Expect(reporter.flush()).To(Succeed())
+			Expect(received).To(HaveLen(2))
+			Expect(received[0].Step).To(Equal(upgradev1alpha1.UpgradeValidated))
+		})
+
+		It("Does not report the upgrades already over when first seen", func() {
+			upgradeConfig.Status.History[0].Phase = upgradev1alpha1.UpgradePhaseUpgraded
+			reporter.Observe(upgradeConfig)
+			Expect(reporter.flush()).To(Succeed())
+			Expect(received).To(BeEmpty())
+		})
+
+		It("Does not report the dry runs", func() {
+			upgradeConfig.Status.History[0].DryRun = true
+			reporter.Observe(upgradeConfig)
+			Expect(reporter.flush()).To(Succeed())
+			Expect(received).To(BeEmpty())
+		})
+	})
+
+	Context("When no endpoint is configured", func() {
+		It("Buffers nothing", func() {
+			operatorconfig.Reset()
+			reporter.Observe(upgradeConfig)
+			Expect(reporter.flush()).To(Succeed())
+			Expect(stored).To(BeNil())
+			Expect(received).To(BeEmpty())
+		})
+	})
+
+	Context("When the endpoint is down", func() {
+		BeforeEach(func() {
+			status = http.StatusServiceUnavailable
+		})
+
+		It("Keeps the reports until the endpoint is back", func() {
+			reporter.Observe(upgradeConfig)
+			Expect(reporter.flush()).NotTo(Succeed())
+			Expect(reporter.reports).To(HaveLen(2))
+			status = http.StatusOK
+			Expect(reporter.flush()).To(Succeed())
+			Expect(received).To(HaveLen(2))
+		})
+
+		It("Delivers the reports buffered before a restart, without reporting them again", func() {
+			reporter.Observe(upgradeConfig)
+			Expect(reporter.flush()).NotTo(Succeed())
+			Expect(stored).NotTo(BeNil())
+
+			restarted := NewStatusReporter(mockKubeClient, namespace)
+			status = http.StatusOK
+			restarted.Observe(upgradeConfig)
+			Expect(restarted.flush()).To(Succeed())
+			Expect(received).To(HaveLen(2))
+			Expect(received[0].Event).To(Equal(EventStepChanged))
+			Expect(received[1].Event).To(Equal(EventPhaseChanged))
+		})
+
+		It("Drops the oldest reports when the buffer is full", func() {
+			cfg := operatorconfig.Get()
+			cfg.Reporter.BufferSize = 1
+			operatorconfig.Set(cfg, "", "")
+			reporter.Observe(upgradeConfig)
+			Expect(reporter.reports).To(HaveLen(1))
+			Expect(reporter.reports[0].Event).To(Equal(EventPhaseChanged))
+		})
+	})
+
+	Context("When the endpoint rejects a report", func() {
+		It("Drops the report", func() {
+			status = http.StatusBadRequest
+			reporter.Observe(upgradeConfig)
+			Expect(reporter.flush()).To(Succeed())
+			Expect(reporter.reports).To(BeEmpty())
+		})
+	})
+
+	Context("When the endpoint is throttling", func() {
+		It("Retries the report", func() {
+			status = http.StatusTooManyRequests
+			reporter.Observe(upgradeConfig)
+			Expect(reporter.flush()).NotTo(Succeed())
+			Expect(reporter.reports).To(HaveLen(2))
+		})
+	})
+
+	Context("Backoff", func() {
+		It("Doubles up to the maximum", func() {
+			Expect(nextBackoff(0, minBackoff, maxBackoff)).To(Equal(minBackoff))
+			Expect(nextBackoff(minBackoff, minBackoff, maxBackoff)).To(Equal(2 * minBackoff))
+			Expect(nextBackoff(maxBackoff, minBackoff, maxBackoff)).To(Equal(maxBackoff))
+		})
+	})
+})
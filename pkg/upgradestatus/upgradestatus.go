@@ -0,0 +1,217 @@
+package upgradestatus
+
+import (
+	"context"
+	"time"
+
+	configv1 "github.com/openshift/api/config/v1"
+	upgradev1alpha1 "github.com/openshift/managed-upgrade-operator/pkg/apis/upgrade/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/util/retry"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Mutation applies in-memory status changes to an UpgradeConfig status.
+// It may be applied more than once, on top of a freshly read UpgradeConfig, so it must only copy
+// already computed values rather than compute new ones.
+type Mutation func(status *upgradev1alpha1.UpgradeConfigStatus)
+
+// Listener is told about every UpgradeConfig status written. It is called synchronously after the write,
+// with the written UpgradeConfig which it must not modify.
+type Listener func(uc *upgradev1alpha1.UpgradeConfig)
+
+// Listeners are registered before the manager starts and never change afterwards
+var listeners []Listener
+
+// AddListener registers a listener to every status written, it must be called before the manager starts
+func AddListener(l Listener) {
+	listeners = append(listeners, l)
+}
+
+// The reader the UpgradeConfig is read again from on conflict, the client writing the status if unset
+var reader client.Reader
+
+// SetReader registers the reader the UpgradeConfig is read again from on conflict, it must be called before the manager starts.
+// It should not be cached: a cache lagging behind the writes would return the stale UpgradeConfig again.
+func SetReader(r client.Reader) {
+	reader = r
+}
+
+// How long the status is written again on conflict, about 6 seconds, giving a cached reader time to catch up
+var conflictBackoff = wait.Backoff{
+	Steps:    6,
+	Duration: 100 * time.Millisecond,
+	Factor:   2.0,
+	Jitter:   0.1,
+}
+
+// Patch writes the mutations to the UpgradeConfig status with a merge patch.
+// The patch carries the resourceVersion of the UpgradeConfig, so the write is rejected if the UpgradeConfig is stale.
+// On conflict, the UpgradeConfig is read again and the mutations re-applied to it before retrying.
+func Patch(c client.Client, uc *upgradev1alpha1.UpgradeConfig, mutations ...Mutation) error {
+	var r client.Reader = c
+	if reader != nil {
+		r = reader
+	}
+	first := true
+	err := retry.RetryOnConflict(conflictBackoff, func() error {
+		if !first {
+			err := r.Get(context.TODO(), types.NamespacedName{Namespace: uc.Namespace, Name: uc.Name}, uc)
+			if err != nil {
+				return err
+			}
+		}
+		first = false
+
+		base := uc.DeepCopy()
+		// Unset on the base so the patch includes the resourceVersion, making it an optimistic write
+		base.ResourceVersion = ""
+		for _, mutate := range mutations {
+			mutate(&uc.Status)
+		}
+		return c.Status().Patch(context.TODO(), uc, client.MergeFrom(base))
+	})
+	if err != nil {
+		return err
+	}
+	for _, l := range listeners {
+		l(uc)
+	}
+	return nil
+}
+
+// SetHistory returns the mutation adding (or updating) the history
+func SetHistory(history upgradev1alpha1.UpgradeHistory) Mutation {
+	h := *history.DeepCopy()
+	return func(status *upgradev1alpha1.UpgradeConfigStatus) {
+		status.History.SetHistory(*h.DeepCopy())
+	}
+}
+
+// PatchHistory writes the history to the UpgradeConfig status
+func PatchHistory(c client.Client, uc *upgradev1alpha1.UpgradeConfig, history upgradev1alpha1.UpgradeHistory) error {
+	return Patch(c, uc, SetHistory(history))
+}
+
+// SetDryRun returns the mutation recording the result of the dry run, and dropping the dry runs
+// the earlier versions of the operator recorded in the history
+func SetDryRun(history upgradev1alpha1.UpgradeHistory) Mutation {
+	h := *history.DeepCopy()
+	return func(status *upgradev1alpha1.UpgradeConfigStatus) {
+		status.DryRun = h.DeepCopy()
+		histories := upgradev1alpha1.UpgradeHistories{}
+		for _, uh := range status.History {
+			if !uh.DryRun {
+				histories = append(histories, uh)
+			}
+		}
+		status.History = histories
+	}
+}
+
+// SetRemovedOverrides returns the mutation recording the ClusterVersion overrides removed for the upgrade
+func SetRemovedOverrides(overrides []configv1.ComponentOverride) Mutation {
+	o := append([]configv1.ComponentOverride(nil), overrides...)
+	return func(status *upgradev1alpha1.UpgradeConfigStatus) {
+		status.RemovedOverrides = append([]configv1.ComponentOverride(nil), o...)
+	}
+}
+
+// SetPreflightResults returns the mutation recording the results of the health checks performed before the upgrade
+func SetPreflightResults(results []upgradev1alpha1.PreflightResult) Mutation {
+	r := make([]upgradev1alpha1.PreflightResult, len(results))
+	for i := range results {
+		results[i].DeepCopyInto(&r[i])
+	}
+	return func(status *upgradev1alpha1.UpgradeConfigStatus) {
+		status.PreflightResults = make([]upgradev1alpha1.PreflightResult, len(r))
+		for i := range r {
+			r[i].DeepCopyInto(&status.PreflightResults[i])
+		}
+	}
+}
+
+// mutateHistory applies the change to the history of the version, dry runs excluded
+func mutateHistory(version string, change func(history *upgradev1alpha1.UpgradeHistory)) Mutation {
+	return func(status *upgradev1alpha1.UpgradeConfigStatus) {
+		for i := range status.History {
+			if status.History[i].Version == version && !status.History[i].DryRun {
+				change(&status.History[i])
+			}
+		}
+	}
+}
+
+// SetPhase returns the mutation setting the phase of the upgrade of the version
+func SetPhase(version string, phase upgradev1alpha1.UpgradePhase) Mutation {
+	return mutateHistory(version, func(history *upgradev1alpha1.UpgradeHistory) {
+		history.Phase = phase
+	})
+}
+
+// SetStartTime returns the mutation recording when the upgrade of the version started
+func SetStartTime(version string, startTime metav1.Time) Mutation {
+	t := *startTime.DeepCopy()
+	return mutateHistory(version, func(history *upgradev1alpha1.UpgradeHistory) {
+		history.StartTime = t.DeepCopy()
+	})
+}
+
+// SetCompleteTime returns the mutation recording when the upgrade of the version ended
+func SetCompleteTime(version string, completeTime metav1.Time) Mutation {
+	t := *completeTime.DeepCopy()
+	return mutateHistory(version, func(history *upgradev1alpha1.UpgradeHistory) {
+		history.CompleteTime = t.DeepCopy()
+	})
+}
+
+// SetEstimate returns the mutation recording the estimated duration of the upgrade of the version
+func SetEstimate(version string, estimate *upgradev1alpha1.UpgradeEstimate) Mutation {
+	e := estimate.DeepCopy()
+	return mutateHistory(version, func(history *upgradev1alpha1.UpgradeHistory) {
+		history.Estimate = e.DeepCopy()
+	})
+}
+
+// SetHistoryCondition returns the mutation setting the condition of the upgrade of the version,
+// leaving its other conditions as they are
+func SetHistoryCondition(version string, condition upgradev1alpha1.UpgradeCondition) Mutation {
+	c := *condition.DeepCopy()
+	return mutateHistory(version, func(history *upgradev1alpha1.UpgradeHistory) {
+		history.Conditions.SetCondition(*c.DeepCopy())
+	})
+}
+
+// SetSettle returns the mutation recording how the health checks performed after the upgrade of the version settle
+func SetSettle(version string, settle upgradev1alpha1.HealthCheckSettle) Mutation {
+	s := *settle.DeepCopy()
+	return mutateHistory(version, func(history *upgradev1alpha1.UpgradeHistory) {
+		history.Settle = s.DeepCopy()
+	})
+}
+
+// SetRegression returns the mutation recording the comparison of the SLO metrics before and after the upgrade of the version
+func SetRegression(version string, comparison upgradev1alpha1.RegressionComparison) Mutation {
+	r := *comparison.DeepCopy()
+	return mutateHistory(version, func(history *upgradev1alpha1.UpgradeHistory) {
+		history.Regression = r.DeepCopy()
+	})
+}
+
+// SetControlPlaneProgress returns the mutation recording how far the control plane upgrade to the version went
+func SetControlPlaneProgress(version string, progress upgradev1alpha1.ControlPlaneProgress) Mutation {
+	p := *progress.DeepCopy()
+	return mutateHistory(version, func(history *upgradev1alpha1.UpgradeHistory) {
+		history.ControlPlaneProgress = p.DeepCopy()
+	})
+}
+
+// SetCondition returns the mutation setting the condition of the UpgradeConfig
+func SetCondition(condition upgradev1alpha1.UpgradeCondition) Mutation {
+	c := *condition.DeepCopy()
+	return func(status *upgradev1alpha1.UpgradeConfigStatus) {
+		status.Conditions.SetCondition(*c.DeepCopy())
+	}
+}
@@ -0,0 +1,13 @@
+package upgradestatus
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestUpgradeStatus(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "UpgradeStatus Suite")
+}
@@ -0,0 +1,173 @@
+package upgradestatus
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/golang/mock/gomock"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	upgradev1alpha1 "github.com/openshift/managed-upgrade-operator/pkg/apis/upgrade/v1alpha1"
+	"github.com/openshift/managed-upgrade-operator/util/mocks"
+	testStructs "github.com/openshift/managed-upgrade-operator/util/mocks/structs"
+	corev1 "k8s.io/api/core/v1"
+	k8serrs "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+var _ = Describe("UpgradeStatus", func() {
+	var (
+		mockCtrl       *gomock.Controller
+		mockKubeClient *mocks.MockClient
+		mockUpdater    *mocks.MockStatusWriter
+		upgradeConfig  *upgradev1alpha1.UpgradeConfig
+		history        upgradev1alpha1.UpgradeHistory
+	)
+
+	BeforeEach(func() {
+		mockCtrl = gomock.NewController(GinkgoT())
+		mockKubeClient = mocks.NewMockClient(mockCtrl)
+		mockUpdater = mocks.NewMockStatusWriter(mockCtrl)
+		upgradeConfig = testStructs.NewUpgradeConfigBuilder().GetUpgradeConfig()
+		upgradeConfig.ResourceVersion = "1"
+		history = upgradev1alpha1.UpgradeHistory{
+			Version: upgradeConfig.Spec.Desired.Version,
+			Phase:   upgradev1alpha1.UpgradePhaseUpgrading,
+		}
+		mockKubeClient.EXPECT().Status().Return(mockUpdater).AnyTimes()
+	})
+
+	AfterEach(func() {
+		mockCtrl.Finish()
+	})
+
+	Context("When patching the history", func() {
+		It("sends a merge patch guarded by the resourceVersion", func() {
+			mockUpdater.EXPECT().Patch(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(
+				func(ctx interface{}, obj *upgradev1alpha1.UpgradeConfig, patch client.Patch) error {
+					Expect(patch.Type()).To(Equal(types.MergePatchType))
+					data, err := patch.Data(obj)
+					Expect(err).NotTo(HaveOccurred())
+					body := map[string]interface{}{}
+					Expect(json.Unmarshal(data, &body)).To(Succeed())
+					Expect(body).To(HaveKeyWithValue("metadata", HaveKeyWithValue("resourceVersion", "1")))
+					Expect(body).To(HaveKey("status"))
+					return nil
+				})
+			err := PatchHistory(mockKubeClient, upgradeConfig, history)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(upgradeConfig.Status.History.GetHistory(history.Version).Phase).To(Equal(upgradev1alpha1.UpgradePhaseUpgrading))
+		})
+
+		It("reads the UpgradeConfig again and re-applies the history on conflict", func() {
+			conflict := k8serrs.NewConflict(schema.GroupResource{}, upgradeConfig.Name, fmt.Errorf("stale"))
+			latest := upgradeConfig.DeepCopy()
+			latest.ResourceVersion = "2"
+			latest.Status.History = upgradev1alpha1.UpgradeHistories{{Version: "another version"}}
+			gomock.InOrder(
+				mockUpdater.EXPECT().Patch(gomock.Any(), gomock.Any(), gomock.Any()).Return(conflict),
+				mockKubeClient.EXPECT().Get(gomock.Any(), types.NamespacedName{Namespace: upgradeConfig.Namespace, Name: upgradeConfig.Name}, gomock.Any()).SetArg(2, *latest),
+				mockUpdater.EXPECT().Patch(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil),
+			)
+			err := PatchHistory(mockKubeClient, upgradeConfig, history)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(upgradeConfig.ResourceVersion).To(Equal("2"))
+			Expect(upgradeConfig.Status.History).To(HaveLen(2))
+			Expect(upgradeConfig.Status.History.GetHistory(history.Version).Phase).To(Equal(upgradev1alpha1.UpgradePhaseUpgrading))
+		})
+
+		It("keeps reading the UpgradeConfig again while it is stale", func() {
+			conflict := k8serrs.NewConflict(schema.GroupResource{}, upgradeConfig.Name, fmt.Errorf("stale"))
+			stale := upgradeConfig.DeepCopy()
+			latest := upgradeConfig.DeepCopy()
+			latest.ResourceVersion = "2"
+			latest.Status.History = upgradev1alpha1.UpgradeHistories{{Version: "another version"}}
+			gomock.InOrder(
+				mockUpdater.EXPECT().Patch(gomock.Any(), gomock.Any(), gomock.Any()).Return(conflict),
+				mockKubeClient.EXPECT().Get(gomock.Any(), gomock.Any(), gomock.Any()).SetArg(2, *stale),
+				mockUpdater.EXPECT().Patch(gomock.Any(), gomock.Any(), gomock.Any()).Return(conflict),
+				mockKubeClient.EXPECT().Get(gomock.Any(), gomock.Any(), gomock.Any()).SetArg(2, *latest),
+				mockUpdater.EXPECT().Patch(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil),
+			)
+			err := PatchHistory(mockKubeClient, upgradeConfig, history)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(upgradeConfig.ResourceVersion).To(Equal("2"))
+			Expect(upgradeConfig.Status.History).To(HaveLen(2))
+		})
+
+		It("reads the UpgradeConfig again from the registered reader", func() {
+			apiReader := mocks.NewMockClient(mockCtrl)
+			SetReader(apiReader)
+			defer SetReader(nil)
+			conflict := k8serrs.NewConflict(schema.GroupResource{}, upgradeConfig.Name, fmt.Errorf("stale"))
+			latest := upgradeConfig.DeepCopy()
+			latest.ResourceVersion = "2"
+			gomock.InOrder(
+				mockUpdater.EXPECT().Patch(gomock.Any(), gomock.Any(), gomock.Any()).Return(conflict),
+				apiReader.EXPECT().Get(gomock.Any(), types.NamespacedName{Namespace: upgradeConfig.Namespace, Name: upgradeConfig.Name}, gomock.Any()).SetArg(2, *latest),
+				mockUpdater.EXPECT().Patch(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil),
+			)
+			mockKubeClient.EXPECT().Get(gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+			err := PatchHistory(mockKubeClient, upgradeConfig, history)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(upgradeConfig.ResourceVersion).To(Equal("2"))
+		})
+
+		It("returns errors other than conflicts", func() {
+			fakeError := k8serrs.NewInternalError(fmt.Errorf("a fake error"))
+			mockUpdater.EXPECT().Patch(gomock.Any(), gomock.Any(), gomock.Any()).Return(fakeError)
+			err := PatchHistory(mockKubeClient, upgradeConfig, history)
+			Expect(err).To(Equal(fakeError))
+		})
+	})
+
+	Context("When recording how the health checks settle", func() {
+		It("updates the history of the upgrade and not its dry run", func() {
+			dryRun := history
+			dryRun.DryRun = true
+			upgradeConfig.Status.History = upgradev1alpha1.UpgradeHistories{dryRun, history}
+			mockUpdater.EXPECT().Patch(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil)
+			now := metav1.Now()
+			err := Patch(mockKubeClient, upgradeConfig, SetSettle(history.Version, upgradev1alpha1.HealthCheckSettle{StartTime: &now, Flaps: 1}))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(upgradeConfig.Status.History.GetHistory(history.Version).Settle.Flaps).To(Equal(int32(1)))
+			Expect(upgradeConfig.Status.History[0].Settle).To(BeNil())
+		})
+	})
+
+	Context("When recording the control plane progress", func() {
+		It("updates the history of the upgrade", func() {
+			upgradeConfig.Status.History = upgradev1alpha1.UpgradeHistories{history}
+			mockUpdater.EXPECT().Patch(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil)
+			progress := upgradev1alpha1.ControlPlaneProgress{UpdatedOperators: 2, TotalOperators: 4, PendingOperators: []string{"dns", "ingress"}}
+			err := Patch(mockKubeClient, upgradeConfig, SetControlPlaneProgress(history.Version, progress))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(*upgradeConfig.Status.History.GetHistory(history.Version).ControlPlaneProgress).To(Equal(progress))
+		})
+	})
+
+	Context("When setting a condition of the history", func() {
+		It("keeps what was written to the history concurrently", func() {
+			upgradeConfig.Status.History = upgradev1alpha1.UpgradeHistories{history}
+			conflict := k8serrs.NewConflict(schema.GroupResource{}, upgradeConfig.Name, fmt.Errorf("stale"))
+			latest := upgradeConfig.DeepCopy()
+			latest.ResourceVersion = "2"
+			latest.Status.History[0].Regression = &upgradev1alpha1.RegressionComparison{WindowMinutes: 30}
+			gomock.InOrder(
+				mockUpdater.EXPECT().Patch(gomock.Any(), gomock.Any(), gomock.Any()).Return(conflict),
+				mockKubeClient.EXPECT().Get(gomock.Any(), gomock.Any(), gomock.Any()).SetArg(2, *latest),
+				mockUpdater.EXPECT().Patch(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil),
+			)
+			condition := upgradev1alpha1.UpgradeCondition{Type: upgradev1alpha1.RegressionCheck, Status: corev1.ConditionTrue}
+			err := Patch(mockKubeClient, upgradeConfig, SetHistoryCondition(history.Version, condition), SetPhase(history.Version, upgradev1alpha1.UpgradePhaseUpgraded))
+			Expect(err).NotTo(HaveOccurred())
+			recorded := upgradeConfig.Status.History.GetHistory(history.Version)
+			Expect(recorded.Regression.WindowMinutes).To(Equal(int32(30)))
+			Expect(recorded.Conditions.IsTrueFor(upgradev1alpha1.RegressionCheck)).To(BeTrue())
+			Expect(recorded.Phase).To(Equal(upgradev1alpha1.UpgradePhaseUpgraded))
+		})
+	})
+})
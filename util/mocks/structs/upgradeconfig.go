@@ -22,6 +22,7 @@ func NewUpgradeConfigBuilder() *testUpgradeConfigBuilder {
 			ObjectMeta: metav1.ObjectMeta{
 				Name:                       "fakeUpgradeConfig",
 				Namespace:                  "fakeNamespace",
+				Annotations:                map[string]string{},
 			},
 			Spec: api.UpgradeConfigSpec{
 				Desired:             api.Update{